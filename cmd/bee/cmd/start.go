@@ -60,6 +60,7 @@ func (c *command) initStartCmd() (err error) {
 			default:
 				return fmt.Errorf("unknown verbosity level %q", v)
 			}
+			logger.SetReportCaller(c.config.GetBool(optionNameLogCaller))
 
 			// If the resolver is specified, resolve all connection strings
 			// and fail on any errors.
@@ -10,8 +10,8 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"path"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 
@@ -106,7 +106,7 @@ func (s *server) bzzDownloadHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// we are expecting manifest Mime type here
-	m, err := manifest.NewManifestReference(
+	m, err := manifest.NewManifestReferenceReadOnly(
 		ctx,
 		manifestMetadata.MimeType,
 		e.Reference(),
@@ -120,81 +120,31 @@ func (s *server) bzzDownloadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if pathVar == "" {
-		logger.Tracef("bzz download: handle empty path %s", address)
+	me, status, err := manifest.ResolveWeb(m, pathVar, time.Now)
+	switch {
+	case status == http.StatusPermanentRedirect:
+		// redirect to directory
+		u := r.URL
+		u.Path += "/"
+		redirectURL := u.String()
 
-		if indexDocumentSuffixKey, ok := manifestMetadataLoad(m, manifestRootPath, manifestWebsiteIndexDocumentSuffixKey); ok {
-			pathWithIndex := path.Join(pathVar, indexDocumentSuffixKey)
-			indexDocumentManifestEntry, err := m.Lookup(pathWithIndex)
-			if err == nil {
-				// index document exists
-				logger.Debugf("bzz download: serving path: %s", pathWithIndex)
+		logger.Debugf("bzz download: redirecting to %s", redirectURL)
 
-				s.serveManifestEntry(w, r, address, indexDocumentManifestEntry.Reference())
-				return
-			}
-		}
-	}
-
-	me, err := m.Lookup(pathVar)
-	if err != nil {
+		http.Redirect(w, r, redirectURL, http.StatusPermanentRedirect)
+		return
+	case err != nil:
 		logger.Debugf("bzz download: invalid path %s/%s: %v", address, pathVar, err)
 		logger.Error("bzz download: invalid path")
 
 		if errors.Is(err, manifest.ErrNotFound) {
-
-			if !strings.HasPrefix(pathVar, "/") {
-				// check for directory
-				dirPath := pathVar + "/"
-				exists, err := m.HasPrefix(dirPath)
-				if err == nil && exists {
-					// redirect to directory
-					u := r.URL
-					u.Path += "/"
-					redirectURL := u.String()
-
-					logger.Debugf("bzz download: redirecting to %s: %v", redirectURL, err)
-
-					http.Redirect(w, r, redirectURL, http.StatusPermanentRedirect)
-					return
-				}
-			}
-
-			// check index suffix path
-			if indexDocumentSuffixKey, ok := manifestMetadataLoad(m, manifestRootPath, manifestWebsiteIndexDocumentSuffixKey); ok {
-				if !strings.HasSuffix(pathVar, indexDocumentSuffixKey) {
-					// check if path is directory with index
-					pathWithIndex := path.Join(pathVar, indexDocumentSuffixKey)
-					indexDocumentManifestEntry, err := m.Lookup(pathWithIndex)
-					if err == nil {
-						// index document exists
-						logger.Debugf("bzz download: serving path: %s", pathWithIndex)
-
-						s.serveManifestEntry(w, r, address, indexDocumentManifestEntry.Reference())
-						return
-					}
-				}
-			}
-
-			// check if error document is to be shown
-			if errorDocumentPath, ok := manifestMetadataLoad(m, manifestRootPath, manifestWebsiteErrorDocumentPathKey); ok {
-				if pathVar != errorDocumentPath {
-					errorDocumentManifestEntry, err := m.Lookup(errorDocumentPath)
-					if err == nil {
-						// error document exists
-						logger.Debugf("bzz download: serving path: %s", errorDocumentPath)
-
-						s.serveManifestEntry(w, r, address, errorDocumentManifestEntry.Reference())
-						return
-					}
-				}
-			}
-
 			jsonhttp.NotFound(w, "path address not found")
 		} else {
 			jsonhttp.NotFound(w, nil)
 		}
 		return
+	case status == http.StatusNotFound:
+		// error document exists
+		logger.Debugf("bzz download: serving error document for path: %s", pathVar)
 	}
 
 	// serve requested path
@@ -268,20 +218,3 @@ func (s *server) serveManifestEntry(w http.ResponseWriter, r *http.Request, addr
 
 	s.downloadHandler(w, r, fileEntryAddress, additionalHeaders)
 }
-
-// manifestMetadataLoad returns the value for a key stored in the metadata of
-// manifest path, or empty string if no value is present.
-// The ok result indicates whether value was found in the metadata.
-func manifestMetadataLoad(manifest manifest.Interface, path, metadataKey string) (string, bool) {
-	me, err := manifest.Lookup(path)
-	if err != nil {
-		return "", false
-	}
-
-	manifestRootMetadata := me.Metadata()
-	if val, ok := manifestRootMetadata[metadataKey]; ok {
-		return val, ok
-	}
-
-	return "", false
-}
@@ -34,9 +34,9 @@ const (
 )
 
 const (
-	manifestRootPath                      = "/"
-	manifestWebsiteIndexDocumentSuffixKey = "website-index-document"
-	manifestWebsiteErrorDocumentPathKey   = "website-error-document"
+	manifestRootPath                      = manifest.RootPath
+	manifestWebsiteIndexDocumentSuffixKey = manifest.WebsiteIndexDocumentSuffixKey
+	manifestWebsiteErrorDocumentPathKey   = manifest.WebsiteErrorDocumentPathKey
 )
 
 // dirUploadHandler uploads a directory supplied as a tar in an HTTP request
@@ -175,8 +175,7 @@ func storeDir(ctx context.Context, reader io.ReadCloser, s storage.Storer, mode
 		if errorFilename != "" {
 			metadata[manifestWebsiteErrorDocumentPathKey] = errorFilename
 		}
-		rootManifestEntry := manifest.NewEntry(swarm.ZeroAddress, metadata)
-		err = dirManifest.Add(manifestRootPath, rootManifestEntry)
+		err = dirManifest.SetRootMetadata(metadata)
 		if err != nil {
 			return swarm.ZeroAddress, fmt.Errorf("add to manifest: %w", err)
 		}
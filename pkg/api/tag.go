@@ -38,13 +38,14 @@ type tagResponse struct {
 }
 
 func newTagResponse(tag *tags.Tag) tagResponse {
+	state := tag.Snapshot()
 	return tagResponse{
-		Total:     tag.Total,
-		Split:     tag.Split,
-		Seen:      tag.Seen,
-		Stored:    tag.Stored,
-		Sent:      tag.Sent,
-		Synced:    tag.Synced,
+		Total:     state.Total,
+		Split:     state.Split,
+		Seen:      state.Seen,
+		Stored:    state.Stored,
+		Sent:      state.Sent,
+		Synced:    state.Synced,
 		Uid:       tag.Uid,
 		Name:      tag.Name,
 		Address:   tag.Address,
@@ -34,3 +34,19 @@ type Joiner interface {
 type Splitter interface {
 	Split(ctx context.Context, dataIn io.ReadCloser, dataLength int64, toEncrypt bool) (addr swarm.Address, err error)
 }
+
+// Loader is the interface for loading data by reference.
+type Loader interface {
+	Load(ref []byte) ([]byte, error)
+}
+
+// Saver is the interface for saving data, returning its reference.
+type Saver interface {
+	Save(data []byte) ([]byte, error)
+}
+
+// LoadSaver loads and saves data by reference.
+type LoadSaver interface {
+	Loader
+	Saver
+}
@@ -0,0 +1,109 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package loadsave
+
+import (
+	"container/list"
+	"encoding/hex"
+	"sync"
+
+	"github.com/ethersphere/bee/pkg/file"
+)
+
+// cachingLoadSaver keeps the most recently loaded and saved data in memory,
+// so that repeated loads of the same references, like the nodes of a
+// manifest, are not fetched from the storage again.
+type cachingLoadSaver struct {
+	inner      file.LoadSaver
+	maxEntries int
+
+	mu      sync.Mutex
+	ll      *list.List // most recently used at the front
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key  string
+	data []byte
+}
+
+// NewCachingLoadSaver returns a new file.LoadSaver which wraps inner and
+// keeps up to maxEntries of the most recently used references in memory.
+func NewCachingLoadSaver(inner file.LoadSaver, maxEntries int) file.LoadSaver {
+	return &cachingLoadSaver{
+		inner:      inner,
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+func (c *cachingLoadSaver) Load(ref []byte) ([]byte, error) {
+	key := hex.EncodeToString(ref)
+
+	if data, ok := c.get(key); ok {
+		return data, nil
+	}
+
+	data, err := c.inner.Load(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	c.add(key, data)
+
+	return data, nil
+}
+
+func (c *cachingLoadSaver) Save(data []byte) ([]byte, error) {
+	ref, err := c.inner.Save(data)
+	if err != nil {
+		return ref, err
+	}
+
+	c.add(hex.EncodeToString(ref), data)
+
+	return ref, nil
+}
+
+// get returns a copy of the cached data, as callers may modify it.
+func (c *cachingLoadSaver) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(e)
+
+	data := e.Value.(*cacheEntry).data
+	return append([]byte(nil), data...), true
+}
+
+func (c *cachingLoadSaver) add(key string, data []byte) {
+	if c.maxEntries <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		c.ll.MoveToFront(e)
+		return
+	}
+
+	c.entries[key] = c.ll.PushFront(&cacheEntry{
+		key:  key,
+		data: append([]byte(nil), data...),
+	})
+
+	if c.ll.Len() > c.maxEntries {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package loadsave_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/file"
+	"github.com/ethersphere/bee/pkg/file/loadsave"
+	"github.com/ethersphere/bee/pkg/manifest"
+	"github.com/ethersphere/bee/pkg/storage"
+	"github.com/ethersphere/bee/pkg/storage/mock"
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+func TestCachingLoadSaver(t *testing.T) {
+	ctx := context.Background()
+	storer := &getCountingStorer{Storer: mock.NewStorer()}
+
+	var refs [][]byte
+	for i := 0; i < 3; i++ {
+		ref, err := loadsave.New(ctx, storer, storage.ModePutUpload, false).Save([]byte{byte(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		refs = append(refs, ref)
+	}
+
+	ls := loadsave.NewCachingLoadSaver(loadsave.NewLoader(ctx, storer), 2)
+
+	load := func(i int) {
+		t.Helper()
+		got, err := ls.Load(refs[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, []byte{byte(i)}) {
+			t.Fatalf("got data %x, want %x", got, []byte{byte(i)})
+		}
+		// modifying the returned data must not affect the cache
+		got[0]++
+	}
+	assertGets := func(want int64) {
+		t.Helper()
+		if got := atomic.LoadInt64(&storer.gets); got != want {
+			t.Fatalf("got %d chunks retrieved, want %d", got, want)
+		}
+	}
+
+	load(0)
+	load(1)
+	assertGets(2)
+
+	load(0)
+	load(1)
+	assertGets(2)
+
+	// evicts the least recently used reference 0
+	load(2)
+	load(1)
+	assertGets(3)
+	load(0)
+	assertGets(4)
+}
+
+func TestCachingLoadSaverSave(t *testing.T) {
+	ctx := context.Background()
+	storer := &getCountingStorer{Storer: mock.NewStorer()}
+
+	ls := loadsave.NewCachingLoadSaver(loadsave.New(ctx, storer, storage.ModePutUpload, false), 10)
+
+	ref, err := ls.Save(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ls.Load(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("got data %q, want %q", got, data)
+	}
+	if gets := atomic.LoadInt64(&storer.gets); gets != 0 {
+		t.Fatalf("got %d chunks retrieved, want none", gets)
+	}
+}
+
+func BenchmarkCachingLoadSaverHasPrefix(b *testing.B) {
+	ctx := context.Background()
+	storer := &getCountingStorer{Storer: mock.NewStorer()}
+	reference := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+
+	m, err := manifest.NewManifest(manifest.ManifestMantarayContentType, false, storer)
+	if err != nil {
+		b.Fatal(err)
+	}
+	var prefixes []string
+	for i := 0; i < 10; i++ {
+		dir := fmt.Sprintf("dir%d/", i)
+		prefixes = append(prefixes, dir)
+		for j := 0; j < 10; j++ {
+			if err := m.Add(fmt.Sprintf("%sfile%d.txt", dir, j), manifest.NewEntry(reference, nil)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	ref, err := m.Store(ctx, storage.ModePutUpload)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	uncached := loadsave.NewLoader(ctx, storer)
+	cached := loadsave.NewCachingLoadSaver(loadsave.NewLoader(ctx, storer), 1000)
+
+	for _, tc := range []struct {
+		name string
+		ls   file.LoadSaver
+	}{
+		{name: "uncached", ls: uncached},
+		{name: "cached", ls: cached},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			gets := atomic.LoadInt64(&storer.gets)
+
+			for n := 0; n < b.N; n++ {
+				// every lookup loads the manifest anew, as when serving
+				// separate requests
+				m, err := manifest.NewManifestWithOptions(manifest.ManifestMantarayContentType, tc.ls, manifest.WithReference(ref))
+				if err != nil {
+					b.Fatal(err)
+				}
+				for _, prefix := range prefixes {
+					if ok, err := m.HasPrefix(prefix); err != nil || !ok {
+						b.Fatalf("has prefix %s: %v, %v", prefix, ok, err)
+					}
+				}
+			}
+
+			b.ReportMetric(float64(atomic.LoadInt64(&storer.gets)-gets)/float64(b.N), "gets/op")
+		})
+	}
+}
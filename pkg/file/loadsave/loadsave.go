@@ -0,0 +1,237 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package loadsave provides file.LoadSaver implementations backed by a
+// storage.Storer, using the joiner to load and the pipeline to save data.
+package loadsave
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/ethersphere/bee/pkg/file"
+	"github.com/ethersphere/bee/pkg/file/joiner"
+	"github.com/ethersphere/bee/pkg/file/pipeline/builder"
+	"github.com/ethersphere/bee/pkg/storage"
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+var (
+	// ErrSizeExceeded is returned by Load when the data on the reference is
+	// larger than the size limit of the loader.
+	ErrSizeExceeded = errors.New("loadsave: size limit exceeded")
+	// ErrReferenceNotFound is returned by Load and LoadReader when a chunk of
+	// the data on the reference is not in the storage. The returned error
+	// also wraps the error of the storage.
+	ErrReferenceNotFound = errors.New("loadsave: reference not found")
+)
+
+// referenceNotFoundError is ErrReferenceNotFound which wraps the
+// storage.ErrNotFound error it was caused by.
+type referenceNotFoundError struct {
+	err error
+}
+
+func (e *referenceNotFoundError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrReferenceNotFound, e.err)
+}
+
+func (e *referenceNotFoundError) Is(target error) bool {
+	return target == ErrReferenceNotFound
+}
+
+func (e *referenceNotFoundError) Unwrap() error {
+	return e.err
+}
+
+// loadError returns ErrReferenceNotFound if the error is caused by a missing
+// chunk and the error unchanged otherwise.
+func loadError(err error) error {
+	if errors.Is(err, storage.ErrNotFound) {
+		return &referenceNotFoundError{err: err}
+	}
+	return err
+}
+
+// ReadLoader is implemented by the load savers of this package to stream the
+// data on a reference instead of loading all of it into memory.
+type ReadLoader interface {
+	LoadReader(ref []byte) (io.ReadCloser, int64, error)
+}
+
+// SaverInfo is implemented by the load savers of this package to report the
+// settings used when saving data.
+type SaverInfo interface {
+	// Encrypted reports whether data is saved encrypted.
+	Encrypted() bool
+	// Mode returns the mode with which data is put into the storage.
+	Mode() storage.ModePut
+}
+
+// Option is a function that configures a load saver.
+type Option func(*loadSave)
+
+// JoinerFactory returns a reader of the data on the address, together with
+// its size.
+type JoinerFactory func(ctx context.Context, storer storage.Storer, addr swarm.Address) (io.ReadCloser, int64, error)
+
+// WithJoinerFactory replaces the joiner used to load data, e.g. to load data
+// with an alternative chunk layout. By default the joiner package is used.
+func WithJoinerFactory(f JoinerFactory) Option {
+	return func(ls *loadSave) {
+		ls.joinerFactory = f
+	}
+}
+
+// defaultJoinerFactory loads data with the joiner package.
+func defaultJoinerFactory(ctx context.Context, storer storage.Storer, addr swarm.Address) (io.ReadCloser, int64, error) {
+	j, span, err := joiner.New(ctx, storer, addr)
+	if err != nil {
+		return nil, 0, err
+	}
+	return ioutil.NopCloser(j), span, nil
+}
+
+// WithSizeLimit limits the size of the data which Load reads into memory to
+// maxBytes. Values less than or equal to zero disable the limit.
+func WithSizeLimit(maxBytes int64) Option {
+	return func(ls *loadSave) {
+		ls.maxBytes = maxBytes
+	}
+}
+
+// loadSave is needed for manifest operations and provides simple wrapping
+// over load and save operations using file package abstractions. Use with
+// caution since Load will read all of the data of a given reference into
+// memory.
+type loadSave struct {
+	ctx           context.Context
+	storer        storage.Storer
+	mode          storage.ModePut
+	encrypted     bool
+	maxBytes      int64
+	joinerFactory JoinerFactory
+}
+
+// New returns a new file.LoadSaver which saves with the given mode and
+// encryption setting.
+func New(ctx context.Context, storer storage.Storer, mode storage.ModePut, encrypted bool, opts ...Option) file.LoadSaver {
+	ls := &loadSave{
+		ctx:           ctx,
+		storer:        storer,
+		mode:          mode,
+		encrypted:     encrypted,
+		joinerFactory: defaultJoinerFactory,
+	}
+	for _, opt := range opts {
+		opt(ls)
+	}
+	return ls
+}
+
+// NewLoader returns a new file.LoadSaver intended for loading data only.
+func NewLoader(ctx context.Context, storer storage.Storer, opts ...Option) file.LoadSaver {
+	ls := &loadSave{
+		ctx:           ctx,
+		storer:        storer,
+		joinerFactory: defaultJoinerFactory,
+	}
+	for _, opt := range opts {
+		opt(ls)
+	}
+	return ls
+}
+
+// NewLoaderWithLimit returns a new file.LoadSaver intended for loading data
+// only, which fails with ErrSizeExceeded instead of loading more than maxBytes
+// of data into memory.
+func NewLoaderWithLimit(ctx context.Context, storer storage.Storer, maxBytes int64) file.LoadSaver {
+	return NewLoader(ctx, storer, WithSizeLimit(maxBytes))
+}
+
+func (ls *loadSave) Load(ref []byte) ([]byte, error) {
+	r, span, err := ls.LoadReader(ref)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	buf := bytes.NewBuffer(nil)
+
+	// hide the ReadFrom method of the buffer, so that the joiner is read
+	// with chunk sized buffers
+	var w io.Writer = struct{ io.Writer }{buf}
+	if ls.maxBytes > 0 {
+		w = &limitedWriter{w: buf, remaining: ls.maxBytes}
+	}
+
+	n, err := io.CopyBuffer(w, r, make([]byte, swarm.ChunkSize))
+	if err != nil {
+		return nil, loadError(err)
+	}
+	if n != span {
+		return nil, fmt.Errorf("received only %d of %d total bytes", n, span)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// LoadReader returns a reader streaming the data on the reference, together
+// with its size. If the load saver has a size limit, larger data fails with
+// ErrSizeExceeded before any of it is read. Missing root chunks fail with
+// ErrReferenceNotFound, while chunks missing further down are reported by the
+// reader as the error of the storage.
+func (ls *loadSave) LoadReader(ref []byte) (io.ReadCloser, int64, error) {
+	r, span, err := ls.joinerFactory(ls.ctx, ls.storer, swarm.NewAddress(ref))
+	if err != nil {
+		return nil, 0, loadError(err)
+	}
+
+	if ls.maxBytes > 0 && span > ls.maxBytes {
+		r.Close()
+		return nil, 0, ErrSizeExceeded
+	}
+
+	return r, span, nil
+}
+
+func (ls *loadSave) Save(data []byte) ([]byte, error) {
+	ctx := ls.ctx
+
+	pipe := builder.NewPipelineBuilder(ctx, ls.storer, ls.mode, ls.encrypted)
+	address, err := builder.FeedPipeline(ctx, pipe, bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return swarm.ZeroAddress.Bytes(), err
+	}
+
+	return address.Bytes(), nil
+}
+
+func (ls *loadSave) Encrypted() bool {
+	return ls.encrypted
+}
+
+func (ls *loadSave) Mode() storage.ModePut {
+	return ls.mode
+}
+
+// limitedWriter writes to the underlying writer until the limit is reached
+// and fails with ErrSizeExceeded afterwards.
+type limitedWriter struct {
+	w         io.Writer
+	remaining int64
+}
+
+func (lw *limitedWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > lw.remaining {
+		return 0, ErrSizeExceeded
+	}
+	n, err := lw.w.Write(p)
+	lw.remaining -= int64(n)
+	return n, err
+}
@@ -0,0 +1,212 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package loadsave_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/file/loadsave"
+	"github.com/ethersphere/bee/pkg/storage"
+	"github.com/ethersphere/bee/pkg/storage/mock"
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+var data = []byte("some data to be saved and loaded")
+
+func TestLoadSave(t *testing.T) {
+	for _, encrypted := range []bool{false, true} {
+		ls := loadsave.New(context.Background(), mock.NewStorer(), storage.ModePutUpload, encrypted)
+
+		ref, err := ls.Save(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := ls.Load(ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("encrypted %v: got data %q, want %q", encrypted, got, data)
+		}
+	}
+}
+
+func TestLoadSizeLimit(t *testing.T) {
+	ctx := context.Background()
+	storer := &getCountingStorer{Storer: mock.NewStorer()}
+
+	large := make([]byte, 10*swarm.ChunkSize)
+	for i := range large {
+		large[i] = byte(i)
+	}
+
+	ref, err := loadsave.New(ctx, storer, storage.ModePutUpload, false).Save(large)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = loadsave.NewLoaderWithLimit(ctx, storer, swarm.ChunkSize).Load(ref)
+	if !errors.Is(err, loadsave.ErrSizeExceeded) {
+		t.Fatalf("got error %v, want %v", err, loadsave.ErrSizeExceeded)
+	}
+	if gets := atomic.LoadInt64(&storer.gets); gets != 1 {
+		t.Fatalf("got %d chunks retrieved, want only the root chunk", gets)
+	}
+
+	got, err := loadsave.NewLoaderWithLimit(ctx, storer, int64(len(large))).Load(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, large) {
+		t.Fatal("loaded data differs from the saved data")
+	}
+
+	ls := loadsave.New(ctx, storer, storage.ModePutUpload, false, loadsave.WithSizeLimit(int64(len(data))))
+	ref, err = ls.Save(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ls.Load(ref); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// getCountingStorer counts the chunks retrieved from the underlying storer.
+type getCountingStorer struct {
+	storage.Storer
+	gets int64
+}
+
+func (s *getCountingStorer) Get(ctx context.Context, mode storage.ModeGet, addr swarm.Address) (swarm.Chunk, error) {
+	atomic.AddInt64(&s.gets, 1)
+	return s.Storer.Get(ctx, mode, addr)
+}
+
+func TestLoadReader(t *testing.T) {
+	ctx := context.Background()
+	storer := mock.NewStorer()
+
+	large := make([]byte, 3*swarm.ChunkSize+100)
+	for i := range large {
+		large[i] = byte(i)
+	}
+
+	ref, err := loadsave.New(ctx, storer, storage.ModePutUpload, false).Save(large)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, size, err := loadsave.NewLoader(ctx, storer).(loadsave.ReadLoader).LoadReader(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if size != int64(len(large)) {
+		t.Fatalf("got size %d, want %d", size, len(large))
+	}
+
+	var got bytes.Buffer
+	if _, err := io.CopyBuffer(struct{ io.Writer }{&got}, r, make([]byte, swarm.ChunkSize)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), large) {
+		t.Fatal("streamed data differs from the saved data")
+	}
+
+	_, _, err = loadsave.NewLoaderWithLimit(ctx, storer, swarm.ChunkSize).(loadsave.ReadLoader).LoadReader(ref)
+	if !errors.Is(err, loadsave.ErrSizeExceeded) {
+		t.Fatalf("got error %v, want %v", err, loadsave.ErrSizeExceeded)
+	}
+}
+
+func TestLoadNotFound(t *testing.T) {
+	ctx := context.Background()
+	reference := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+
+	_, err := loadsave.NewLoader(ctx, mock.NewStorer()).Load(reference.Bytes())
+	if !errors.Is(err, loadsave.ErrReferenceNotFound) {
+		t.Fatalf("got error %v, want %v", err, loadsave.ErrReferenceNotFound)
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("got error %v, want it to wrap %v", err, storage.ErrNotFound)
+	}
+
+	errStorage := errors.New("storage failure")
+	_, err = loadsave.NewLoader(ctx, &failingStorer{Storer: mock.NewStorer(), err: errStorage}).Load(reference.Bytes())
+	if !errors.Is(err, errStorage) {
+		t.Fatalf("got error %v, want %v", err, errStorage)
+	}
+	if errors.Is(err, loadsave.ErrReferenceNotFound) {
+		t.Fatalf("got error %v for a storage failure", loadsave.ErrReferenceNotFound)
+	}
+}
+
+// failingStorer fails to retrieve any chunk with the given error.
+type failingStorer struct {
+	storage.Storer
+	err error
+}
+
+func (s *failingStorer) Get(ctx context.Context, mode storage.ModeGet, addr swarm.Address) (swarm.Chunk, error) {
+	return nil, s.err
+}
+
+func TestJoinerFactory(t *testing.T) {
+	ctx := context.Background()
+	reference := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+
+	var called swarm.Address
+	factory := func(ctx context.Context, storer storage.Storer, addr swarm.Address) (io.ReadCloser, int64, error) {
+		called = addr
+		return ioutil.NopCloser(bytes.NewReader(data)), int64(len(data)), nil
+	}
+
+	got, err := loadsave.NewLoader(ctx, mock.NewStorer(), loadsave.WithJoinerFactory(factory)).Load(reference.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !called.Equal(reference) {
+		t.Fatalf("got joiner called with %s, want %s", called, reference)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("got data %q, want %q", got, data)
+	}
+
+	_, err = loadsave.NewLoader(ctx, mock.NewStorer(), loadsave.WithJoinerFactory(factory), loadsave.WithSizeLimit(1)).Load(reference.Bytes())
+	if !errors.Is(err, loadsave.ErrSizeExceeded) {
+		t.Fatalf("got error %v, want %v", err, loadsave.ErrSizeExceeded)
+	}
+}
+
+func TestSaverInfo(t *testing.T) {
+	for _, tc := range []struct {
+		mode      storage.ModePut
+		encrypted bool
+	}{
+		{mode: storage.ModePutUpload},
+		{mode: storage.ModePutUploadPin, encrypted: true},
+	} {
+		ls := loadsave.New(context.Background(), mock.NewStorer(), tc.mode, tc.encrypted)
+
+		info, ok := ls.(loadsave.SaverInfo)
+		if !ok {
+			t.Fatal("load saver does not implement SaverInfo")
+		}
+		if got := info.Mode(); got != tc.mode {
+			t.Fatalf("got mode %v, want %v", got, tc.mode)
+		}
+		if got := info.Encrypted(); got != tc.encrypted {
+			t.Fatalf("got encrypted %v, want %v", got, tc.encrypted)
+		}
+	}
+}
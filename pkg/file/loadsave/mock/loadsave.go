@@ -0,0 +1,69 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package mock provides a file.LoadSaver which keeps the data in memory, for
+// tests and manifests which are never stored.
+package mock
+
+import (
+	"bytes"
+	"context"
+	"sync"
+
+	"github.com/ethersphere/bee/pkg/file"
+	"github.com/ethersphere/bee/pkg/file/pipeline/builder"
+	"github.com/ethersphere/bee/pkg/storage"
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+type loadSaver struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+// New returns a file.LoadSaver keeping the saved data in memory. References
+// are computed by the same pipeline which saves unencrypted data to a storer,
+// so they match the references of the data stored in swarm, but the chunks
+// are discarded.
+func New() file.LoadSaver {
+	return &loadSaver{
+		data: make(map[string][]byte),
+	}
+}
+
+// Load returns a copy of the data saved with the reference or
+// storage.ErrNotFound if there is none.
+func (ls *loadSaver) Load(ref []byte) ([]byte, error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	data, ok := ls.data[string(ref)]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return append([]byte(nil), data...), nil
+}
+
+func (ls *loadSaver) Save(data []byte) ([]byte, error) {
+	ctx := context.Background()
+
+	pipe := builder.NewPipelineBuilder(ctx, discardPutter{}, storage.ModePutUpload, false)
+	address, err := builder.FeedPipeline(ctx, pipe, bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return swarm.ZeroAddress.Bytes(), err
+	}
+
+	ls.mu.Lock()
+	ls.data[string(address.Bytes())] = append([]byte(nil), data...)
+	ls.mu.Unlock()
+
+	return address.Bytes(), nil
+}
+
+// discardPutter is a storage.Putter which drops all chunks.
+type discardPutter struct{}
+
+func (discardPutter) Put(_ context.Context, _ storage.ModePut, chs ...swarm.Chunk) ([]bool, error) {
+	return make([]bool, len(chs)), nil
+}
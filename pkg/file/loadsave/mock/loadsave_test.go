@@ -0,0 +1,54 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mock_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/file/loadsave"
+	"github.com/ethersphere/bee/pkg/file/loadsave/mock"
+	"github.com/ethersphere/bee/pkg/storage"
+	storagemock "github.com/ethersphere/bee/pkg/storage/mock"
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+func TestLoadSaver(t *testing.T) {
+	ls := mock.New()
+	stored := loadsave.New(context.Background(), storagemock.NewStorer(), storage.ModePutUpload, false)
+
+	for _, size := range []int{1, swarm.ChunkSize, 3*swarm.ChunkSize + 1} {
+		data := bytes.Repeat([]byte{byte(size)}, size)
+
+		ref, err := ls.Save(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the references match the ones of data stored in swarm
+		storedRef, err := stored.Save(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(ref, storedRef) {
+			t.Fatalf("size %d: got reference %x, want %x", size, ref, storedRef)
+		}
+
+		loaded, err := ls.Load(ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(loaded, data) {
+			t.Fatalf("size %d: loaded data does not match", size)
+		}
+	}
+
+	_, err := ls.Load(swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111").Bytes())
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("got error %v, want %v", err, storage.ErrNotFound)
+	}
+}
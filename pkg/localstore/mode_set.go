@@ -78,13 +78,27 @@ func (db *DB) set(mode storage.ModeSet, addrs ...swarm.Address) (err error) {
 		}
 
 	case storage.ModeSetSync:
+		// synced chunks are counted per tag and the tags
+		// are incremented once for the whole batch
+		syncedTags := make(map[uint32]int)
 		for _, addr := range addrs {
-			c, err := db.setSync(batch, addr, mode)
+			c, err := db.setSync(batch, addr, mode, syncedTags)
 			if err != nil {
 				return err
 			}
 			gcSizeChange += c
 		}
+		for uid, n := range syncedTags {
+			t, err := db.tags.Get(uid)
+			if err != nil {
+				db.logger.Errorf("localstore: get tags on push sync set uid %d: %v", uid, err)
+				continue
+			}
+			err = t.IncN(tags.StateSynced, n)
+			if err != nil {
+				return err
+			}
+		}
 
 	case storage.ModeSetRemove:
 		for _, addr := range addrs {
@@ -197,11 +211,11 @@ func (db *DB) setAccess(batch *leveldb.Batch, binIDs map[uint8]uint64, addr swar
 }
 
 // setSync adds the chunk to the garbage collection after syncing by updating indexes
-// - ModeSetSync - the corresponding tag is incremented, then item is removed
-//   from push sync index
+// - ModeSetSync - the chunk is counted for its tag in syncedTags, then item
+//   is removed from push sync index
 // - update to gc index happens given item does not exist in pin index
 // Provided batch is updated.
-func (db *DB) setSync(batch *leveldb.Batch, addr swarm.Address, mode storage.ModeSet) (gcSizeChange int64, err error) {
+func (db *DB) setSync(batch *leveldb.Batch, addr swarm.Address, mode storage.ModeSet, syncedTags map[uint32]int) (gcSizeChange int64, err error) {
 	item := addressToItem(addr)
 
 	// need to get access timestamp here as it is not
@@ -238,17 +252,7 @@ func (db *DB) setSync(batch *leveldb.Batch, addr swarm.Address, mode storage.Mod
 		}
 	}
 	if err == nil && db.tags != nil && i.Tag != 0 {
-		t, err := db.tags.Get(i.Tag)
-		if err != nil {
-			// we cannot break or return here since the function needs to
-			// run to end from db.pushIndex.DeleteInBatch
-			db.logger.Errorf("localstore: get tags on push sync set uid %d: %v", i.Tag, err)
-		} else {
-			err = t.Inc(tags.StateSynced)
-			if err != nil {
-				return 0, err
-			}
-		}
+		syncedTags[i.Tag]++
 	}
 
 	err = db.pushIndex.DeleteInBatch(batch, item)
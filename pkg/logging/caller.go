@@ -0,0 +1,99 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxCallerDepth is the number of stack frames searched for the caller.
+const maxCallerDepth = 25
+
+// packages whose frames are skipped when looking for the caller
+var (
+	logrusPackage  = reflect.TypeOf((*logrus.Logger)(nil)).Elem().PkgPath()
+	loggingPackage = reflect.TypeOf((*logger)(nil)).Elem().PkgPath()
+)
+
+// callerFormatter adds the function and the file and line of the first
+// caller outside of logrus and this package to the entries it formats while
+// reporting the caller is enabled, so that entries logged through the helpers
+// of this package report where they were called from. The file is trimmed to
+// its directory and name, e.g. tags/tag.go. The caller reporting of logrus is
+// not used, so the stack is walked once per written entry and not at all for
+// entries which are dropped because of their level.
+type callerFormatter struct {
+	formatter logrus.Formatter
+	enabled   int32 // accessed atomically, 1 if the caller is reported
+}
+
+func (f *callerFormatter) setEnabled(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&f.enabled, v)
+}
+
+func (f *callerFormatter) Format(e *logrus.Entry) ([]byte, error) {
+	if atomic.LoadInt32(&f.enabled) == 0 {
+		return f.formatter.Format(e)
+	}
+
+	frame, ok := findCaller()
+	if !ok {
+		return f.formatter.Format(e)
+	}
+
+	// the data may be shared with the entry the logging call was made on
+	entry := *e
+	entry.Data = make(logrus.Fields, len(e.Data)+2)
+	for k, v := range e.Data {
+		entry.Data[k] = v
+	}
+	entry.Data[logrus.FieldKeyFunc] = frame.Function
+	entry.Data[logrus.FieldKeyFile] = fmt.Sprintf("%s:%d", trimCallerFile(frame.File), frame.Line)
+
+	return f.formatter.Format(&entry)
+}
+
+// findCaller returns the first stack frame outside of logrus and this
+// package.
+func findCaller() (runtime.Frame, bool) {
+	pcs := make([]uintptr, maxCallerDepth)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if pkg := packageName(frame.Function); pkg != logrusPackage && pkg != loggingPackage {
+			return frame, true
+		}
+		if !more {
+			return runtime.Frame{}, false
+		}
+	}
+}
+
+// packageName returns the package path of the fully qualified function name.
+func packageName(function string) string {
+	slash := strings.LastIndex(function, "/")
+	if dot := strings.Index(function[slash+1:], "."); dot >= 0 {
+		return function[:slash+1+dot]
+	}
+	return function
+}
+
+// trimCallerFile shortens the path of the file to its directory and name.
+func trimCallerFile(file string) string {
+	dir, name := path.Split(file)
+	return path.Join(path.Base(dir), name)
+}
@@ -0,0 +1,127 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ComponentField is the entry field key which components use to tag their
+// log entries, for example logger.WithField(logging.ComponentField, "cashout").
+const ComponentField = "component"
+
+// components keeps the level of the logger and the levels of the components.
+// All entries go through the one logrus logger, so they share its output,
+// formatter and lock. Its level is set to the most verbose of these levels,
+// so logrus passes on the entries of every component, and the filter hook
+// and the filter formatter drop the entries above the level of their
+// component.
+type components struct {
+	mu     sync.RWMutex
+	base   *logrus.Logger
+	level  logrus.Level            // level of the entries without a component level
+	levels map[string]logrus.Level // levels set for components
+}
+
+func newComponents(base *logrus.Logger) *components {
+	return &components{
+		base:   base,
+		level:  base.GetLevel(),
+		levels: make(map[string]logrus.Level),
+	}
+}
+
+// enabled returns whether the entry is within the level of its component,
+// or of the logger if the component has no level of its own.
+func (c *components) enabled(e *logrus.Entry) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	level := c.level
+	if component, ok := e.Data[ComponentField].(string); ok {
+		if l, ok := c.levels[component]; ok {
+			level = l
+		}
+	}
+	return e.Level <= level
+}
+
+// baseLevel returns the level of the entries without a component level.
+func (c *components) baseLevel() logrus.Level {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.level
+}
+
+// setComponentLevel sets the level of the component.
+func (c *components) setComponentLevel(component string, level logrus.Level) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.levels[component] = level
+	c.updateLevel()
+}
+
+// setBaseLevel sets the level of the entries without a component level.
+func (c *components) setBaseLevel(level logrus.Level) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.level = level
+	c.updateLevel()
+}
+
+// updateLevel sets the logrus logger to the most verbose level. It must be
+// called with the lock held.
+func (c *components) updateLevel() {
+	level := c.level
+	for _, l := range c.levels {
+		if l > level {
+			level = l
+		}
+	}
+	c.base.SetLevel(level)
+}
+
+// filterHook fires the hooks only for the entries which are enabled for
+// their component. It is the only hook of the logrus logger.
+type filterHook struct {
+	components *components
+	hooks      logrus.LevelHooks
+}
+
+func (h *filterHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *filterHook) Fire(e *logrus.Entry) error {
+	if !h.components.enabled(e) {
+		return nil
+	}
+	for _, hook := range h.hooks[e.Level] {
+		if err := hook.Fire(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filterFormatter formats only the entries which are enabled for their
+// component. logrus writes the empty output of the others, which leaves
+// the output unchanged.
+type filterFormatter struct {
+	components *components
+	formatter  logrus.Formatter
+}
+
+func (f *filterFormatter) Format(e *logrus.Entry) ([]byte, error) {
+	if !f.components.enabled(e) {
+		return nil, nil
+	}
+	return f.formatter.Format(e)
+}
@@ -6,6 +6,7 @@ package logging
 
 import (
 	"io"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -21,32 +22,87 @@ type Logger interface {
 	Warning(args ...interface{})
 	Errorf(format string, args ...interface{})
 	Error(args ...interface{})
+	// ErrorfThrottled logs like Errorf, but suppresses messages with the same
+	// key for the duration of every after one was logged.
+	ErrorfThrottled(key string, every time.Duration, format string, args ...interface{})
 	WithField(key string, value interface{}) *logrus.Entry
 	WithFields(fields logrus.Fields) *logrus.Entry
 	WriterLevel(logrus.Level) *io.PipeWriter
 	NewEntry() *logrus.Entry
+	// SetComponentLevel sets the level for entries tagged with the component
+	// field, overriding the logger level for that component.
+	SetComponentLevel(component string, level logrus.Level)
+	// SetLevel changes the logger level at runtime. It is safe to call while
+	// other goroutines are logging.
+	SetLevel(level logrus.Level)
+	// GetLevel returns the logger level.
+	GetLevel() logrus.Level
+	// SetReportCaller sets whether entries include the file and line they
+	// were logged from. It is disabled by default, as the caller is looked
+	// up for every written entry.
+	SetReportCaller(include bool)
 }
 
 type logger struct {
 	*logrus.Logger
-	metrics metrics
+	metrics    metrics
+	caller     *callerFormatter
+	components *components
+	throttle   *throttle
 }
 
 func New(w io.Writer, level logrus.Level) Logger {
+	return newLogger(w, level, &logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+}
+
+// NewJSON returns a logger which writes every entry as a JSON object with an
+// RFC3339 timestamp and the entry fields as top-level keys.
+func NewJSON(w io.Writer, level logrus.Level) Logger {
+	return newLogger(w, level, &logrus.JSONFormatter{
+		TimestampFormat: time.RFC3339,
+	})
+}
+
+func newLogger(w io.Writer, level logrus.Level, formatter logrus.Formatter) Logger {
 	l := logrus.New()
 	l.SetOutput(w)
 	l.SetLevel(level)
-	l.Formatter = &logrus.TextFormatter{
-		FullTimestamp: true,
-	}
+	components := newComponents(l)
+	caller := &callerFormatter{formatter: formatter}
+	l.Formatter = &filterFormatter{components: components, formatter: caller}
 	metrics := newMetrics()
-	l.AddHook(metrics)
+	hooks := make(logrus.LevelHooks)
+	hooks.Add(metrics)
+	l.AddHook(&filterHook{components: components, hooks: hooks})
 	return &logger{
-		Logger:  l,
-		metrics: metrics,
+		Logger:     l,
+		metrics:    metrics,
+		caller:     caller,
+		components: components,
+		throttle:   newThrottle(),
 	}
 }
 
 func (l *logger) NewEntry() *logrus.Entry {
 	return logrus.NewEntry(l.Logger)
 }
+
+func (l *logger) SetComponentLevel(component string, level logrus.Level) {
+	l.components.setComponentLevel(component, level)
+}
+
+func (l *logger) SetLevel(level logrus.Level) {
+	l.components.setBaseLevel(level)
+}
+
+// GetLevel returns the level of the entries without a component level. The
+// logrus logger may be set to a more verbose level of a component.
+func (l *logger) GetLevel() logrus.Level {
+	return l.components.baseLevel()
+}
+
+func (l *logger) SetReportCaller(include bool) {
+	l.caller.setEnabled(include)
+}
@@ -0,0 +1,264 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+)
+
+func TestComponentLevel(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := logging.New(buf, logrus.InfoLevel)
+
+	logger.SetComponentLevel("cashout", logrus.DebugLevel)
+
+	logger.WithField(logging.ComponentField, "cashout").Debug("cashout debug line")
+	logger.WithField(logging.ComponentField, "tags").Debug("tags debug line")
+	logger.Debug("untagged debug line")
+	logger.WithField(logging.ComponentField, "tags").Info("tags info line")
+
+	out := buf.String()
+	if !strings.Contains(out, "cashout debug line") {
+		t.Fatalf("debug line of verbose component suppressed: %q", out)
+	}
+	if strings.Contains(out, "tags debug line") {
+		t.Fatalf("debug line of quiet component emitted: %q", out)
+	}
+	if strings.Contains(out, "untagged debug line") {
+		t.Fatalf("untagged debug line emitted: %q", out)
+	}
+	if !strings.Contains(out, "tags info line") {
+		t.Fatalf("info line of quiet component suppressed: %q", out)
+	}
+}
+
+func TestComponentLevelMetrics(t *testing.T) {
+	logger := logging.New(ioutil.Discard, logrus.InfoLevel)
+	logger.SetComponentLevel("cashout", logrus.DebugLevel)
+
+	logger.WithField(logging.ComponentField, "cashout").Debug("cashout debug line")
+	logger.WithFields(logrus.Fields{logging.ComponentField: "tags"}).Debug("tags debug line")
+	logger.Debug("untagged debug line")
+
+	// only the emitted debug line is counted
+	for _, c := range logger.(interface {
+		Metrics() []prometheus.Collector
+	}).Metrics() {
+		metric := c.(prometheus.Metric)
+		if !strings.Contains(metric.Desc().String(), "debug_count") {
+			continue
+		}
+		var m dto.Metric
+		if err := metric.Write(&m); err != nil {
+			t.Fatal(err)
+		}
+		if got := m.GetCounter().GetValue(); got != 1 {
+			t.Fatalf("got %v debug lines counted, want 1", got)
+		}
+		return
+	}
+	t.Fatal("no debug line metric")
+}
+
+func TestNewJSON(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := logging.NewJSON(buf, logrus.InfoLevel)
+
+	logger.WithFields(logrus.Fields{
+		"peer":  "abcd",
+		"count": 3,
+	}).Info("json line")
+	logger.Debug("suppressed line")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("output is not a single JSON object: %v: %q", err, buf.String())
+	}
+
+	for key, want := range map[string]interface{}{
+		"msg":   "json line",
+		"level": "info",
+		"peer":  "abcd",
+		"count": float64(3),
+	} {
+		if got := entry[key]; got != want {
+			t.Fatalf("got %s %v, want %v", key, got, want)
+		}
+	}
+
+	ts, ok := entry["time"].(string)
+	if !ok {
+		t.Fatalf("got time %v, want a string", entry["time"])
+	}
+	if _, err := time.Parse(time.RFC3339, ts); err != nil {
+		t.Fatalf("time is not RFC3339: %v", err)
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := logging.New(buf, logrus.InfoLevel)
+	logger.SetComponentLevel("cashout", logrus.DebugLevel)
+
+	logger.SetLevel(logrus.TraceLevel)
+	if got := logger.GetLevel(); got != logrus.TraceLevel {
+		t.Fatalf("got level %v, want %v", got, logrus.TraceLevel)
+	}
+	logger.Trace("trace line")
+	if !strings.Contains(buf.String(), "trace line") {
+		t.Fatalf("trace line suppressed after raising the level: %q", buf.String())
+	}
+
+	buf.Reset()
+	logger.SetLevel(logrus.InfoLevel)
+	if got := logger.GetLevel(); got != logrus.InfoLevel {
+		t.Fatalf("got level %v, want %v", got, logrus.InfoLevel)
+	}
+	logger.Debug("untagged debug line")
+	logger.WithField(logging.ComponentField, "cashout").Debug("cashout debug line")
+	out := buf.String()
+	if strings.Contains(out, "untagged debug line") {
+		t.Fatalf("debug line emitted after lowering the level: %q", out)
+	}
+	if !strings.Contains(out, "cashout debug line") {
+		t.Fatalf("component level lost after lowering the level: %q", out)
+	}
+}
+
+func TestSetLevelConcurrent(t *testing.T) {
+	logger := logging.New(ioutil.Discard, logrus.InfoLevel)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				logger.Debug("debug line")
+				logger.Info("info line")
+			}
+		}()
+	}
+	for j := 0; j < 100; j++ {
+		logger.SetLevel(logrus.TraceLevel)
+		logger.SetLevel(logrus.InfoLevel)
+	}
+	wg.Wait()
+}
+
+func TestNoop(t *testing.T) {
+	logger := logging.Noop()
+
+	logger.SetLevel(logrus.TraceLevel)
+	logger.Trace("trace line")
+	logger.Errorf("error line %d", 1)
+	logger.WithField("key", "value").Info("entry line")
+	logger.WithFields(logrus.Fields{"key": "value"}).Error("entry line")
+	logger.NewEntry().Warning("entry line")
+
+	w := logger.WriterLevel(logrus.InfoLevel)
+	if _, err := w.Write([]byte("writer line\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestErrorfThrottled(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := logging.New(buf, logrus.InfoLevel)
+
+	for i := 0; i < 3; i++ {
+		logger.ErrorfThrottled("rpc", time.Hour, "rpc failure %d", i)
+	}
+	logger.ErrorfThrottled("other", time.Hour, "other failure")
+
+	out := buf.String()
+	if !strings.Contains(out, "rpc failure 0") {
+		t.Fatalf("first message suppressed: %q", out)
+	}
+	if strings.Contains(out, "rpc failure 1") || strings.Contains(out, "rpc failure 2") {
+		t.Fatalf("repeated message not suppressed: %q", out)
+	}
+	if !strings.Contains(out, "other failure") {
+		t.Fatalf("message with another key suppressed: %q", out)
+	}
+
+	buf.Reset()
+	logger.ErrorfThrottled("short", time.Millisecond, "short failure %d", 0)
+	logger.ErrorfThrottled("short", time.Hour, "short failure %d", 1)
+	logger.ErrorfThrottled("short", time.Hour, "short failure %d", 2)
+	time.Sleep(10 * time.Millisecond)
+	logger.ErrorfThrottled("short", time.Millisecond, "short failure %d", 3)
+
+	out = buf.String()
+	if !strings.Contains(out, "short failure 3 (suppressed 2 times)") {
+		t.Fatalf("message after the window without suppressed count: %q", out)
+	}
+}
+
+func TestReportCaller(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := logging.NewJSON(buf, logrus.InfoLevel)
+
+	logger.Info("line without caller")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := entry["file"]; ok {
+		t.Fatalf("got caller file with reporting disabled by default: %q", buf.String())
+	}
+
+	logger.SetReportCaller(true)
+
+	lines := map[string]func(){
+		"direct":    func() { logger.Info("caller line") },
+		"entry":     func() { logger.WithField("key", "value").Info("caller line") },
+		"component": func() { logger.WithField(logging.ComponentField, "test").Info("caller line") },
+		"throttled": func() { logger.ErrorfThrottled("caller", time.Hour, "caller line") },
+	}
+	for name, log := range lines {
+		buf.Reset()
+		log()
+
+		var entry map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Fatalf("%s: %v: %q", name, err, buf.String())
+		}
+		file, ok := entry["file"].(string)
+		if !ok {
+			t.Fatalf("%s: got no caller file in %q", name, buf.String())
+		}
+		if !strings.HasPrefix(file, "logging/logging_test.go:") {
+			t.Fatalf("%s: got caller file %q, want logging/logging_test.go with the line", name, file)
+		}
+	}
+
+	buf.Reset()
+	logger.SetReportCaller(false)
+	logger.Info("line without caller")
+
+	entry = nil
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := entry["file"]; ok {
+		t.Fatalf("got caller file with reporting disabled: %q", buf.String())
+	}
+}
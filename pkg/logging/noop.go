@@ -0,0 +1,66 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type noopLogger struct {
+	// entries are created from a logger which discards everything and
+	// enables no level, so that they never run the formatter
+	logger *logrus.Logger
+}
+
+// Noop returns a logger which discards everything, for tests and for
+// embedding components which should not log.
+func Noop() Logger {
+	l := logrus.New()
+	l.SetOutput(ioutil.Discard)
+	l.SetLevel(logrus.PanicLevel)
+	return &noopLogger{logger: l}
+}
+
+func (*noopLogger) Tracef(format string, args ...interface{})   {}
+func (*noopLogger) Trace(args ...interface{})                   {}
+func (*noopLogger) Debugf(format string, args ...interface{})   {}
+func (*noopLogger) Debug(args ...interface{})                   {}
+func (*noopLogger) Infof(format string, args ...interface{})    {}
+func (*noopLogger) Info(args ...interface{})                    {}
+func (*noopLogger) Warningf(format string, args ...interface{}) {}
+func (*noopLogger) Warning(args ...interface{})                 {}
+func (*noopLogger) Errorf(format string, args ...interface{})   {}
+func (*noopLogger) Error(args ...interface{})                   {}
+
+func (*noopLogger) ErrorfThrottled(key string, every time.Duration, format string, args ...interface{}) {
+}
+
+func (l *noopLogger) WithField(key string, value interface{}) *logrus.Entry {
+	return l.NewEntry()
+}
+
+func (l *noopLogger) WithFields(fields logrus.Fields) *logrus.Entry {
+	return l.NewEntry()
+}
+
+func (l *noopLogger) WriterLevel(level logrus.Level) *io.PipeWriter {
+	return l.logger.WriterLevel(level)
+}
+
+func (l *noopLogger) NewEntry() *logrus.Entry {
+	return logrus.NewEntry(l.logger)
+}
+
+func (*noopLogger) SetComponentLevel(component string, level logrus.Level) {}
+func (*noopLogger) SetLevel(level logrus.Level)                            {}
+func (*noopLogger) SetReportCaller(include bool)                           {}
+
+func (*noopLogger) GetLevel() logrus.Level {
+	return logrus.PanicLevel
+}
@@ -0,0 +1,97 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxThrottleKeys is the number of keys the throttle keeps state for. Once
+// it is reached, keys whose window has passed are dropped and, if that is
+// not enough, the key which fired longest ago.
+const maxThrottleKeys = 1024
+
+// throttle tracks when messages were last logged per key and how many were
+// suppressed since then.
+type throttle struct {
+	mu      sync.Mutex
+	entries map[string]*throttleEntry
+}
+
+type throttleEntry struct {
+	last       time.Time
+	every      time.Duration
+	suppressed int
+}
+
+func newThrottle() *throttle {
+	return &throttle{
+		entries: make(map[string]*throttleEntry),
+	}
+}
+
+// allow reports whether a message with the key may be logged at the given
+// time, together with the number of messages suppressed since the key last
+// fired.
+func (t *throttle) allow(key string, every time.Duration, now time.Time) (suppressed int, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e, exists := t.entries[key]
+	if exists && now.Sub(e.last) < every {
+		e.suppressed++
+		return 0, false
+	}
+
+	if !exists {
+		if len(t.entries) >= maxThrottleKeys {
+			t.evict(now)
+		}
+		e = new(throttleEntry)
+		t.entries[key] = e
+	}
+
+	suppressed = e.suppressed
+	*e = throttleEntry{last: now, every: every}
+
+	return suppressed, true
+}
+
+// evict drops the keys whose window has passed or, if there are none, the
+// key which fired longest ago. It must be called with the lock held.
+func (t *throttle) evict(now time.Time) {
+	var oldestKey string
+	var oldest time.Time
+	for key, e := range t.entries {
+		if now.Sub(e.last) >= e.every {
+			delete(t.entries, key)
+			continue
+		}
+		if oldestKey == "" || e.last.Before(oldest) {
+			oldestKey, oldest = key, e.last
+		}
+	}
+	if len(t.entries) >= maxThrottleKeys {
+		delete(t.entries, oldestKey)
+	}
+}
+
+// ErrorfThrottled logs the error like Errorf, unless a message with the same
+// key was logged within the window. Once the window has passed, the next
+// message reports how many messages were suppressed in the meantime.
+func (l *logger) ErrorfThrottled(key string, every time.Duration, format string, args ...interface{}) {
+	suppressed, ok := l.throttle.allow(key, every, time.Now())
+	if !ok {
+		return
+	}
+
+	msg := fmt.Sprintf(format, args...)
+	if suppressed > 0 {
+		msg = fmt.Sprintf("%s (suppressed %d times)", msg, suppressed)
+	}
+	l.Error(msg)
+}
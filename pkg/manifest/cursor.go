@@ -0,0 +1,134 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrInvalidCursor is returned when a cursor passed to WalkFrom can not
+	// be decoded.
+	ErrInvalidCursor = errors.New("manifest: invalid cursor")
+
+	errStopWalk = errors.New("stop walk")
+)
+
+// walkFrom implements WalkFrom on top of a function walking all entries in
+// sorted path order. The cursor is the URL safe base64 encoding of the last
+// returned path, so it remains valid even if entries are added or removed
+// between calls.
+func walkFrom(walk func(fn func(path string, entry Entry) error) error, cursor string, limit int) ([]PathEntry, string, error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("invalid limit %d", limit)
+	}
+
+	var (
+		after    string
+		hasAfter bool
+	)
+	if cursor != "" {
+		p, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return nil, "", ErrInvalidCursor
+		}
+		after, hasAfter = string(p), true
+	}
+
+	var (
+		entries []PathEntry
+		more    bool
+	)
+	err := withoutRoot(walk)(func(path string, entry Entry) error {
+		if hasAfter && path <= after {
+			return nil
+		}
+		if len(entries) == limit {
+			more = true
+			return errStopWalk
+		}
+		entries = append(entries, PathEntry{Path: path, Entry: entry})
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStopWalk) {
+		return nil, "", err
+	}
+
+	if !more {
+		return entries, "", nil
+	}
+
+	return entries, base64.RawURLEncoding.EncodeToString([]byte(entries[len(entries)-1].Path)), nil
+}
+
+// iterate implements Iterate on top of a function walking all entries in
+// sorted path order.
+func iterate(walk func(fn func(path string, entry Entry) error) error, prefix string, fn func(path string, entry Entry) (stop bool, err error)) error {
+	err := withoutRoot(walk)(func(path string, entry Entry) error {
+		if !strings.HasPrefix(path, prefix) {
+			return nil
+		}
+		stop, err := fn(path, entry)
+		if err != nil {
+			return err
+		}
+		if stop {
+			return errStopWalk
+		}
+		return nil
+	})
+	if errors.Is(err, errStopWalk) {
+		return nil
+	}
+	return err
+}
+
+// withoutRoot wraps a function walking all entries so that it skips the
+// RootPath entry, which only holds the manifest-level metadata.
+func withoutRoot(walk func(fn func(path string, entry Entry) error) error) func(fn func(path string, entry Entry) error) error {
+	return func(fn func(path string, entry Entry) error) error {
+		return walk(func(path string, entry Entry) error {
+			if path == RootPath {
+				return nil
+			}
+			return fn(path, entry)
+		})
+	}
+}
+
+// listPrefix implements ListPrefix on top of a function walking all entries.
+func listPrefix(walk func(fn func(path string, entry Entry) error) error, prefix string) ([]string, error) {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	seen := make(map[string]struct{})
+	var names []string
+	err := iterate(walk, prefix, func(path string, _ Entry) (bool, error) {
+		name := strings.TrimPrefix(path, prefix)
+		if name == "" {
+			return false, nil
+		}
+		if i := strings.Index(name, "/"); i >= 0 {
+			name = name[:i+1]
+		}
+		if _, ok := seen[name]; !ok {
+			seen[name] = struct{}{}
+			names = append(names, name)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethersphere/bee/pkg/crypto"
+	"github.com/ethersphere/bee/pkg/file"
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+const (
+	// mantarayVersionString is the version marker which mantaray embeds in
+	// every serialized node as the first bytes of its keccak256 hash.
+	mantarayVersionString = "mantaray:0.1"
+	// mantarayObfuscationKeySize is the size of the obfuscation key
+	// prepended to every serialized mantaray node.
+	mantarayObfuscationKeySize = 32
+	// mantarayVersionHashSize is the number of version hash bytes stored
+	// after the obfuscation key.
+	mantarayVersionHashSize = 31
+)
+
+// DetectType loads the root of the manifest on the reference and determines
+// its type from the format markers. It returns the content type constant
+// usable with NewManifestReference, or ErrInvalidManifestType if the data is
+// not a known manifest.
+func DetectType(ctx context.Context, reference swarm.Address, ls file.LoadSaver) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	data, err := ls.Load(reference.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("manifest load error: %w", err)
+	}
+
+	return detectType(data)
+}
+
+// detectType classifies the serialized root of a manifest.
+func detectType(data []byte) (string, error) {
+	if isMantaray(data) {
+		return ManifestMantarayContentType, nil
+	}
+
+	if isSimpleCompact(data) {
+		return ManifestSimpleCompactContentType, nil
+	}
+
+	if isSimple(data) {
+		return ManifestSimpleContentType, nil
+	}
+
+	return "", ErrInvalidManifestType
+}
+
+// isMantaray checks for the obfuscated mantaray version hash.
+func isMantaray(data []byte) bool {
+	if len(data) < mantarayObfuscationKeySize+mantarayVersionHashSize {
+		return false
+	}
+
+	versionHash, err := crypto.LegacyKeccak256([]byte(mantarayVersionString))
+	if err != nil {
+		return false
+	}
+
+	key := data[:mantarayObfuscationKeySize]
+	version := make([]byte, mantarayVersionHashSize)
+	for i := range version {
+		version[i] = data[mantarayObfuscationKeySize+i] ^ key[i%len(key)]
+	}
+
+	return bytes.Equal(version, versionHash[:mantarayVersionHashSize])
+}
+
+// isSimple checks whether the data is a JSON object in the simple manifest
+// serialization format.
+func isSimple(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '{' {
+		return false
+	}
+
+	var serialized struct {
+		Entries map[string]json.RawMessage `json:"entries"`
+	}
+
+	return json.Unmarshal(data, &serialized) == nil
+}
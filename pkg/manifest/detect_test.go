@@ -0,0 +1,62 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/file/loadsave"
+	"github.com/ethersphere/bee/pkg/manifest"
+	"github.com/ethersphere/bee/pkg/storage"
+	"github.com/ethersphere/bee/pkg/storage/mock"
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+func TestDetectType(t *testing.T) {
+	ctx := context.Background()
+	reference := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+
+	for _, manifestType := range manifestTypes {
+		t.Run(manifestType, func(t *testing.T) {
+			storer := mock.NewStorer()
+
+			m, err := manifest.NewManifest(manifestType, false, storer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := m.Add("index.html", manifest.NewEntry(reference, nil)); err != nil {
+				t.Fatal(err)
+			}
+			address, err := m.Store(ctx, storage.ModePutUpload)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			detected, err := manifest.DetectType(ctx, address, loadsave.NewLoader(ctx, storer))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if detected != manifestType {
+				t.Fatalf("got type %s, want %s", detected, manifestType)
+			}
+		})
+	}
+
+	t.Run("not a manifest", func(t *testing.T) {
+		ls := loadsave.New(ctx, mock.NewStorer(), storage.ModePutUpload, false)
+
+		address, err := ls.Save([]byte("this is not a manifest, just some plain text"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = manifest.DetectType(ctx, swarm.NewAddress(address), ls)
+		if !errors.Is(err, manifest.ErrInvalidManifestType) {
+			t.Fatalf("got error %v, want %v", err, manifest.ErrInvalidManifestType)
+		}
+	})
+}
@@ -0,0 +1,104 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ChangeType is the kind of change of a path between two manifests.
+type ChangeType int
+
+const (
+	// ChangeAdded is the change of a path which only exists in the new
+	// manifest.
+	ChangeAdded ChangeType = iota
+	// ChangeRemoved is the change of a path which only exists in the old
+	// manifest.
+	ChangeRemoved
+	// ChangeModified is the change of a path which exists in both manifests
+	// with a different reference or metadata.
+	ChangeModified
+)
+
+func (t ChangeType) String() string {
+	switch t {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "modified"
+	default:
+		return fmt.Sprintf("ChangeType(%d)", int(t))
+	}
+}
+
+// ManifestChange is a path which differs between two manifests. Old is nil
+// for added paths and New is nil for removed paths.
+type ManifestChange struct {
+	Path string
+	Type ChangeType
+	Old  Entry
+	New  Entry
+}
+
+// Diff returns the changes from the old to the new manifest in sorted path
+// order. The manifests do not need to be of the same type. Changes of the
+// root metadata are not included, as Iterate does not list it.
+func Diff(oldManifest, newManifest Interface) ([]ManifestChange, error) {
+	oldEntries, err := collectEntries(oldManifest)
+	if err != nil {
+		return nil, fmt.Errorf("iterate old manifest: %w", err)
+	}
+	newEntries, err := collectEntries(newManifest)
+	if err != nil {
+		return nil, fmt.Errorf("iterate new manifest: %w", err)
+	}
+
+	var changes []ManifestChange
+	for len(oldEntries) > 0 || len(newEntries) > 0 {
+		switch {
+		case len(newEntries) == 0 || (len(oldEntries) > 0 && oldEntries[0].Path < newEntries[0].Path):
+			changes = append(changes, ManifestChange{Path: oldEntries[0].Path, Type: ChangeRemoved, Old: oldEntries[0].Entry})
+			oldEntries = oldEntries[1:]
+		case len(oldEntries) == 0 || newEntries[0].Path < oldEntries[0].Path:
+			changes = append(changes, ManifestChange{Path: newEntries[0].Path, Type: ChangeAdded, New: newEntries[0].Entry})
+			newEntries = newEntries[1:]
+		default:
+			o, n := oldEntries[0], newEntries[0]
+			if !entriesEqual(o.Entry, n.Entry) {
+				changes = append(changes, ManifestChange{Path: o.Path, Type: ChangeModified, Old: o.Entry, New: n.Entry})
+			}
+			oldEntries, newEntries = oldEntries[1:], newEntries[1:]
+		}
+	}
+
+	return changes, nil
+}
+
+// collectEntries returns all entries of the manifest in sorted path order.
+func collectEntries(m Interface) ([]PathEntry, error) {
+	var entries []PathEntry
+	err := m.Iterate("", func(path string, entry Entry) (bool, error) {
+		entries = append(entries, PathEntry{Path: path, Entry: entry})
+		return false, nil
+	})
+	return entries, err
+}
+
+// entriesEqual reports whether the entries have the same reference and
+// metadata. Missing and empty metadata are considered equal.
+func entriesEqual(a, b Entry) bool {
+	if !a.Reference().Equal(b.Reference()) {
+		return false
+	}
+	am, bm := a.Metadata(), b.Metadata()
+	if len(am) == 0 && len(bm) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(am, bm)
+}
@@ -0,0 +1,89 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest_test
+
+import (
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/manifest"
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+func TestDiff(t *testing.T) {
+	first := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+	second := swarm.MustParseHexAddress("2222222222222222222222222222222222222222222222222222222222222222")
+
+	for _, oldType := range manifestTypes {
+		for _, newType := range manifestTypes {
+			t.Run(oldType+"/"+newType, func(t *testing.T) {
+				old, err := manifest.NewManifest(oldType, false, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = old.AddBatch(map[string]manifest.Entry{
+					"index.html":  manifest.NewEntry(first, nil),
+					"style.css":   manifest.NewEntry(first, nil),
+					"app.js":      manifest.NewEntry(first, map[string]string{"name": "app.js"}),
+					"removed.txt": manifest.NewEntry(first, nil),
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				updated, err := manifest.NewManifest(newType, false, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = updated.AddBatch(map[string]manifest.Entry{
+					"index.html": manifest.NewEntry(first, nil),
+					"style.css":  manifest.NewEntry(second, nil),
+					"app.js":     manifest.NewEntry(first, map[string]string{"name": "main.js"}),
+					"added.txt":  manifest.NewEntry(second, nil),
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				changes, err := manifest.Diff(old, updated)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				want := []struct {
+					path       string
+					changeType manifest.ChangeType
+				}{
+					{path: "added.txt", changeType: manifest.ChangeAdded},
+					{path: "app.js", changeType: manifest.ChangeModified},
+					{path: "removed.txt", changeType: manifest.ChangeRemoved},
+					{path: "style.css", changeType: manifest.ChangeModified},
+				}
+				if len(changes) != len(want) {
+					t.Fatalf("got %d changes, want %d: %v", len(changes), len(want), changes)
+				}
+				for i, w := range want {
+					c := changes[i]
+					if c.Path != w.path || c.Type != w.changeType {
+						t.Fatalf("change %d: got %s %s, want %s %s", i, c.Type, c.Path, w.changeType, w.path)
+					}
+					if (c.Old == nil) != (c.Type == manifest.ChangeAdded) {
+						t.Fatalf("change %d: unexpected old entry %v", i, c.Old)
+					}
+					if (c.New == nil) != (c.Type == manifest.ChangeRemoved) {
+						t.Fatalf("change %d: unexpected new entry %v", i, c.New)
+					}
+				}
+
+				changes, err = manifest.Diff(updated, updated)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(changes) != 0 {
+					t.Fatalf("got changes %v between equal manifests", changes)
+				}
+			})
+		}
+	}
+}
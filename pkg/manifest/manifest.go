@@ -7,6 +7,9 @@ package manifest
 import (
 	"context"
 	"errors"
+	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/ethersphere/bee/pkg/storage"
 	"github.com/ethersphere/bee/pkg/swarm"
@@ -14,6 +17,19 @@ import (
 
 const DefaultManifestType = ManifestMantarayContentType
 
+const (
+	// EntryMetadataExpiresKey is the metadata key holding the time after which
+	// the entry should no longer be served. The value is either an HTTP-date
+	// or a unix timestamp in seconds.
+	EntryMetadataExpiresKey = "Expires"
+	// EntryMetadataContentTypeKey is the metadata key holding the MIME type
+	// of the file.
+	EntryMetadataContentTypeKey = "Content-Type"
+	// EntryMetadataFilenameKey is the metadata key holding the name of the
+	// file.
+	EntryMetadataFilenameKey = "Filename"
+)
+
 var (
 	// ErrNotFound is returned when an Entry is not found in the manifest.
 	ErrNotFound = errors.New("manifest: not found")
@@ -21,6 +37,10 @@ var (
 	// ErrInvalidManifestType is returned when an unknown manifest type
 	// is provided to the function.
 	ErrInvalidManifestType = errors.New("manifest: invalid type")
+
+	// ErrEmptyMetadata is returned when the metadata of an entry is updated
+	// with empty metadata.
+	ErrEmptyMetadata = errors.New("manifest: empty metadata")
 )
 
 // Interface for operations with manifest.
@@ -29,14 +49,83 @@ type Interface interface {
 	Type() string
 	// Add a manifest entry to the specified path.
 	Add(string, Entry) error
+	// AddBatch adds all entries keyed by their paths, replacing existing
+	// entries, before any of them are stored.
+	AddBatch(map[string]Entry) error
+	// UpdateMetadata replaces the metadata of the entry on the specified
+	// path, keeping its reference. Empty metadata is rejected with
+	// ErrEmptyMetadata.
+	UpdateMetadata(string, map[string]string) error
 	// Remove a manifest entry on the specified path.
 	Remove(string) error
+	// RemovePrefix removes all entries with a path starting with the prefix,
+	// returning their number, or ErrNotFound if there were none. The RootPath
+	// entry with the root metadata is kept.
+	RemovePrefix(string) (int, error)
 	// Lookup returns a manifest entry if one is found in the specified path.
 	Lookup(string) (Entry, error)
+	// SetRootMetadata replaces the manifest-level metadata, which is kept on
+	// the RootPath entry. Empty metadata removes the entry. The RootPath
+	// entry can be looked up, but it is not listed by WalkFrom, Iterate and
+	// ListPrefix, nor counted by EntryCount.
+	SetRootMetadata(map[string]string) error
+	// RootMetadata returns the manifest-level metadata, or nil if none is
+	// set.
+	RootMetadata() (map[string]string, error)
 	// HasPrefix tests whether the specified prefix path exists.
 	HasPrefix(string) (bool, error)
-	// Store stores the manifest, returning the resulting address.
+	// Store stores the manifest, returning the resulting address. An already
+	// stored manifest which has not been modified is not stored again.
 	Store(context.Context, storage.ModePut) (swarm.Address, error)
+	// StoreContext stores the manifest like Store, calling progress with the
+	// number of chunks of the manifest which were saved so far. It stops once
+	// the context is done and returns the context error, leaving the manifest
+	// modified and without a stored root.
+	StoreContext(ctx context.Context, mode storage.ModePut, progress func(saved int)) (swarm.Address, error)
+	// RewriteReferences replaces the references of all entries which are keyed
+	// in the mapping by their hex encoded address, preserving the metadata.
+	// It returns the number of changed entries.
+	RewriteReferences(map[string]swarm.Address) (int, error)
+	// Compact rebuilds the manifest into a minimal structure, preserving all
+	// entries and their metadata.
+	Compact() error
+	// Clone returns a copy of the manifest which can be modified without
+	// affecting the original.
+	Clone() (Interface, error)
+	// WalkFrom returns up to limit entries in sorted path order, starting
+	// after the entry the cursor points to, and the cursor to pass to the next
+	// call. An empty cursor starts from the beginning, an empty next cursor
+	// means that there are no more entries.
+	WalkFrom(cursor string, limit int) ([]PathEntry, string, error)
+	// Iterate calls fn for every entry with a path starting with the prefix
+	// in sorted path order, until fn returns stop or an error.
+	Iterate(prefix string, fn func(path string, entry Entry) (stop bool, err error)) error
+	// ListPrefix returns the sorted distinct names of the next path segment
+	// of the entries under the prefix, like listing a directory. The prefix
+	// is treated as a directory whether or not it ends with a slash, and
+	// names of segments which have entries below them end with a slash.
+	ListPrefix(prefix string) ([]string, error)
+	// EntryCount returns the number of entries in the manifest. Depending on
+	// the implementation this may load the whole manifest.
+	EntryCount() (int, error)
+	// Modified reports whether the manifest has been changed since it was
+	// created, loaded or last stored.
+	Modified() bool
+	// StorageSize returns the number of bytes the manifest itself occupies
+	// in storage, not counting the data its entries reference. Depending on
+	// the implementation this may load the whole manifest.
+	StorageSize() (int64, error)
+	// Validate loads the data of every entry, and every node of manifests
+	// made of several nodes, returning the ones which could not be loaded
+	// instead of stopping at the first. It returns an error if the manifest
+	// could not be walked or the context is done.
+	Validate(ctx context.Context) ([]ValidationError, error)
+}
+
+// PathEntry is a manifest entry together with its path.
+type PathEntry struct {
+	Path  string
+	Entry Entry
 }
 
 // Entry represents a single manifest entry.
@@ -45,6 +134,8 @@ type Entry interface {
 	Reference() swarm.Address
 	// Metadata returns the metadata of the file.
 	Metadata() map[string]string
+	// Expires returns the expiry time of the entry, if one is set.
+	Expires() (time.Time, bool)
 }
 
 // NewDefaultManifest creates a new manifest with default type.
@@ -64,6 +155,8 @@ func NewManifest(
 	switch manifestType {
 	case ManifestSimpleContentType:
 		return NewSimpleManifest(encrypted, storer)
+	case ManifestSimpleCompactContentType:
+		return NewSimpleCompactManifest(encrypted, storer)
 	case ManifestMantarayContentType:
 		return NewMantarayManifest(encrypted, storer)
 	default:
@@ -82,6 +175,8 @@ func NewManifestReference(
 	switch manifestType {
 	case ManifestSimpleContentType:
 		return NewSimpleManifestReference(ctx, reference, encrypted, storer)
+	case ManifestSimpleCompactContentType:
+		return NewSimpleCompactManifestReference(ctx, reference, encrypted, storer)
 	case ManifestMantarayContentType:
 		return NewMantarayManifestReference(ctx, reference, encrypted, storer)
 	default:
@@ -109,3 +204,37 @@ func (e *manifestEntry) Reference() swarm.Address {
 func (e *manifestEntry) Metadata() map[string]string {
 	return e.metadata
 }
+
+func (e *manifestEntry) Expires() (time.Time, bool) {
+	return parseExpires(e.metadata)
+}
+
+// EntryName returns the file name stored in the entry metadata, or an empty
+// string if there is none.
+func EntryName(e Entry) string {
+	return e.Metadata()[EntryMetadataFilenameKey]
+}
+
+// EntryMimeType returns the MIME type stored in the entry metadata, or an
+// empty string if there is none.
+func EntryMimeType(e Entry) string {
+	return e.Metadata()[EntryMetadataContentTypeKey]
+}
+
+// parseExpires reads the expiry time from the entry metadata.
+func parseExpires(metadata map[string]string) (time.Time, bool) {
+	v, ok := metadata[EntryMetadataExpiresKey]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		return t, true
+	}
+
+	if sec, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(sec, 0), true
+	}
+
+	return time.Time{}, false
+}
@@ -0,0 +1,1078 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/manifest"
+	"github.com/ethersphere/bee/pkg/storage"
+	"github.com/ethersphere/bee/pkg/storage/mock"
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+var manifestTypes = []string{
+	manifest.ManifestSimpleContentType,
+	manifest.ManifestSimpleCompactContentType,
+	manifest.ManifestMantarayContentType,
+}
+
+func TestRewriteReferences(t *testing.T) {
+	oldReference1 := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+	oldReference2 := swarm.MustParseHexAddress("2222222222222222222222222222222222222222222222222222222222222222")
+	untouchedReference := swarm.MustParseHexAddress("3333333333333333333333333333333333333333333333333333333333333333")
+	newReference1 := swarm.MustParseHexAddress("4444444444444444444444444444444444444444444444444444444444444444")
+	newReference2 := swarm.MustParseHexAddress("5555555555555555555555555555555555555555555555555555555555555555")
+	metadata := map[string]string{"Content-Type": "text/html"}
+
+	for _, manifestType := range manifestTypes {
+		t.Run(manifestType, func(t *testing.T) {
+			m, err := manifest.NewManifest(manifestType, false, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			entries := map[string]swarm.Address{
+				"index.html":     oldReference1,
+				"img/logo.png":   oldReference2,
+				"img/icon.png":   oldReference1,
+				"robots.txt":     untouchedReference,
+				"css/style.css":  untouchedReference,
+				"js/index.js":    newReference2,
+				"docs/index.htm": oldReference2,
+			}
+			for p, ref := range entries {
+				if err := m.Add(p, manifest.NewEntry(ref, metadata)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			changed, err := m.RewriteReferences(map[string]swarm.Address{
+				oldReference1.String(): newReference1,
+				oldReference2.String(): newReference2,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed != 4 {
+				t.Fatalf("got %d changed entries, want 4", changed)
+			}
+
+			expected := map[string]swarm.Address{
+				"index.html":     newReference1,
+				"img/logo.png":   newReference2,
+				"img/icon.png":   newReference1,
+				"robots.txt":     untouchedReference,
+				"css/style.css":  untouchedReference,
+				"js/index.js":    newReference2,
+				"docs/index.htm": newReference2,
+			}
+			for p, ref := range expected {
+				entry, err := m.Lookup(p)
+				if err != nil {
+					t.Fatalf("lookup %s: %v", p, err)
+				}
+				if !entry.Reference().Equal(ref) {
+					t.Fatalf("%s: got reference %s, want %s", p, entry.Reference(), ref)
+				}
+				if !reflect.DeepEqual(entry.Metadata(), metadata) {
+					t.Fatalf("%s: got metadata %v, want %v", p, entry.Metadata(), metadata)
+				}
+			}
+		})
+	}
+}
+
+func TestClone(t *testing.T) {
+	reference1 := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+	reference2 := swarm.MustParseHexAddress("2222222222222222222222222222222222222222222222222222222222222222")
+
+	for _, manifestType := range manifestTypes {
+		for _, stored := range []bool{false, true} {
+			name := manifestType
+			if stored {
+				name += "/stored"
+			}
+			t.Run(name, func(t *testing.T) {
+				m, err := manifest.NewManifest(manifestType, false, mock.NewStorer())
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				for _, p := range []string{"index.html", "img/logo.png"} {
+					if err := m.Add(p, manifest.NewEntry(reference1, nil)); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				if stored {
+					if _, err := m.Store(context.Background(), storage.ModePutUpload); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				clone, err := m.Clone()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if err := clone.Add("index.html", manifest.NewEntry(reference2, nil)); err != nil {
+					t.Fatal(err)
+				}
+				if err := clone.Add("robots.txt", manifest.NewEntry(reference2, nil)); err != nil {
+					t.Fatal(err)
+				}
+				if err := clone.Remove("img/logo.png"); err != nil {
+					t.Fatal(err)
+				}
+
+				for _, p := range []string{"index.html", "img/logo.png"} {
+					entry, err := m.Lookup(p)
+					if err != nil {
+						t.Fatalf("original lookup %s: %v", p, err)
+					}
+					if !entry.Reference().Equal(reference1) {
+						t.Fatalf("original %s: got reference %s, want %s", p, entry.Reference(), reference1)
+					}
+				}
+				if _, err := m.Lookup("robots.txt"); !errors.Is(err, manifest.ErrNotFound) {
+					t.Fatalf("original lookup robots.txt: got error %v, want %v", err, manifest.ErrNotFound)
+				}
+
+				for _, p := range []string{"index.html", "robots.txt"} {
+					entry, err := clone.Lookup(p)
+					if err != nil {
+						t.Fatalf("clone lookup %s: %v", p, err)
+					}
+					if !entry.Reference().Equal(reference2) {
+						t.Fatalf("clone %s: got reference %s, want %s", p, entry.Reference(), reference2)
+					}
+				}
+				if _, err := clone.Lookup("img/logo.png"); !errors.Is(err, manifest.ErrNotFound) {
+					t.Fatalf("clone lookup img/logo.png: got error %v, want %v", err, manifest.ErrNotFound)
+				}
+			})
+		}
+	}
+}
+
+func TestCloneModified(t *testing.T) {
+	reference := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+
+	for _, manifestType := range manifestTypes {
+		t.Run(manifestType, func(t *testing.T) {
+			storer := mock.NewStorer()
+			m, err := manifest.NewManifest(manifestType, false, storer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range []string{"index.html", "img/logo.png"} {
+				if err := m.Add(p, manifest.NewEntry(reference, nil)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			ref, err := m.Store(context.Background(), storage.ModePutUpload)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			loaded, err := manifest.NewManifestReference(context.Background(), manifestType, ref, false, storer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := loaded.Remove("img/logo.png"); err != nil {
+				t.Fatal(err)
+			}
+			if err := loaded.Add("robots.txt", manifest.NewEntry(reference, nil)); err != nil {
+				t.Fatal(err)
+			}
+
+			clone, err := loaded.Clone()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the unsaved changes of the loaded manifest are part of the clone
+			if _, err := clone.Lookup("img/logo.png"); !errors.Is(err, manifest.ErrNotFound) {
+				t.Fatalf("clone lookup img/logo.png: got error %v, want %v", err, manifest.ErrNotFound)
+			}
+			for _, p := range []string{"index.html", "robots.txt"} {
+				if _, err := clone.Lookup(p); err != nil {
+					t.Fatalf("clone lookup %s: %v", p, err)
+				}
+			}
+			assertEntryCount(t, clone, 2)
+		})
+	}
+}
+
+func TestWalkFrom(t *testing.T) {
+	reference := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+	paths := []string{
+		"a.txt",
+		"b/c.txt",
+		"b/d.txt",
+		"b/e/f.txt",
+		"g.txt",
+		"h/i.txt",
+		"index.html",
+	}
+
+	for _, manifestType := range manifestTypes {
+		for _, limit := range []int{1, 2, 3, len(paths), len(paths) + 1} {
+			t.Run(fmt.Sprintf("%s/limit %d", manifestType, limit), func(t *testing.T) {
+				m, err := manifest.NewManifest(manifestType, false, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				// add in reverse order to make sure the order is not insertion order
+				for i := len(paths) - 1; i >= 0; i-- {
+					if err := m.Add(paths[i], manifest.NewEntry(reference, nil)); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				var (
+					got    []string
+					cursor string
+					calls  int
+				)
+				for {
+					entries, next, err := m.WalkFrom(cursor, limit)
+					if err != nil {
+						t.Fatal(err)
+					}
+					calls++
+					if len(entries) > limit {
+						t.Fatalf("got %d entries, limit %d", len(entries), limit)
+					}
+					for _, e := range entries {
+						got = append(got, e.Path)
+					}
+					if next == "" {
+						break
+					}
+					if calls > len(paths) {
+						t.Fatal("walk does not terminate")
+					}
+					cursor = next
+				}
+
+				if !reflect.DeepEqual(got, paths) {
+					t.Fatalf("got paths %v, want %v", got, paths)
+				}
+			})
+		}
+	}
+}
+
+func TestIterate(t *testing.T) {
+	reference := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+	paths := []string{
+		"index.html",
+		"img/logo.png",
+		"img/icons/a.png",
+		"img/icons/b.png",
+		"imgs.txt",
+		"robots.txt",
+	}
+
+	for _, manifestType := range manifestTypes {
+		t.Run(manifestType, func(t *testing.T) {
+			m, err := manifest.NewManifest(manifestType, false, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range paths {
+				if err := m.Add(p, manifest.NewEntry(reference, nil)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for _, tc := range []struct {
+				prefix string
+				limit  int
+				want   []string
+			}{
+				{prefix: "img/", want: []string{"img/icons/a.png", "img/icons/b.png", "img/logo.png"}},
+				{prefix: "img", want: []string{"img/icons/a.png", "img/icons/b.png", "img/logo.png", "imgs.txt"}},
+				{prefix: "", limit: 2, want: []string{"img/icons/a.png", "img/icons/b.png"}},
+				{prefix: "missing/"},
+			} {
+				var got []string
+				err := m.Iterate(tc.prefix, func(path string, entry manifest.Entry) (bool, error) {
+					if !entry.Reference().Equal(reference) {
+						t.Fatalf("%s: got reference %s, want %s", path, entry.Reference(), reference)
+					}
+					got = append(got, path)
+					return tc.limit > 0 && len(got) == tc.limit, nil
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Fatalf("prefix %q: got paths %v, want %v", tc.prefix, got, tc.want)
+				}
+			}
+
+			errTest := errors.New("test error")
+			err = m.Iterate("", func(path string, entry manifest.Entry) (bool, error) {
+				return false, errTest
+			})
+			if !errors.Is(err, errTest) {
+				t.Fatalf("got error %v, want %v", err, errTest)
+			}
+		})
+	}
+}
+
+func TestEntryCount(t *testing.T) {
+	reference := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+
+	for _, manifestType := range manifestTypes {
+		t.Run(manifestType, func(t *testing.T) {
+			storer := mock.NewStorer()
+
+			m, err := manifest.NewManifest(manifestType, false, storer)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertEntryCount(t, m, 0)
+
+			for _, p := range []string{"index.html", "img/logo.png", "robots.txt", "index.html"} {
+				if err := m.Add(p, manifest.NewEntry(reference, nil)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			assertEntryCount(t, m, 3)
+
+			if err := m.Remove("robots.txt"); err != nil {
+				t.Fatal(err)
+			}
+			assertEntryCount(t, m, 2)
+
+			ref, err := m.Store(context.Background(), storage.ModePutUpload)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			loaded, err := manifest.NewManifestReference(context.Background(), manifestType, ref, false, storer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertEntryCount(t, loaded, 2)
+		})
+	}
+}
+
+func assertEntryCount(t *testing.T, m manifest.Interface, want int) {
+	t.Helper()
+
+	got, err := m.EntryCount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Fatalf("got entry count %d, want %d", got, want)
+	}
+}
+
+func TestStoreUnmodified(t *testing.T) {
+	reference := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+
+	for _, manifestType := range manifestTypes {
+		t.Run(manifestType, func(t *testing.T) {
+			storer := &putCountingStorer{Storer: mock.NewStorer()}
+			ctx := context.Background()
+
+			m, err := manifest.NewManifest(manifestType, false, storer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := m.Add("index.html", manifest.NewEntry(reference, nil)); err != nil {
+				t.Fatal(err)
+			}
+			if !m.Modified() {
+				t.Fatal("manifest not modified after add")
+			}
+
+			ref, err := m.Store(ctx, storage.ModePutUpload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.Modified() {
+				t.Fatal("manifest modified after store")
+			}
+
+			loaded, err := manifest.NewManifestReference(ctx, manifestType, ref, false, storer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := loaded.Lookup("index.html"); err != nil {
+				t.Fatal(err)
+			}
+			if loaded.Modified() {
+				t.Fatal("loaded manifest modified after lookup")
+			}
+
+			puts := atomic.LoadInt64(&storer.puts)
+			for _, s := range []manifest.Interface{m, loaded} {
+				got, err := s.Store(ctx, storage.ModePutUpload)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !got.Equal(ref) {
+					t.Fatalf("got reference %s, want %s", got, ref)
+				}
+			}
+			if n := atomic.LoadInt64(&storer.puts) - puts; n != 0 {
+				t.Fatalf("got %d chunk puts storing unmodified manifests, want none", n)
+			}
+
+			if err := loaded.Remove("index.html"); err != nil {
+				t.Fatal(err)
+			}
+			if !loaded.Modified() {
+				t.Fatal("loaded manifest not modified after remove")
+			}
+			got, err := loaded.Store(ctx, storage.ModePutUpload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Equal(ref) {
+				t.Fatal("modified manifest stored under the original reference")
+			}
+		})
+	}
+}
+
+// putCountingStorer counts the chunks put into the underlying storer.
+type putCountingStorer struct {
+	storage.Storer
+	puts int64
+}
+
+func (s *putCountingStorer) Put(ctx context.Context, mode storage.ModePut, chs ...swarm.Chunk) ([]bool, error) {
+	atomic.AddInt64(&s.puts, int64(len(chs)))
+	return s.Storer.Put(ctx, mode, chs...)
+}
+
+func TestAddBatch(t *testing.T) {
+	reference1 := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+	reference2 := swarm.MustParseHexAddress("2222222222222222222222222222222222222222222222222222222222222222")
+
+	for _, manifestType := range manifestTypes {
+		t.Run(manifestType, func(t *testing.T) {
+			ctx := context.Background()
+			storer := mock.NewStorer()
+
+			m, err := manifest.NewManifest(manifestType, false, storer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := m.Add("index.html", manifest.NewEntry(reference1, nil)); err != nil {
+				t.Fatal(err)
+			}
+
+			entries := map[string]manifest.Entry{
+				"index.html":      manifest.NewEntry(reference2, nil),
+				"img/logo.png":    manifest.NewEntry(reference2, map[string]string{"Content-Type": "image/png"}),
+				"img/icons/a.png": manifest.NewEntry(reference2, nil),
+			}
+			if err := m.AddBatch(entries); err != nil {
+				t.Fatal(err)
+			}
+
+			ref, err := m.Store(ctx, storage.ModePutUpload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := manifest.NewManifestReference(ctx, manifestType, ref, false, storer)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertEntryCount(t, loaded, len(entries))
+			for p, want := range entries {
+				got, err := loaded.Lookup(p)
+				if err != nil {
+					t.Fatalf("lookup %s: %v", p, err)
+				}
+				if !got.Reference().Equal(want.Reference()) {
+					t.Fatalf("%s: got reference %s, want %s", p, got.Reference(), want.Reference())
+				}
+				if !reflect.DeepEqual(got.Metadata(), want.Metadata()) {
+					t.Fatalf("%s: got metadata %v, want %v", p, got.Metadata(), want.Metadata())
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkAddBatch(b *testing.B) {
+	reference := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+
+	for _, count := range []int{100, 1000, 10000} {
+		entries := make(map[string]manifest.Entry, count)
+		for i := 0; i < count; i++ {
+			entries[fmt.Sprintf("dir%d/file%d.txt", i%10, i)] = manifest.NewEntry(reference, nil)
+		}
+
+		for _, manifestType := range manifestTypes {
+			b.Run(fmt.Sprintf("%s/%d/sequential", manifestType, count), func(b *testing.B) {
+				for n := 0; n < b.N; n++ {
+					benchmarkAdd(b, manifestType, entries, false)
+				}
+			})
+			b.Run(fmt.Sprintf("%s/%d/batch", manifestType, count), func(b *testing.B) {
+				for n := 0; n < b.N; n++ {
+					benchmarkAdd(b, manifestType, entries, true)
+				}
+			})
+		}
+	}
+}
+
+func benchmarkAdd(b *testing.B, manifestType string, entries map[string]manifest.Entry, batch bool) {
+	b.StopTimer()
+
+	m, err := manifest.NewManifest(manifestType, false, mock.NewStorer())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.StartTimer()
+
+	if batch {
+		err = m.AddBatch(entries)
+	} else {
+		for p, entry := range entries {
+			if err = m.Add(p, entry); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	_, err = m.Store(context.Background(), storage.ModePutUpload)
+	if err != nil {
+		b.Fatal(err)
+	}
+}
+
+func TestUpdateMetadata(t *testing.T) {
+	reference := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+
+	for _, manifestType := range manifestTypes {
+		t.Run(manifestType, func(t *testing.T) {
+			m, err := manifest.NewManifest(manifestType, false, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = m.Add("index.html", manifest.NewEntry(reference, map[string]string{"Content-Type": "text/plain"}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := map[string]string{"Content-Type": "text/html; charset=utf-8"}
+			if err := m.UpdateMetadata("index.html", want); err != nil {
+				t.Fatal(err)
+			}
+
+			entry, err := m.Lookup("index.html")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !entry.Reference().Equal(reference) {
+				t.Fatalf("got reference %s, want %s", entry.Reference(), reference)
+			}
+			if !reflect.DeepEqual(entry.Metadata(), want) {
+				t.Fatalf("got metadata %v, want %v", entry.Metadata(), want)
+			}
+
+			err = m.UpdateMetadata("missing.html", want)
+			if !errors.Is(err, manifest.ErrNotFound) {
+				t.Fatalf("got error %v, want %v", err, manifest.ErrNotFound)
+			}
+
+			// all manifest types reject empty metadata and keep the existing one
+			for _, metadata := range []map[string]string{nil, {}} {
+				err = m.UpdateMetadata("index.html", metadata)
+				if !errors.Is(err, manifest.ErrEmptyMetadata) {
+					t.Fatalf("got error %v, want %v", err, manifest.ErrEmptyMetadata)
+				}
+			}
+			entry, err = m.Lookup("index.html")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(entry.Metadata(), want) {
+				t.Fatalf("got metadata %v, want %v", entry.Metadata(), want)
+			}
+		})
+	}
+}
+
+func TestRootMetadata(t *testing.T) {
+	ctx := context.Background()
+	reference := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+
+	for _, manifestType := range manifestTypes {
+		t.Run(manifestType, func(t *testing.T) {
+			storer := mock.NewStorer()
+
+			m, err := manifest.NewManifest(manifestType, false, storer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := m.Add("index.html", manifest.NewEntry(reference, nil)); err != nil {
+				t.Fatal(err)
+			}
+
+			metadata, err := m.RootMetadata()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if metadata != nil {
+				t.Fatalf("got root metadata %v, want none", metadata)
+			}
+
+			want := map[string]string{manifest.WebsiteIndexDocumentSuffixKey: "index.html"}
+			if err := m.SetRootMetadata(want); err != nil {
+				t.Fatal(err)
+			}
+
+			ref, err := m.Store(ctx, storage.ModePutUpload)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			loaded, err := manifest.NewManifestReference(ctx, manifestType, ref, false, storer)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			metadata, err = loaded.RootMetadata()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(metadata, want) {
+				t.Fatalf("got root metadata %v, want %v", metadata, want)
+			}
+
+			// the root metadata is not an entry of the manifest
+			count, err := loaded.EntryCount()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != 1 {
+				t.Fatalf("got %d entries, want 1", count)
+			}
+			names, err := loaded.ListPrefix("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(names, []string{"index.html"}) {
+				t.Fatalf("got names %v, want [index.html]", names)
+			}
+			var paths []string
+			err = loaded.Iterate("", func(path string, _ manifest.Entry) (bool, error) {
+				paths = append(paths, path)
+				return false, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(paths, []string{"index.html"}) {
+				t.Fatalf("got paths %v, want [index.html]", paths)
+			}
+			entries, _, err := loaded.WalkFrom("", 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 || entries[0].Path != "index.html" {
+				t.Fatalf("got entries %v, want only index.html", entries)
+			}
+
+			if err := loaded.SetRootMetadata(nil); err != nil {
+				t.Fatal(err)
+			}
+			metadata, err = loaded.RootMetadata()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if metadata != nil {
+				t.Fatalf("got root metadata %v after removal, want none", metadata)
+			}
+			if _, err := loaded.Lookup("index.html"); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestStoreContext(t *testing.T) {
+	reference := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+
+	newManifest := func(t *testing.T, manifestType string, storer storage.Storer) manifest.Interface {
+		t.Helper()
+
+		m, err := manifest.NewManifest(manifestType, false, storer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 50; i++ {
+			if err := m.Add(fmt.Sprintf("dir%d/file%d.txt", i%5, i), manifest.NewEntry(reference, nil)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return m
+	}
+
+	for _, manifestType := range manifestTypes {
+		t.Run(manifestType, func(t *testing.T) {
+			storer := mock.NewStorer()
+			m := newManifest(t, manifestType, storer)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			if _, err := m.StoreContext(ctx, storage.ModePutUpload, nil); !errors.Is(err, context.Canceled) {
+				t.Fatalf("got error %v, want %v", err, context.Canceled)
+			}
+			if !m.Modified() {
+				t.Fatal("manifest not modified after cancelled store")
+			}
+
+			var progress []int
+			ref, err := m.StoreContext(context.Background(), storage.ModePutUpload, func(saved int) {
+				progress = append(progress, saved)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(progress) == 0 {
+				t.Fatal("no progress reported")
+			}
+			for i, saved := range progress {
+				if saved != i+1 {
+					t.Fatalf("got progress %v, want consecutive counts", progress)
+				}
+			}
+
+			loaded, err := manifest.NewManifestReference(context.Background(), manifestType, ref, false, storer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			count, err := loaded.EntryCount()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != 50 {
+				t.Fatalf("got %d entries, want 50", count)
+			}
+		})
+	}
+
+	t.Run("cancel while saving", func(t *testing.T) {
+		storer := mock.NewStorer()
+		m := newManifest(t, manifest.ManifestMantarayContentType, storer)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		saved := 0
+		_, err := m.StoreContext(ctx, storage.ModePutUpload, func(n int) {
+			saved = n
+			cancel()
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("got error %v, want %v", err, context.Canceled)
+		}
+		if saved != 1 {
+			t.Fatalf("got %d saved nodes after cancellation, want 1", saved)
+		}
+		if !m.Modified() {
+			t.Fatal("manifest not modified after cancelled store")
+		}
+
+		ref, err := m.StoreContext(context.Background(), storage.ModePutUpload, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := manifest.NewManifestReference(context.Background(), manifest.ManifestMantarayContentType, ref, false, storer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		count, err := loaded.EntryCount()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 50 {
+			t.Fatalf("got %d entries, want 50", count)
+		}
+	})
+}
+
+func TestEntryNameMimeType(t *testing.T) {
+	reference := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+	metadata := map[string]string{
+		manifest.EntryMetadataFilenameKey:    "logo.png",
+		manifest.EntryMetadataContentTypeKey: "image/png",
+	}
+
+	for _, manifestType := range manifestTypes {
+		t.Run(manifestType, func(t *testing.T) {
+			m, err := manifest.NewManifest(manifestType, false, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := m.Add("img/logo.png", manifest.NewEntry(reference, metadata)); err != nil {
+				t.Fatal(err)
+			}
+			if err := m.Add("robots.txt", manifest.NewEntry(reference, nil)); err != nil {
+				t.Fatal(err)
+			}
+
+			entry, err := m.Lookup("img/logo.png")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := manifest.EntryName(entry); got != "logo.png" {
+				t.Fatalf("got name %q, want %q", got, "logo.png")
+			}
+			if got := manifest.EntryMimeType(entry); got != "image/png" {
+				t.Fatalf("got mime type %q, want %q", got, "image/png")
+			}
+
+			entry, err = m.Lookup("robots.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := manifest.EntryName(entry); got != "" {
+				t.Fatalf("got name %q, want none", got)
+			}
+			if got := manifest.EntryMimeType(entry); got != "" {
+				t.Fatalf("got mime type %q, want none", got)
+			}
+		})
+	}
+}
+
+func TestListPrefix(t *testing.T) {
+	reference := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+	paths := []string{
+		"index.html",
+		"img/logo.png",
+		"img/icons/a.png",
+		"img/icons/b.png",
+		"img/icons.txt",
+		"imgs.txt",
+	}
+
+	for _, manifestType := range manifestTypes {
+		t.Run(manifestType, func(t *testing.T) {
+			m, err := manifest.NewManifest(manifestType, false, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range paths {
+				if err := m.Add(p, manifest.NewEntry(reference, nil)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for _, tc := range []struct {
+				prefix string
+				want   []string
+			}{
+				{prefix: "", want: []string{"img/", "imgs.txt", "index.html"}},
+				{prefix: "img/", want: []string{"icons.txt", "icons/", "logo.png"}},
+				{prefix: "img", want: []string{"icons.txt", "icons/", "logo.png"}},
+				{prefix: "img/icons", want: []string{"a.png", "b.png"}},
+				{prefix: "missing/"},
+			} {
+				got, err := m.ListPrefix(tc.prefix)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Fatalf("prefix %q: got %v, want %v", tc.prefix, got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRemovePrefix(t *testing.T) {
+	reference := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+	paths := []string{
+		"index.html",
+		"img/logo.png",
+		"img/icons/a.png",
+		"img/icons/b.png",
+		"imgs.txt",
+	}
+
+	for _, manifestType := range manifestTypes {
+		t.Run(manifestType, func(t *testing.T) {
+			m, err := manifest.NewManifest(manifestType, false, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range paths {
+				if err := m.Add(p, manifest.NewEntry(reference, nil)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			removed, err := m.RemovePrefix("img/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if removed != 3 {
+				t.Fatalf("got %d removed entries, want 3", removed)
+			}
+			assertEntryCount(t, m, 2)
+
+			for _, p := range []string{"index.html", "imgs.txt"} {
+				if _, err := m.Lookup(p); err != nil {
+					t.Fatalf("lookup %s: %v", p, err)
+				}
+			}
+			if exists, err := m.HasPrefix("img/"); err != nil || exists {
+				t.Fatalf("got prefix exists %v, %v after removal", exists, err)
+			}
+
+			_, err = m.RemovePrefix("img/")
+			if !errors.Is(err, manifest.ErrNotFound) {
+				t.Fatalf("got error %v, want %v", err, manifest.ErrNotFound)
+			}
+		})
+	}
+}
+
+func TestRemovePrefixRootMetadata(t *testing.T) {
+	reference := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+	metadata := map[string]string{manifest.WebsiteIndexDocumentSuffixKey: "index.html"}
+
+	for _, manifestType := range manifestTypes {
+		t.Run(manifestType, func(t *testing.T) {
+			m, err := manifest.NewManifest(manifestType, false, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range []string{"index.html", "img/logo.png"} {
+				if err := m.Add(p, manifest.NewEntry(reference, nil)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := m.SetRootMetadata(metadata); err != nil {
+				t.Fatal(err)
+			}
+
+			// the root metadata is the only path starting with a slash
+			if _, err := m.RemovePrefix("/"); !errors.Is(err, manifest.ErrNotFound) {
+				t.Fatalf("got error %v, want %v", err, manifest.ErrNotFound)
+			}
+			assertRootMetadata(t, m, metadata)
+
+			removed, err := m.RemovePrefix("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if removed != 2 {
+				t.Fatalf("got %d removed entries, want 2", removed)
+			}
+			assertEntryCount(t, m, 0)
+			assertRootMetadata(t, m, metadata)
+			for _, p := range []string{"index.html", "img/logo.png"} {
+				if _, err := m.Lookup(p); !errors.Is(err, manifest.ErrNotFound) {
+					t.Fatalf("lookup %s: got error %v, want %v", p, err, manifest.ErrNotFound)
+				}
+			}
+
+			if _, err := m.RemovePrefix(""); !errors.Is(err, manifest.ErrNotFound) {
+				t.Fatalf("got error %v, want %v", err, manifest.ErrNotFound)
+			}
+			assertRootMetadata(t, m, metadata)
+		})
+	}
+}
+
+func assertRootMetadata(t *testing.T, m manifest.Interface, want map[string]string) {
+	t.Helper()
+
+	got, err := m.RootMetadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got root metadata %v, want %v", got, want)
+	}
+}
+
+func TestStorageSize(t *testing.T) {
+	reference := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+
+	for _, manifestType := range manifestTypes {
+		t.Run(manifestType, func(t *testing.T) {
+			ctx := context.Background()
+			storer := mock.NewStorer()
+
+			sizes := make(map[int]int64)
+			for _, count := range []int{5, 50} {
+				m, err := manifest.NewManifest(manifestType, false, storer)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i := 0; i < count; i++ {
+					if err := m.Add(fmt.Sprintf("dir%d/file%d.txt", i%3, i), manifest.NewEntry(reference, nil)); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				size, err := m.StorageSize()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if size <= 0 {
+					t.Fatalf("got size %d of a manifest with %d entries", size, count)
+				}
+
+				// a stored manifest has the size it was measured with before
+				ref, err := m.Store(ctx, storage.ModePutUpload)
+				if err != nil {
+					t.Fatal(err)
+				}
+				loaded, err := manifest.NewManifestReference(ctx, manifestType, ref, false, storer)
+				if err != nil {
+					t.Fatal(err)
+				}
+				storedSize, err := loaded.StorageSize()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if storedSize != size {
+					t.Fatalf("got size %d of the stored manifest with %d entries, want %d", storedSize, count, size)
+				}
+
+				sizes[count] = size
+			}
+
+			if sizes[50] <= sizes[5] {
+				t.Fatalf("got size %d of the manifest with 50 entries, not larger than %d of the one with 5", sizes[50], sizes[5])
+			}
+		})
+	}
+}
@@ -5,14 +5,16 @@
 package manifest
 
 import (
-	"bytes"
 	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 
+	"github.com/ethersphere/bee/pkg/encryption"
 	"github.com/ethersphere/bee/pkg/file"
-	"github.com/ethersphere/bee/pkg/file/joiner"
-	"github.com/ethersphere/bee/pkg/file/pipeline/builder"
+	"github.com/ethersphere/bee/pkg/file/loadsave"
 	"github.com/ethersphere/bee/pkg/storage"
 	"github.com/ethersphere/bee/pkg/swarm"
 	"github.com/ethersphere/manifest/mantaray"
@@ -31,6 +33,10 @@ type mantarayManifest struct {
 	storer    storage.Storer
 
 	loader mantaray.LoadSaver
+	saver  mantaray.LoadSaver // used by Store instead of the storer, if set
+
+	entryCount int  // cached number of entries or -1 if unknown
+	modified   bool // changed since created, loaded or stored
 }
 
 // NewMantarayManifest creates a new mantaray-based manifest.
@@ -53,10 +59,11 @@ func NewMantarayManifestReference(
 	storer storage.Storer,
 ) (Interface, error) {
 	return &mantarayManifest{
-		trie:      mantaray.NewNodeRef(reference.Bytes()),
-		encrypted: encrypted,
-		storer:    storer,
-		loader:    newMantarayLoader(ctx, encrypted, storer),
+		trie:       mantaray.NewNodeRef(reference.Bytes()),
+		encrypted:  encrypted,
+		storer:     storer,
+		loader:     loadsave.NewLoader(ctx, storer),
+		entryCount: -1,
 	}, nil
 }
 
@@ -68,9 +75,57 @@ func (m *mantarayManifest) Add(path string, entry Entry) error {
 	p := []byte(path)
 	e := entry.Reference().Bytes()
 
+	// the entry might replace an existing one
+	m.entryCount = -1
+	m.modified = true
+
 	return m.trie.Add(p, e, entry.Metadata(), m.loader)
 }
 
+// AddBatch inserts all entries into the in-memory trie in sorted path order,
+// so that shared prefixes are restructured while building and only saved
+// once on Store.
+func (m *mantarayManifest) AddBatch(entries map[string]Entry) error {
+	paths := make([]string, 0, len(entries))
+	for p := range entries {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	m.entryCount = -1
+	m.modified = true
+
+	for _, p := range paths {
+		entry := entries[p]
+		err := m.trie.Add([]byte(p), entry.Reference().Bytes(), entry.Metadata(), m.loader)
+		if err != nil {
+			return fmt.Errorf("add %s: %w", p, err)
+		}
+	}
+
+	return nil
+}
+
+// UpdateMetadata re-adds the value node with the new metadata. Mantaray only
+// replaces the metadata of a node if new metadata is given, so empty metadata
+// is rejected instead of leaving the existing metadata in place.
+func (m *mantarayManifest) UpdateMetadata(path string, metadata map[string]string) error {
+	if len(metadata) == 0 {
+		return ErrEmptyMetadata
+	}
+
+	p := []byte(path)
+
+	node, err := m.trie.LookupNode(p, m.loader)
+	if err != nil || !node.IsValueType() {
+		return ErrNotFound
+	}
+
+	m.modified = true
+
+	return m.trie.Add(p, node.Entry(), metadata, m.loader)
+}
+
 func (m *mantarayManifest) Remove(path string) error {
 	p := []byte(path)
 
@@ -82,9 +137,94 @@ func (m *mantarayManifest) Remove(path string) error {
 		return err
 	}
 
+	if m.entryCount > 0 && path != RootPath {
+		m.entryCount--
+	}
+	m.modified = true
+
 	return nil
 }
 
+// SetRootMetadata stores the metadata on the RootPath entry. Mantaray
+// serializes the metadata of a node in the fork of its parent, so the root
+// node of the trie itself can not hold metadata which survives Store.
+func (m *mantarayManifest) SetRootMetadata(metadata map[string]string) error {
+	return setRootMetadata(m, metadata)
+}
+
+func (m *mantarayManifest) RootMetadata() (map[string]string, error) {
+	return lookupRootMetadata(m)
+}
+
+// RemovePrefix removes the fork of the trie which holds the entries under the
+// prefix. The RootPath entry holds the root metadata, so it is added back if
+// the prefix matched it.
+func (m *mantarayManifest) RemovePrefix(prefix string) (int, error) {
+	var paths []string
+	err := m.walkEntries(func(path string, _ Entry) error {
+		if path != RootPath && strings.HasPrefix(path, prefix) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(paths) == 0 {
+		return 0, ErrNotFound
+	}
+
+	var root Entry
+	if strings.HasPrefix(RootPath, prefix) {
+		root, err = m.Lookup(RootPath)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return 0, err
+		}
+	}
+
+	if prefix == "" {
+		m.trie = mantaray.New()
+	} else if err := m.removeFork(prefix, paths); err != nil {
+		return 0, err
+	}
+
+	if root != nil {
+		if err := m.trie.Add([]byte(RootPath), root.Reference().Bytes(), root.Metadata(), m.loader); err != nil {
+			return 0, err
+		}
+	}
+
+	if m.entryCount > 0 {
+		m.entryCount -= len(paths)
+	}
+	m.modified = true
+
+	return len(paths), nil
+}
+
+// removeFork removes the fork of the trie which holds the sorted paths under
+// the prefix. The prefix may end within the prefix of the fork, whose path
+// then ends between the prefix and the common prefix of the paths.
+func (m *mantarayManifest) removeFork(prefix string, paths []string) error {
+	first, last := paths[0], paths[len(paths)-1]
+	n := 0
+	for n < len(first) && n < len(last) && first[n] == last[n] {
+		n++
+	}
+
+	for end := len(prefix); end <= n; end++ {
+		err := m.trie.Remove([]byte(first[:end]), m.loader)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, mantaray.ErrNotFound) {
+			return err
+		}
+	}
+
+	return ErrNotFound
+}
+
 func (m *mantarayManifest) Lookup(path string) (Entry, error) {
 	p := []byte(path)
 
@@ -111,79 +251,341 @@ func (m *mantarayManifest) HasPrefix(prefix string) (bool, error) {
 }
 
 func (m *mantarayManifest) Store(ctx context.Context, mode storage.ModePut) (swarm.Address, error) {
+	return m.StoreContext(ctx, mode, nil)
+}
 
-	saver := newMantaraySaver(ctx, m.encrypted, m.storer, mode)
+// StoreContext saves the nodes of the trie bottom up, so the root node is
+// only saved, and the manifest only gets a reference, once all other nodes
+// are. Nodes saved before the context was done keep their references and
+// are not saved again by the next call.
+func (m *mantarayManifest) StoreContext(ctx context.Context, mode storage.ModePut, progress func(saved int)) (swarm.Address, error) {
+	if ref := m.trie.Reference(); !m.modified && len(ref) > 0 {
+		return swarm.NewAddress(ref), nil
+	}
+
+	saver := m.saver
+	if saver == nil {
+		saver = loadsave.New(ctx, m.storer, mode, m.encrypted)
+	}
 
-	err := m.trie.Save(saver)
+	err := m.trie.Save(&progressSaver{ctx: ctx, LoadSaver: saver, progress: progress})
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return swarm.ZeroAddress, ctxErr
+		}
 		return swarm.ZeroAddress, fmt.Errorf("manifest save error: %w", err)
 	}
 
+	if err := checkEncrypted(m.encrypted, m.trie.Reference()); err != nil {
+		return swarm.ZeroAddress, err
+	}
+
 	address := swarm.NewAddress(m.trie.Reference())
 
+	m.modified = false
+
 	return address, nil
 }
 
-// mantarayLoadSaver implements required interface 'mantaray.LoadSaver'
-type mantarayLoadSaver struct {
-	ctx       context.Context
-	encrypted bool
-	storer    storage.Storer
-	modePut   storage.ModePut
+// progressSaver stops saving once the context is done and reports the
+// number of saved nodes.
+type progressSaver struct {
+	mantaray.LoadSaver
+	ctx      context.Context
+	progress func(saved int)
+	saved    int
 }
 
-func newMantarayLoader(
-	ctx context.Context,
-	encrypted bool,
-	storer storage.Storer,
-) *mantarayLoadSaver {
-	return &mantarayLoadSaver{
-		ctx:       ctx,
-		encrypted: encrypted,
-		storer:    storer,
+func (s *progressSaver) Save(data []byte) ([]byte, error) {
+	if err := s.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ref, err := s.LoadSaver.Save(data)
+	if err != nil {
+		return nil, err
 	}
+
+	s.saved++
+	if s.progress != nil {
+		s.progress(s.saved)
+	}
+
+	return ref, nil
 }
 
-func newMantaraySaver(
-	ctx context.Context,
-	encrypted bool,
-	storer storage.Storer,
-	modePut storage.ModePut,
-) *mantarayLoadSaver {
-	return &mantarayLoadSaver{
-		ctx:       ctx,
-		encrypted: encrypted,
-		storer:    storer,
-		modePut:   modePut,
+func (m *mantarayManifest) RewriteReferences(mapping map[string]swarm.Address) (int, error) {
+	type rewrite struct {
+		path  string
+		entry Entry
 	}
+
+	var rewrites []rewrite
+	err := m.walkEntries(func(path string, entry Entry) error {
+		if newReference, ok := mapping[entry.Reference().String()]; ok {
+			rewrites = append(rewrites, rewrite{
+				path:  path,
+				entry: NewEntry(newReference, entry.Metadata()),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, r := range rewrites {
+		if err := m.Add(r.path, r.entry); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(rewrites), nil
 }
 
-func (ls *mantarayLoadSaver) Load(ref []byte) ([]byte, error) {
-	ctx := ls.ctx
+// Compact rebuilds the trie from scratch. Removals leave behind nodes which
+// would not exist had the remaining entries been added to an empty trie, so
+// inserting the entries in sorted order into a new trie yields the minimal
+// structure. All nodes are loaded in the process.
+func (m *mantarayManifest) Compact() error {
+	trie := mantaray.New()
 
-	j, _, err := joiner.New(ctx, ls.storer, swarm.NewAddress(ref))
+	err := m.walkEntries(func(path string, entry Entry) error {
+		return trie.Add([]byte(path), entry.Reference().Bytes(), entry.Metadata(), nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	m.trie = trie
+	m.modified = true
+
+	return nil
+}
+
+// Clone returns a manifest with an independent trie. If the trie has no
+// unsaved changes, the clone references the stored root node and shares
+// all the stored nodes with the original, otherwise the entries are copied
+// into a new trie.
+func (m *mantarayManifest) Clone() (Interface, error) {
+	clone := &mantarayManifest{
+		encrypted:  m.encrypted,
+		storer:     m.storer,
+		loader:     m.loader,
+		saver:      m.saver,
+		entryCount: m.entryCount,
+		modified:   m.modified,
+	}
+
+	if ref := m.trie.Reference(); !m.modified && len(ref) > 0 {
+		if clone.loader == nil {
+			// manifests created in memory have no loader, but the clone
+			// needs one to read the shared nodes
+			clone.loader = loadsave.NewLoader(context.Background(), m.storer)
+		}
+		clone.trie = mantaray.NewNodeRef(ref)
+		return clone, nil
+	}
+
+	clone.trie = mantaray.New()
+
+	err := m.walkEntries(func(path string, entry Entry) error {
+		return clone.trie.Add([]byte(path), entry.Reference().Bytes(), entry.Metadata(), m.loader)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	buf := bytes.NewBuffer(nil)
-	_, err = file.JoinReadAll(ctx, j, buf)
+	return clone, nil
+}
+
+func (m *mantarayManifest) WalkFrom(cursor string, limit int) ([]PathEntry, string, error) {
+	return walkFrom(m.walkEntries, cursor, limit)
+}
+
+func (m *mantarayManifest) Iterate(prefix string, fn func(path string, entry Entry) (stop bool, err error)) error {
+	return iterate(m.walkEntries, prefix, fn)
+}
+
+func (m *mantarayManifest) ListPrefix(prefix string) ([]string, error) {
+	return listPrefix(m.walkEntries, prefix)
+}
+
+// EntryCount returns the number of entries in the manifest, not counting the
+// RootPath entry. Unless the count is known from the operations on the
+// manifest, the whole trie is walked, which loads all nodes.
+func (m *mantarayManifest) EntryCount() (int, error) {
+	if m.entryCount >= 0 {
+		return m.entryCount, nil
+	}
+
+	count := 0
+	walker := func(path []byte, node *mantaray.Node, err error) error {
+		if err != nil {
+			return err
+		}
+		if node != nil && node.IsValueType() && string(path) != RootPath {
+			count++
+		}
+		return nil
+	}
+
+	err := m.trie.WalkNode([]byte{}, m.loader, walker)
+	if err != nil {
+		return 0, err
+	}
+
+	m.entryCount = count
+
+	return count, nil
+}
+
+func (m *mantarayManifest) Modified() bool {
+	return m.modified
+}
+
+// StorageSize sums the serialized sizes of all nodes of the trie. A stored
+// trie is measured by loading every node, which forces loading the nodes
+// which are only referenced so far. A trie with unsaved changes is measured
+// as it would be stored, by serializing a copy of it, which loads all the
+// nodes as well.
+func (m *mantarayManifest) StorageSize() (int64, error) {
+	if ref := m.trie.Reference(); !m.modified && len(ref) > 0 {
+		loader := m.loader
+		if loader == nil {
+			loader = loadsave.NewLoader(context.Background(), m.storer)
+		}
+		counter := &sizeCountingLoadSaver{LoadSaver: loader}
+
+		// a new trie loads every node, including the ones already loaded
+		err := mantaray.NewNodeRef(ref).WalkNode([]byte{}, counter, func([]byte, *mantaray.Node, error) error {
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+		return counter.size, nil
+	}
+
+	clone, err := m.Clone()
+	if err != nil {
+		return 0, err
+	}
+	counter := &sizeCountingLoadSaver{encrypted: m.encrypted}
+	if err := clone.(*mantarayManifest).trie.Save(counter); err != nil {
+		return 0, err
+	}
+	return counter.size, nil
+}
+
+// sizeCountingLoadSaver sums the sizes of the loaded or saved nodes. Saved
+// nodes are not stored, they are given a reference of the length of the
+// references of stored nodes, which the size of their parents depends on.
+type sizeCountingLoadSaver struct {
+	mantaray.LoadSaver
+	encrypted bool
+	size      int64
+}
+
+func (s *sizeCountingLoadSaver) Load(ref []byte) ([]byte, error) {
+	data, err := s.LoadSaver.Load(ref)
 	if err != nil {
 		return nil, err
 	}
+	s.size += int64(len(data))
+	return data, nil
+}
 
-	return buf.Bytes(), nil
+func (s *sizeCountingLoadSaver) Save(data []byte) ([]byte, error) {
+	s.size += int64(len(data))
+	ref := sha256.Sum256(data)
+	if s.encrypted {
+		// encrypted references carry the decryption key
+		return append(ref[:], make([]byte, encryption.KeyLength)...), nil
+	}
+	return ref[:], nil
 }
 
-func (ls *mantarayLoadSaver) Save(data []byte) ([]byte, error) {
-	ctx := ls.ctx
+// Validate loads every node of the trie and the data of every entry. A node
+// which can not be loaded stops the walk of the trie, so it is reported
+// without a path and only the entries found before it are validated.
+func (m *mantarayManifest) Validate(ctx context.Context) ([]ValidationError, error) {
+	var nodeLoader mantaray.LoadSaver
+	recorder := &failureRecordingLoader{LoadSaver: m.loader}
+	if m.loader != nil {
+		nodeLoader = recorder
+	}
 
-	pipe := builder.NewPipelineBuilder(ctx, ls.storer, ls.modePut, ls.encrypted)
-	address, err := builder.FeedPipeline(ctx, pipe, bytes.NewReader(data), int64(len(data)))
+	var entries []PathEntry
+	walker := func(path []byte, node *mantaray.Node, err error) error {
+		if err != nil {
+			return err
+		}
+		if node == nil || !node.IsValueType() {
+			return nil
+		}
+		entries = append(entries, PathEntry{
+			Path:  string(path),
+			Entry: NewEntry(swarm.NewAddress(node.Entry()), node.Metadata()),
+		})
+		return nil
+	}
 
+	var failures []ValidationError
+	err := m.trie.WalkNode([]byte{}, nodeLoader, walker)
 	if err != nil {
-		return swarm.ZeroAddress.Bytes(), err
+		if recorder.failed == nil {
+			return nil, err
+		}
+		failures = append(failures, ValidationError{
+			Reference: swarm.NewAddress(recorder.failed),
+			Err:       err,
+		})
+	}
+
+	var loader file.Loader = m.loader
+	if m.storer != nil {
+		loader = loadsave.NewLoader(ctx, m.storer)
 	}
 
-	return address.Bytes(), nil
+	entryFailures, err := validateEntries(ctx, loader, entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(failures, entryFailures...), nil
+}
+
+// walkEntries calls fn for every entry in the trie in sorted path order.
+func (m *mantarayManifest) walkEntries(fn func(path string, entry Entry) error) error {
+	var paths []string
+	entries := make(map[string]Entry)
+
+	walker := func(path []byte, node *mantaray.Node, err error) error {
+		if err != nil {
+			return err
+		}
+		if node == nil || !node.IsValueType() {
+			return nil
+		}
+		p := string(path)
+		paths = append(paths, p)
+		entries[p] = NewEntry(swarm.NewAddress(node.Entry()), node.Metadata())
+		return nil
+	}
+
+	err := m.trie.WalkNode([]byte{}, m.loader, walker)
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		if err := fn(p, entries[p]); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
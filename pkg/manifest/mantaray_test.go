@@ -0,0 +1,107 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/ethersphere/manifest/mantaray"
+)
+
+// nodeCount counts all nodes of the mantaray trie.
+func nodeCount(t *testing.T, m *mantarayManifest) int {
+	t.Helper()
+
+	count := 0
+	err := m.trie.WalkNode([]byte{}, m.loader, func(_ []byte, node *mantaray.Node, err error) error {
+		if err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return count
+}
+
+// entries collects all entries of the manifest by path.
+func entries(t *testing.T, m *mantarayManifest) map[string]Entry {
+	t.Helper()
+
+	e := make(map[string]Entry)
+	err := m.walkEntries(func(path string, entry Entry) error {
+		e[path] = entry
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return e
+}
+
+func TestMantarayCompact(t *testing.T) {
+	mi, err := NewMantarayManifest(false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := mi.(*mantarayManifest)
+
+	reference := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+
+	// fragment the trie by adding many paths with shared prefixes and
+	// removing most of them again
+	for i := 0; i < 50; i++ {
+		path := fmt.Sprintf("dir/sub%d/file%d.txt", i%5, i)
+		metadata := map[string]string{"index": fmt.Sprint(i)}
+		if err := m.Add(path, NewEntry(reference, metadata)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 50; i++ {
+		if i%7 == 0 {
+			continue
+		}
+		if err := m.Remove(fmt.Sprintf("dir/sub%d/file%d.txt", i%5, i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	before := entries(t, m)
+	beforeCount := nodeCount(t, m)
+
+	if err := m.Compact(); err != nil {
+		t.Fatal(err)
+	}
+
+	after := entries(t, m)
+	afterCount := nodeCount(t, m)
+
+	if len(after) != len(before) {
+		t.Fatalf("got %d entries after compaction, want %d", len(after), len(before))
+	}
+	for path, e := range before {
+		a, ok := after[path]
+		if !ok {
+			t.Fatalf("entry %s missing after compaction", path)
+		}
+		if !a.Reference().Equal(e.Reference()) {
+			t.Fatalf("%s: got reference %s, want %s", path, a.Reference(), e.Reference())
+		}
+		if !reflect.DeepEqual(a.Metadata(), e.Metadata()) {
+			t.Fatalf("%s: got metadata %v, want %v", path, a.Metadata(), e.Metadata())
+		}
+	}
+
+	if afterCount > beforeCount {
+		t.Fatalf("compaction increased node count from %d to %d", beforeCount, afterCount)
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest
+
+import "fmt"
+
+// Merge adds every entry of src into dst with its path prefixed by prefix.
+// On conflicting paths the entry from src overrides the one in dst, so
+// merging several manifests into the same dst in turn lets the later ones
+// overlay the earlier ones. The manifests do not need to be of the same type.
+// The root metadata of src is not merged, as Iterate does not list it.
+func Merge(dst, src Interface, prefix string) error {
+	entries := make(map[string]Entry)
+
+	err := src.Iterate("", func(path string, entry Entry) (bool, error) {
+		entries[prefix+path] = NewEntry(entry.Reference(), entry.Metadata())
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("iterate source manifest: %w", err)
+	}
+
+	if len(entries) == 0 {
+		return nil
+	}
+
+	return dst.AddBatch(entries)
+}
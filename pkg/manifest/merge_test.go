@@ -0,0 +1,74 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest_test
+
+import (
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/manifest"
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+func TestMerge(t *testing.T) {
+	base := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+	overlay := swarm.MustParseHexAddress("2222222222222222222222222222222222222222222222222222222222222222")
+
+	for _, dstType := range manifestTypes {
+		for _, srcType := range manifestTypes {
+			t.Run(dstType+"/"+srcType, func(t *testing.T) {
+				dst, err := manifest.NewManifest(dstType, false, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, p := range []string{"assets/style.css", "assets/app.js", "index.html"} {
+					if err := dst.Add(p, manifest.NewEntry(base, nil)); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				src, err := manifest.NewManifest(srcType, false, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, p := range []string{"style.css", "logo.png"} {
+					if err := src.Add(p, manifest.NewEntry(overlay, map[string]string{"name": p})); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				if err := manifest.Merge(dst, src, "assets/"); err != nil {
+					t.Fatal(err)
+				}
+
+				for p, want := range map[string]swarm.Address{
+					"assets/style.css": overlay,
+					"assets/logo.png":  overlay,
+					"assets/app.js":    base,
+					"index.html":       base,
+				} {
+					entry, err := dst.Lookup(p)
+					if err != nil {
+						t.Fatalf("lookup %s: %v", p, err)
+					}
+					if !entry.Reference().Equal(want) {
+						t.Fatalf("%s: got reference %s, want %s", p, entry.Reference(), want)
+					}
+				}
+
+				entry, err := dst.Lookup("assets/logo.png")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := entry.Metadata()["name"]; got != "logo.png" {
+					t.Fatalf("got name metadata %q, want %q", got, "logo.png")
+				}
+
+				if _, err := src.Lookup("assets/logo.png"); err == nil {
+					t.Fatal("source manifest modified by merge")
+				}
+			})
+		}
+	}
+}
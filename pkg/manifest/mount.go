@@ -0,0 +1,120 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/ethersphere/bee/pkg/encryption"
+	"github.com/ethersphere/bee/pkg/file"
+)
+
+const (
+	// EntryMetadataTypeKey is the metadata key holding the type of entries
+	// which do not reference a file.
+	EntryMetadataTypeKey = "entry-type"
+	// EntryTypeManifest is the entry type of an entry which references
+	// another manifest, mounted on the path of the entry. The manifest type
+	// is held under EntryMetadataContentTypeKey and defaults to
+	// DefaultManifestType.
+	EntryTypeManifest = "manifest"
+
+	// DefaultMaxMountDepth is the default number of mounted manifests which
+	// LookupMounted descends into.
+	DefaultMaxMountDepth = 8
+)
+
+// ErrTooManyRedirects is returned by LookupMounted when resolving the path
+// descends into more mounted manifests than allowed, e.g. because the mounts
+// form a cycle.
+var ErrTooManyRedirects = errors.New("manifest: too many redirects")
+
+// LookupOption configures LookupMounted.
+type LookupOption func(*lookupOptions)
+
+type lookupOptions struct {
+	maxDepth int
+}
+
+// WithMaxMountDepth sets the number of mounted manifests which LookupMounted
+// descends into.
+func WithMaxMountDepth(depth int) LookupOption {
+	return func(o *lookupOptions) {
+		o.maxDepth = depth
+	}
+}
+
+// LookupMounted looks up the entry on the specified path like Lookup, but if
+// the path leads through an entry of type EntryTypeManifest, the manifest it
+// references is loaded through the load saver and the rest of the path is
+// looked up in it. Mount entries are matched on a path segment boundary, with
+// or without a trailing slash, preferring the longest one.
+func LookupMounted(m Interface, path string, ls file.LoadSaver, opts ...LookupOption) (Entry, error) {
+	o := lookupOptions{maxDepth: DefaultMaxMountDepth}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	for depth := 0; ; depth++ {
+		entry, err := m.Lookup(path)
+		if err == nil {
+			return entry, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+
+		mount, rest, err := findMount(m, path)
+		if err != nil {
+			return nil, err
+		}
+
+		if depth == o.maxDepth {
+			return nil, ErrTooManyRedirects
+		}
+
+		manifestType := mount.Metadata()[EntryMetadataContentTypeKey]
+		if manifestType == "" {
+			manifestType = DefaultManifestType
+		}
+		reference := mount.Reference()
+
+		m, err = NewManifestWithOptions(
+			manifestType,
+			ls,
+			WithReference(reference),
+			WithEncryption(len(reference.Bytes()) == encryption.ReferenceSize),
+		)
+		if err != nil {
+			return nil, err
+		}
+		path = rest
+	}
+}
+
+// findMount returns the mount entry with the longest path which the path
+// leads through, and the rest of the path below it. It returns ErrNotFound
+// if there is none.
+func findMount(m Interface, path string) (Entry, string, error) {
+	for i := strings.LastIndex(path, "/"); i >= 0; i = strings.LastIndex(path[:i], "/") {
+		for _, mountPath := range []string{path[:i+1], path[:i]} {
+			if mountPath == "" {
+				continue
+			}
+			entry, err := m.Lookup(mountPath)
+			if err != nil {
+				if errors.Is(err, ErrNotFound) {
+					continue
+				}
+				return nil, "", err
+			}
+			if entry.Metadata()[EntryMetadataTypeKey] == EntryTypeManifest {
+				return entry, path[i+1:], nil
+			}
+		}
+	}
+	return nil, "", ErrNotFound
+}
@@ -0,0 +1,83 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	loadsavemock "github.com/ethersphere/bee/pkg/file/loadsave/mock"
+	"github.com/ethersphere/bee/pkg/manifest"
+	"github.com/ethersphere/bee/pkg/storage"
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+func TestLookupMounted(t *testing.T) {
+	reference := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+
+	for _, manifestType := range manifestTypes {
+		t.Run(manifestType, func(t *testing.T) {
+			ls := loadsavemock.New()
+
+			// store a chain of manifests, each mounting the previous one
+			// under "sub/" next to its own "page.html"
+			var mounted swarm.Address
+			var root manifest.Interface
+			for i := 0; i < 3; i++ {
+				m, err := manifest.NewManifestWithOptions(manifestType, ls)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := m.Add("page.html", manifest.NewEntry(reference, nil)); err != nil {
+					t.Fatal(err)
+				}
+				if !mounted.IsZero() {
+					err = m.Add("sub/", manifest.NewEntry(mounted, map[string]string{
+						manifest.EntryMetadataTypeKey:        manifest.EntryTypeManifest,
+						manifest.EntryMetadataContentTypeKey: manifestType,
+					}))
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+				mounted, err = m.Store(context.Background(), storage.ModePutUpload)
+				if err != nil {
+					t.Fatal(err)
+				}
+				root = m
+			}
+
+			for _, p := range []string{"page.html", "sub/page.html", "sub/sub/page.html"} {
+				entry, err := manifest.LookupMounted(root, p, ls)
+				if err != nil {
+					t.Fatalf("lookup %s: %v", p, err)
+				}
+				if !entry.Reference().Equal(reference) {
+					t.Fatalf("lookup %s: got reference %s, want %s", p, entry.Reference(), reference)
+				}
+			}
+
+			// the mount entry itself is returned for its path
+			entry, err := manifest.LookupMounted(root, "sub/", ls)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if entry.Metadata()[manifest.EntryMetadataTypeKey] != manifest.EntryTypeManifest {
+				t.Fatalf("got entry %v, want the mount entry", entry)
+			}
+
+			_, err = manifest.LookupMounted(root, "sub/missing.html", ls)
+			if !errors.Is(err, manifest.ErrNotFound) {
+				t.Fatalf("got error %v, want %v", err, manifest.ErrNotFound)
+			}
+
+			_, err = manifest.LookupMounted(root, "sub/sub/page.html", ls, manifest.WithMaxMountDepth(1))
+			if !errors.Is(err, manifest.ErrTooManyRedirects) {
+				t.Fatalf("got error %v, want %v", err, manifest.ErrTooManyRedirects)
+			}
+		})
+	}
+}
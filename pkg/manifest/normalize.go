@@ -0,0 +1,45 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest
+
+// normalizedManifest wraps a manifest, normalizing the paths of entries which
+// are added, modified or looked up. Prefixes passed to the other operations
+// are used as given.
+type normalizedManifest struct {
+	Interface
+	normalize func(string) string
+}
+
+func (m *normalizedManifest) Add(path string, entry Entry) error {
+	return m.Interface.Add(m.normalize(path), entry)
+}
+
+func (m *normalizedManifest) AddBatch(entries map[string]Entry) error {
+	normalized := make(map[string]Entry, len(entries))
+	for path, entry := range entries {
+		normalized[m.normalize(path)] = entry
+	}
+	return m.Interface.AddBatch(normalized)
+}
+
+func (m *normalizedManifest) UpdateMetadata(path string, metadata map[string]string) error {
+	return m.Interface.UpdateMetadata(m.normalize(path), metadata)
+}
+
+func (m *normalizedManifest) Remove(path string) error {
+	return m.Interface.Remove(m.normalize(path))
+}
+
+func (m *normalizedManifest) Lookup(path string) (Entry, error) {
+	return m.Interface.Lookup(m.normalize(path))
+}
+
+func (m *normalizedManifest) Clone() (Interface, error) {
+	c, err := m.Interface.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return &normalizedManifest{Interface: c, normalize: m.normalize}, nil
+}
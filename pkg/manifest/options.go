@@ -0,0 +1,117 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest
+
+import (
+	"errors"
+
+	"github.com/ethersphere/bee/pkg/encryption"
+	"github.com/ethersphere/bee/pkg/file"
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/ethersphere/manifest/mantaray"
+	"github.com/ethersphere/manifest/simple"
+)
+
+// ErrNotEncrypted is returned by Store when an encrypted manifest is saved
+// by a load saver which does not encrypt.
+var ErrNotEncrypted = errors.New("manifest: load saver does not encrypt")
+
+// Option configures a manifest created with NewManifestWithOptions.
+type Option func(*options)
+
+type options struct {
+	encrypted bool
+	reference swarm.Address
+	normalize func(string) string
+}
+
+// WithEncryption requires the manifest to be stored encrypted. Encryption
+// is done by the load saver, so it must be created with encryption enabled,
+// otherwise Store returns ErrNotEncrypted.
+func WithEncryption(encrypted bool) Option {
+	return func(o *options) {
+		o.encrypted = encrypted
+	}
+}
+
+// WithReference loads the existing manifest on the reference instead of
+// creating an empty one.
+func WithReference(reference swarm.Address) Option {
+	return func(o *options) {
+		o.reference = reference
+	}
+}
+
+// WithPathNormalization applies the function to the paths passed to Add,
+// AddBatch, UpdateMetadata, Remove and Lookup, e.g. to collapse redundant
+// slashes or to make lookups case-insensitive. Entries are stored under the
+// normalized paths, so a manifest stored with a normalization must be loaded
+// with the same one, otherwise lookups of paths which the normalization
+// changes do not find their entries. By default paths are used as given.
+func WithPathNormalization(fn func(string) string) Option {
+	return func(o *options) {
+		o.normalize = fn
+	}
+}
+
+// NewManifestWithOptions creates a manifest which loads and saves all of its
+// data through the load saver. The context and mode passed to Store are not
+// used, as the load saver is already bound to them.
+func NewManifestWithOptions(manifestType string, ls file.LoadSaver, opts ...Option) (Interface, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	m, err := newManifestWithOptions(manifestType, ls, o)
+	if err != nil {
+		return nil, err
+	}
+	if o.normalize != nil {
+		return &normalizedManifest{Interface: m, normalize: o.normalize}, nil
+	}
+	return m, nil
+}
+
+func newManifestWithOptions(manifestType string, ls file.LoadSaver, o options) (Interface, error) {
+	switch manifestType {
+	case ManifestSimpleContentType, ManifestSimpleCompactContentType:
+		m := &simpleManifest{
+			manifest:  simple.NewManifest(),
+			compact:   manifestType == ManifestSimpleCompactContentType,
+			encrypted: o.encrypted,
+			ls:        ls,
+		}
+		if !o.reference.IsZero() {
+			if err := m.loadWith(ls, o.reference); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case ManifestMantarayContentType:
+		m := &mantarayManifest{
+			trie:      mantaray.New(),
+			encrypted: o.encrypted,
+			loader:    ls,
+			saver:     ls,
+		}
+		if !o.reference.IsZero() {
+			m.trie = mantaray.NewNodeRef(o.reference.Bytes())
+			m.entryCount = -1
+		}
+		return m, nil
+	default:
+		return nil, ErrInvalidManifestType
+	}
+}
+
+// checkEncrypted verifies that a manifest which requires encryption was
+// stored under an encrypted reference.
+func checkEncrypted(encrypted bool, reference []byte) error {
+	if encrypted && len(reference) != encryption.ReferenceSize {
+		return ErrNotEncrypted
+	}
+	return nil
+}
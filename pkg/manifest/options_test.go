@@ -0,0 +1,168 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/encryption"
+	"github.com/ethersphere/bee/pkg/file/loadsave"
+	"github.com/ethersphere/bee/pkg/manifest"
+	"github.com/ethersphere/bee/pkg/storage"
+	"github.com/ethersphere/bee/pkg/storage/mock"
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+func TestNewManifestWithOptionsEncrypted(t *testing.T) {
+	// references of encrypted files carry the decryption key
+	entries := map[string]swarm.Address{
+		"index.html": swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111" +
+			"2222222222222222222222222222222222222222222222222222222222222222"),
+		"img/logo.png": swarm.MustParseHexAddress("3333333333333333333333333333333333333333333333333333333333333333" +
+			"4444444444444444444444444444444444444444444444444444444444444444"),
+	}
+
+	for _, manifestType := range manifestTypes {
+		t.Run(manifestType, func(t *testing.T) {
+			ctx := context.Background()
+			storer := mock.NewStorer()
+			ls := loadsave.New(ctx, storer, storage.ModePutUpload, true)
+
+			m, err := manifest.NewManifestWithOptions(manifestType, ls, manifest.WithEncryption(true))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for p, ref := range entries {
+				if err := m.Add(p, manifest.NewEntry(ref, nil)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ref, err := m.Store(ctx, storage.ModePutUpload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ref.Bytes()) != encryption.ReferenceSize {
+				t.Fatalf("got reference length %d, want %d", len(ref.Bytes()), encryption.ReferenceSize)
+			}
+
+			loaders := map[string]func() (manifest.Interface, error){
+				"options": func() (manifest.Interface, error) {
+					return manifest.NewManifestWithOptions(manifestType, loadsave.NewLoader(ctx, storer), manifest.WithReference(ref))
+				},
+				"reference": func() (manifest.Interface, error) {
+					return manifest.NewManifestReference(ctx, manifestType, ref, true, storer)
+				},
+			}
+			for name, load := range loaders {
+				loaded, err := load()
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+				for p, want := range entries {
+					entry, err := loaded.Lookup(p)
+					if err != nil {
+						t.Fatalf("%s: lookup %s: %v", name, p, err)
+					}
+					if !entry.Reference().Equal(want) {
+						t.Fatalf("%s: %s: got reference %s, want %s", name, p, entry.Reference(), want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestNewManifestWithOptionsNotEncrypted(t *testing.T) {
+	reference := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+
+	for _, manifestType := range manifestTypes {
+		t.Run(manifestType, func(t *testing.T) {
+			ctx := context.Background()
+			ls := loadsave.New(ctx, mock.NewStorer(), storage.ModePutUpload, false)
+
+			m, err := manifest.NewManifestWithOptions(manifestType, ls, manifest.WithEncryption(true))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := m.Add("index.html", manifest.NewEntry(reference, nil)); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = m.Store(ctx, storage.ModePutUpload)
+			if !errors.Is(err, manifest.ErrNotEncrypted) {
+				t.Fatalf("got error %v, want %v", err, manifest.ErrNotEncrypted)
+			}
+		})
+	}
+}
+
+func TestWithPathNormalization(t *testing.T) {
+	reference := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+	normalize := func(p string) string {
+		for strings.Contains(p, "//") {
+			p = strings.ReplaceAll(p, "//", "/")
+		}
+		return strings.ToLower(p)
+	}
+
+	for _, manifestType := range manifestTypes {
+		t.Run(manifestType, func(t *testing.T) {
+			ctx := context.Background()
+			storer := mock.NewStorer()
+			ls := loadsave.New(ctx, storer, storage.ModePutUpload, false)
+
+			m, err := manifest.NewManifestWithOptions(manifestType, ls, manifest.WithPathNormalization(normalize))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := m.Add("Docs//Index.html", manifest.NewEntry(reference, nil)); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, p := range []string{"docs/index.html", "DOCS/INDEX.HTML", "docs///index.html"} {
+				entry, err := m.Lookup(p)
+				if err != nil {
+					t.Fatalf("lookup %s: %v", p, err)
+				}
+				if !entry.Reference().Equal(reference) {
+					t.Fatalf("lookup %s: got reference %s, want %s", p, entry.Reference(), reference)
+				}
+			}
+
+			ref, err := m.Store(ctx, storage.ModePutUpload)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the entry is stored under the normalized path
+			loaded, err := manifest.NewManifestWithOptions(manifestType, loadsave.NewLoader(ctx, storer), manifest.WithReference(ref))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := loaded.Lookup("docs/index.html"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := loaded.Lookup("Docs//Index.html"); !errors.Is(err, manifest.ErrNotFound) {
+				t.Fatalf("got error %v, want %v", err, manifest.ErrNotFound)
+			}
+
+			// without normalization paths are used as given
+			m, err = manifest.NewManifestWithOptions(manifestType, ls)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := m.Add("Docs//Index.html", manifest.NewEntry(reference, nil)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := m.Lookup("docs/index.html"); !errors.Is(err, manifest.ErrNotFound) {
+				t.Fatalf("got error %v, want %v", err, manifest.ErrNotFound)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ethersphere/bee/pkg/storage"
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+// ErrReadOnly is returned when a read-only manifest is modified or stored.
+var ErrReadOnly = errors.New("manifest: read-only")
+
+// NewManifestReferenceReadOnly loads existing manifest which rejects all
+// operations that would modify or store it with ErrReadOnly.
+func NewManifestReferenceReadOnly(
+	ctx context.Context,
+	manifestType string,
+	reference swarm.Address,
+	encrypted bool,
+	storer storage.Storer,
+) (Interface, error) {
+	m, err := NewManifestReference(ctx, manifestType, reference, encrypted, storer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &readOnlyManifest{Interface: m}, nil
+}
+
+// readOnlyManifest wraps a manifest, passing through only the operations
+// which do not modify it. Clones are not read-only, as modifying them does not
+// affect the original.
+type readOnlyManifest struct {
+	Interface
+}
+
+func (m *readOnlyManifest) Add(string, Entry) error {
+	return ErrReadOnly
+}
+
+func (m *readOnlyManifest) AddBatch(map[string]Entry) error {
+	return ErrReadOnly
+}
+
+func (m *readOnlyManifest) UpdateMetadata(string, map[string]string) error {
+	return ErrReadOnly
+}
+
+func (m *readOnlyManifest) Remove(string) error {
+	return ErrReadOnly
+}
+
+func (m *readOnlyManifest) RemovePrefix(string) (int, error) {
+	return 0, ErrReadOnly
+}
+
+func (m *readOnlyManifest) SetRootMetadata(map[string]string) error {
+	return ErrReadOnly
+}
+
+func (m *readOnlyManifest) Store(context.Context, storage.ModePut) (swarm.Address, error) {
+	return swarm.ZeroAddress, ErrReadOnly
+}
+
+func (m *readOnlyManifest) StoreContext(context.Context, storage.ModePut, func(int)) (swarm.Address, error) {
+	return swarm.ZeroAddress, ErrReadOnly
+}
+
+func (m *readOnlyManifest) RewriteReferences(map[string]swarm.Address) (int, error) {
+	return 0, ErrReadOnly
+}
+
+func (m *readOnlyManifest) Compact() error {
+	return ErrReadOnly
+}
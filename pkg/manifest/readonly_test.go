@@ -0,0 +1,96 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/manifest"
+	"github.com/ethersphere/bee/pkg/storage"
+	"github.com/ethersphere/bee/pkg/storage/mock"
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+func TestReadOnly(t *testing.T) {
+	reference := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+
+	for _, manifestType := range manifestTypes {
+		t.Run(manifestType, func(t *testing.T) {
+			ctx := context.Background()
+			storer := mock.NewStorer()
+
+			m, err := manifest.NewManifest(manifestType, false, storer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := m.Add("img/logo.png", manifest.NewEntry(reference, nil)); err != nil {
+				t.Fatal(err)
+			}
+			ref, err := m.Store(ctx, storage.ModePutUpload)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ro, err := manifest.NewManifestReferenceReadOnly(ctx, manifestType, ref, false, storer)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := ro.Type(); got != manifestType {
+				t.Fatalf("got type %q, want %q", got, manifestType)
+			}
+			if _, err := ro.Lookup("img/logo.png"); err != nil {
+				t.Fatal(err)
+			}
+			if ok, err := ro.HasPrefix("img/"); err != nil || !ok {
+				t.Fatalf("got has prefix %v, %v, want true", ok, err)
+			}
+			var paths []string
+			err = ro.Iterate("", func(path string, _ manifest.Entry) (bool, error) {
+				paths = append(paths, path)
+				return false, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(paths) != 1 {
+				t.Fatalf("got paths %v, want one", paths)
+			}
+
+			for name, err := range map[string]error{
+				"add":       ro.Add("index.html", manifest.NewEntry(reference, nil)),
+				"add batch": ro.AddBatch(map[string]manifest.Entry{"index.html": manifest.NewEntry(reference, nil)}),
+				"update":    ro.UpdateMetadata("img/logo.png", map[string]string{"Content-Type": "image/png"}),
+				"remove":    ro.Remove("img/logo.png"),
+				"compact":   ro.Compact(),
+			} {
+				if !errors.Is(err, manifest.ErrReadOnly) {
+					t.Fatalf("%s: got error %v, want %v", name, err, manifest.ErrReadOnly)
+				}
+			}
+			if _, err := ro.Store(ctx, storage.ModePutUpload); !errors.Is(err, manifest.ErrReadOnly) {
+				t.Fatalf("store: got error %v, want %v", err, manifest.ErrReadOnly)
+			}
+			if _, err := ro.StoreContext(ctx, storage.ModePutUpload, nil); !errors.Is(err, manifest.ErrReadOnly) {
+				t.Fatalf("store context: got error %v, want %v", err, manifest.ErrReadOnly)
+			}
+			if _, err := ro.RemovePrefix("a"); !errors.Is(err, manifest.ErrReadOnly) {
+				t.Fatalf("remove prefix: got error %v, want %v", err, manifest.ErrReadOnly)
+			}
+			if err := ro.SetRootMetadata(map[string]string{"a": "b"}); !errors.Is(err, manifest.ErrReadOnly) {
+				t.Fatalf("set root metadata: got error %v, want %v", err, manifest.ErrReadOnly)
+			}
+			if _, err := ro.RewriteReferences(map[string]swarm.Address{reference.String(): swarm.ZeroAddress}); !errors.Is(err, manifest.ErrReadOnly) {
+				t.Fatalf("rewrite references: got error %v, want %v", err, manifest.ErrReadOnly)
+			}
+
+			if _, err := ro.Lookup("img/logo.png"); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+const (
+	// RootPath is the path of the manifest entry holding manifest-level
+	// metadata.
+	RootPath = "/"
+	// WebsiteIndexDocumentSuffixKey is the root metadata key holding the name
+	// of the document served for directory paths.
+	WebsiteIndexDocumentSuffixKey = "website-index-document"
+	// WebsiteErrorDocumentPathKey is the root metadata key holding the path
+	// of the document served when nothing is found on the requested path.
+	WebsiteErrorDocumentPathKey = "website-error-document"
+)
+
+// ResolveForServing looks up the entry on the specified path like Lookup,
+// but reports entries which have expired according to the provided clock as
+// not found.
+func ResolveForServing(m Interface, path string, now func() time.Time) (Entry, error) {
+	entry, err := m.Lookup(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if expires, ok := entry.Expires(); ok && !now().Before(expires) {
+		return nil, ErrNotFound
+	}
+
+	return entry, nil
+}
+
+// ResolveWeb resolves the path like a web server would, falling back to the
+// index and error documents configured in the root entry metadata. The
+// returned status is:
+//   - http.StatusOK with the entry found on the path or its index document,
+//   - http.StatusPermanentRedirect with a nil entry if the path is a directory
+//     which should be requested with a trailing slash,
+//   - http.StatusNotFound with the error document entry, if one is
+//     configured, or with a nil entry and an error otherwise.
+func ResolveWeb(m Interface, p string, now func() time.Time) (Entry, int, error) {
+	indexDocument, hasIndex := rootMetadata(m, WebsiteIndexDocumentSuffixKey)
+
+	if p == "" && hasIndex {
+		if entry, err := ResolveForServing(m, indexDocument, now); err == nil {
+			return entry, http.StatusOK, nil
+		}
+	}
+
+	entry, err := ResolveForServing(m, p, now)
+	if err == nil {
+		return entry, http.StatusOK, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, http.StatusNotFound, err
+	}
+
+	if !strings.HasPrefix(p, "/") {
+		// check for directory
+		if exists, err := m.HasPrefix(p + "/"); err == nil && exists {
+			return nil, http.StatusPermanentRedirect, nil
+		}
+	}
+
+	if hasIndex && !strings.HasSuffix(p, indexDocument) {
+		// check if path is directory with index
+		if entry, err := ResolveForServing(m, path.Join(p, indexDocument), now); err == nil {
+			return entry, http.StatusOK, nil
+		}
+	}
+
+	if errorDocument, ok := rootMetadata(m, WebsiteErrorDocumentPathKey); ok && p != errorDocument {
+		if entry, err := ResolveForServing(m, errorDocument, now); err == nil {
+			return entry, http.StatusNotFound, nil
+		}
+	}
+
+	return nil, http.StatusNotFound, ErrNotFound
+}
+
+// rootMetadata returns the value for a key stored in the root metadata of the
+// manifest. The ok result indicates whether the value was found.
+func rootMetadata(m Interface, key string) (value string, ok bool) {
+	metadata, err := m.RootMetadata()
+	if err != nil {
+		return "", false
+	}
+	value, ok = metadata[key]
+	return value, ok
+}
+
+// setRootMetadata replaces the metadata of the RootPath entry, or removes the
+// entry if the metadata is empty.
+func setRootMetadata(m Interface, metadata map[string]string) error {
+	if len(metadata) == 0 {
+		err := m.Remove(RootPath)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		return nil
+	}
+	return m.Add(RootPath, NewEntry(swarm.ZeroAddress, metadata))
+}
+
+// lookupRootMetadata returns the metadata of the RootPath entry, or nil if
+// there is no such entry.
+func lookupRootMetadata(m Interface) (map[string]string, error) {
+	entry, err := m.Lookup(RootPath)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return entry.Metadata(), nil
+}
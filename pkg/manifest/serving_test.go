@@ -0,0 +1,159 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest_test
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/manifest"
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+func TestResolveForServing(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	clock := func() time.Time { return now }
+	reference := swarm.MustParseHexAddress("8a74889a73c23fe8be0e5a5b5c2e7c5e4fd6a0cf6bbc3c0a7c0e3e2e4c0bd9f7")
+
+	for _, tc := range []struct {
+		name     string
+		metadata map[string]string
+		expired  bool
+	}{
+		{
+			name: "no expiry",
+		},
+		{
+			name: "unexpired epoch",
+			metadata: map[string]string{
+				manifest.EntryMetadataExpiresKey: strconv.FormatInt(now.Add(time.Hour).Unix(), 10),
+			},
+		},
+		{
+			name: "unexpired http date",
+			metadata: map[string]string{
+				manifest.EntryMetadataExpiresKey: now.Add(time.Hour).UTC().Format(http.TimeFormat),
+			},
+		},
+		{
+			name: "expired epoch",
+			metadata: map[string]string{
+				manifest.EntryMetadataExpiresKey: strconv.FormatInt(now.Add(-time.Hour).Unix(), 10),
+			},
+			expired: true,
+		},
+		{
+			name: "expired http date",
+			metadata: map[string]string{
+				manifest.EntryMetadataExpiresKey: now.Add(-time.Hour).UTC().Format(http.TimeFormat),
+			},
+			expired: true,
+		},
+	} {
+		for _, manifestType := range manifestTypes {
+			t.Run(tc.name+" "+manifestType, func(t *testing.T) {
+				m, err := manifest.NewManifest(manifestType, false, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				err = m.Add("file.txt", manifest.NewEntry(reference, tc.metadata))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				entry, err := manifest.ResolveForServing(m, "file.txt", clock)
+				if tc.expired {
+					if !errors.Is(err, manifest.ErrNotFound) {
+						t.Fatalf("got error %v, want %v", err, manifest.ErrNotFound)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !entry.Reference().Equal(reference) {
+					t.Fatalf("got reference %s, want %s", entry.Reference(), reference)
+				}
+			})
+		}
+	}
+}
+
+func TestResolveWeb(t *testing.T) {
+	var (
+		indexReference = swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+		errorReference = swarm.MustParseHexAddress("2222222222222222222222222222222222222222222222222222222222222222")
+		fileReference  = swarm.MustParseHexAddress("3333333333333333333333333333333333333333333333333333333333333333")
+	)
+
+	for _, manifestType := range manifestTypes {
+		t.Run(manifestType, func(t *testing.T) {
+			m, err := manifest.NewManifest(manifestType, false, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = m.AddBatch(map[string]manifest.Entry{
+				manifest.RootPath: manifest.NewEntry(swarm.ZeroAddress, map[string]string{
+					manifest.WebsiteIndexDocumentSuffixKey: "index.html",
+					manifest.WebsiteErrorDocumentPathKey:   "404.html",
+				}),
+				"index.html":      manifest.NewEntry(indexReference, nil),
+				"404.html":        manifest.NewEntry(errorReference, nil),
+				"docs/index.html": manifest.NewEntry(indexReference, nil),
+				"docs/file.txt":   manifest.NewEntry(fileReference, nil),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, tc := range []struct {
+				path      string
+				status    int
+				reference swarm.Address
+			}{
+				{path: "", status: http.StatusOK, reference: indexReference},
+				{path: "docs/file.txt", status: http.StatusOK, reference: fileReference},
+				{path: "docs/", status: http.StatusOK, reference: indexReference},
+				{path: "docs", status: http.StatusPermanentRedirect},
+				{path: "missing.txt", status: http.StatusNotFound, reference: errorReference},
+			} {
+				entry, status, err := manifest.ResolveWeb(m, tc.path, time.Now)
+				if err != nil {
+					t.Fatalf("%q: %v", tc.path, err)
+				}
+				if status != tc.status {
+					t.Fatalf("%q: got status %d, want %d", tc.path, status, tc.status)
+				}
+				if tc.reference.IsZero() {
+					if entry != nil {
+						t.Fatalf("%q: got entry %v, want none", tc.path, entry)
+					}
+					continue
+				}
+				if !entry.Reference().Equal(tc.reference) {
+					t.Fatalf("%q: got reference %s, want %s", tc.path, entry.Reference(), tc.reference)
+				}
+			}
+
+			// without an error document, missing paths are not found
+			err = m.Remove("404.html")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, status, err := manifest.ResolveWeb(m, "missing.txt", time.Now)
+			if !errors.Is(err, manifest.ErrNotFound) {
+				t.Fatalf("got error %v, want %v", err, manifest.ErrNotFound)
+			}
+			if status != http.StatusNotFound {
+				t.Fatalf("got status %d, want %d", status, http.StatusNotFound)
+			}
+		})
+	}
+}
@@ -7,11 +7,15 @@ package manifest
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/ethersphere/bee/pkg/file"
 	"github.com/ethersphere/bee/pkg/file/joiner"
+	"github.com/ethersphere/bee/pkg/file/loadsave"
 	"github.com/ethersphere/bee/pkg/file/pipeline/builder"
 	"github.com/ethersphere/bee/pkg/storage"
 	"github.com/ethersphere/bee/pkg/swarm"
@@ -26,9 +30,14 @@ const (
 
 type simpleManifest struct {
 	manifest simple.Manifest
+	compact  bool // serialized in the compact format instead of JSON
 
 	encrypted bool
 	storer    storage.Storer
+	ls        file.LoadSaver // used instead of the storer, if set
+
+	reference swarm.Address // address of the last stored or loaded state
+	modified  bool          // changed since created, loaded or stored
 }
 
 // NewSimpleManifest creates a new simple manifest.
@@ -60,15 +69,47 @@ func NewSimpleManifestReference(
 }
 
 func (m *simpleManifest) Type() string {
+	if m.compact {
+		return ManifestSimpleCompactContentType
+	}
 	return ManifestSimpleContentType
 }
 
 func (m *simpleManifest) Add(path string, entry Entry) error {
 	e := entry.Reference().String()
 
+	m.modified = true
+
 	return m.manifest.Add(path, e, entry.Metadata())
 }
 
+func (m *simpleManifest) AddBatch(entries map[string]Entry) error {
+	for p, entry := range entries {
+		if err := m.Add(p, entry); err != nil {
+			return fmt.Errorf("add %s: %w", p, err)
+		}
+	}
+
+	return nil
+}
+
+// UpdateMetadata rejects empty metadata like the mantaray manifest, which can
+// not clear the metadata of an entry.
+func (m *simpleManifest) UpdateMetadata(path string, metadata map[string]string) error {
+	if len(metadata) == 0 {
+		return ErrEmptyMetadata
+	}
+
+	n, err := m.manifest.Lookup(path)
+	if err != nil {
+		return ErrNotFound
+	}
+
+	m.modified = true
+
+	return m.manifest.Add(path, n.Reference(), metadata)
+}
+
 func (m *simpleManifest) Remove(path string) error {
 
 	err := m.manifest.Remove(path)
@@ -79,9 +120,45 @@ func (m *simpleManifest) Remove(path string) error {
 		return err
 	}
 
+	m.modified = true
+
 	return nil
 }
 
+// SetRootMetadata stores the metadata on the RootPath entry.
+func (m *simpleManifest) SetRootMetadata(metadata map[string]string) error {
+	return setRootMetadata(m, metadata)
+}
+
+func (m *simpleManifest) RootMetadata() (map[string]string, error) {
+	return lookupRootMetadata(m)
+}
+
+func (m *simpleManifest) RemovePrefix(prefix string) (int, error) {
+	paths, err := m.paths()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, p := range paths {
+		// the RootPath entry holds the root metadata
+		if p == RootPath || !strings.HasPrefix(p, prefix) {
+			continue
+		}
+		if err := m.manifest.Remove(p); err != nil {
+			return removed, err
+		}
+		m.modified = true
+		removed++
+	}
+	if removed == 0 {
+		return 0, ErrNotFound
+	}
+
+	return removed, nil
+}
+
 func (m *simpleManifest) Lookup(path string) (Entry, error) {
 
 	n, err := m.manifest.Lookup(path)
@@ -104,21 +181,227 @@ func (m *simpleManifest) HasPrefix(prefix string) (bool, error) {
 }
 
 func (m *simpleManifest) Store(ctx context.Context, mode storage.ModePut) (swarm.Address, error) {
+	if !m.modified && !m.reference.IsZero() {
+		return m.reference, nil
+	}
 
-	data, err := m.manifest.MarshalBinary()
+	data, err := m.marshal()
 	if err != nil {
 		return swarm.ZeroAddress, fmt.Errorf("manifest marshal error: %w", err)
 	}
 
-	pipe := builder.NewPipelineBuilder(ctx, m.storer, mode, m.encrypted)
-	address, err := builder.FeedPipeline(ctx, pipe, bytes.NewReader(data), int64(len(data)))
+	var address swarm.Address
+	if m.ls != nil {
+		ref, err := m.ls.Save(data)
+		if err != nil {
+			return swarm.ZeroAddress, fmt.Errorf("manifest save error: %w", err)
+		}
+		address = swarm.NewAddress(ref)
+	} else {
+		pipe := builder.NewPipelineBuilder(ctx, m.storer, mode, m.encrypted)
+		address, err = builder.FeedPipeline(ctx, pipe, bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return swarm.ZeroAddress, fmt.Errorf("manifest save error: %w", err)
+		}
+	}
+
+	if err := checkEncrypted(m.encrypted, address.Bytes()); err != nil {
+		return swarm.ZeroAddress, err
+	}
+
+	m.reference = address
+	m.modified = false
+
+	return address, nil
+}
+
+// StoreContext stores the manifest like Store. The manifest is serialized as
+// a whole, so progress is only reported once it has been saved.
+func (m *simpleManifest) StoreContext(ctx context.Context, mode storage.ModePut, progress func(saved int)) (swarm.Address, error) {
+	if err := ctx.Err(); err != nil {
+		return swarm.ZeroAddress, err
+	}
+
+	address, err := m.Store(ctx, mode)
 	if err != nil {
-		return swarm.ZeroAddress, fmt.Errorf("manifest save error: %w", err)
+		return swarm.ZeroAddress, err
+	}
+
+	if progress != nil {
+		progress(1)
 	}
 
 	return address, nil
 }
 
+func (m *simpleManifest) RewriteReferences(mapping map[string]swarm.Address) (int, error) {
+	count := 0
+	err := m.walkEntries(func(path string, entry Entry) error {
+		newReference, ok := mapping[entry.Reference().String()]
+		if !ok {
+			return nil
+		}
+		count++
+		m.modified = true
+		return m.manifest.Add(path, newReference.String(), entry.Metadata())
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Compact is a no-op as simple manifests are flat and hold no structure
+// besides their entries.
+func (m *simpleManifest) Compact() error {
+	return nil
+}
+
+// Clone returns a copy of the manifest, made by serializing the entries into
+// a new simple manifest.
+func (m *simpleManifest) Clone() (Interface, error) {
+	data, err := m.manifest.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("manifest marshal error: %w", err)
+	}
+
+	clone := &simpleManifest{
+		manifest:  simple.NewManifest(),
+		compact:   m.compact,
+		encrypted: m.encrypted,
+		storer:    m.storer,
+		ls:        m.ls,
+		reference: m.reference,
+		modified:  m.modified,
+	}
+
+	err = clone.manifest.UnmarshalBinary(data)
+	if err != nil {
+		return nil, fmt.Errorf("manifest unmarshal error: %w", err)
+	}
+
+	return clone, nil
+}
+
+func (m *simpleManifest) WalkFrom(cursor string, limit int) ([]PathEntry, string, error) {
+	return walkFrom(m.walkEntries, cursor, limit)
+}
+
+func (m *simpleManifest) Iterate(prefix string, fn func(path string, entry Entry) (stop bool, err error)) error {
+	return iterate(m.walkEntries, prefix, fn)
+}
+
+func (m *simpleManifest) ListPrefix(prefix string) ([]string, error) {
+	return listPrefix(m.walkEntries, prefix)
+}
+
+// EntryCount returns the number of entries in the manifest, not counting the
+// RootPath entry.
+func (m *simpleManifest) EntryCount() (int, error) {
+	count := m.manifest.Length()
+	if _, err := m.manifest.Lookup(RootPath); err == nil {
+		count--
+	}
+	return count, nil
+}
+
+func (m *simpleManifest) Modified() bool {
+	return m.modified
+}
+
+// StorageSize returns the size of the serialized manifest, which is stored as
+// a single file.
+func (m *simpleManifest) StorageSize() (int64, error) {
+	data, err := m.marshal()
+	if err != nil {
+		return 0, fmt.Errorf("manifest marshal error: %w", err)
+	}
+	return int64(len(data)), nil
+}
+
+// Validate loads the data of every entry. The manifest has no nodes apart
+// from its root, which is loaded with the manifest.
+func (m *simpleManifest) Validate(ctx context.Context) ([]ValidationError, error) {
+	var entries []PathEntry
+	err := m.walkEntries(func(path string, entry Entry) error {
+		entries = append(entries, PathEntry{Path: path, Entry: entry})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var loader file.Loader = m.ls
+	if m.ls == nil {
+		loader = loadsave.NewLoader(ctx, m.storer)
+	}
+
+	return validateEntries(ctx, loader, entries)
+}
+
+// walkEntries calls fn for every entry in the manifest in sorted path order.
+func (m *simpleManifest) walkEntries(fn func(path string, entry Entry) error) error {
+	paths, err := m.paths()
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		entry, err := m.Lookup(p)
+		if err != nil {
+			return err
+		}
+		if err := fn(p, entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// paths returns the paths of all entries in no particular order.
+func (m *simpleManifest) paths() ([]string, error) {
+	// simple.Manifest does not expose its entries, so the paths are
+	// recovered from the serialized form
+	data, err := m.manifest.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("manifest marshal error: %w", err)
+	}
+
+	var serialized struct {
+		Entries map[string]json.RawMessage `json:"entries"`
+	}
+	err = json.Unmarshal(data, &serialized)
+	if err != nil {
+		return nil, fmt.Errorf("manifest unmarshal error: %w", err)
+	}
+
+	paths := make([]string, 0, len(serialized.Entries))
+	for p := range serialized.Entries {
+		paths = append(paths, p)
+	}
+
+	return paths, nil
+}
+
+// marshal serializes the manifest in its format.
+func (m *simpleManifest) marshal() ([]byte, error) {
+	if m.compact {
+		return m.marshalCompact()
+	}
+	return m.manifest.MarshalBinary()
+}
+
+// unmarshal replaces the entries of the manifest with the serialized ones.
+func (m *simpleManifest) unmarshal(data []byte) error {
+	if m.compact {
+		return m.unmarshalCompact(data)
+	}
+	return m.manifest.UnmarshalBinary(data)
+}
+
 func (m *simpleManifest) load(ctx context.Context, reference swarm.Address) error {
 	j, _, err := joiner.New(ctx, m.storer, reference)
 	if err != nil {
@@ -131,10 +414,29 @@ func (m *simpleManifest) load(ctx context.Context, reference swarm.Address) erro
 		return fmt.Errorf("manifest load error: %w", err)
 	}
 
-	err = m.manifest.UnmarshalBinary(buf.Bytes())
+	err = m.unmarshal(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("manifest unmarshal error: %w", err)
+	}
+
+	m.reference = reference
+
+	return nil
+}
+
+// loadWith loads the manifest through the load saver.
+func (m *simpleManifest) loadWith(ls file.LoadSaver, reference swarm.Address) error {
+	data, err := ls.Load(reference.Bytes())
+	if err != nil {
+		return fmt.Errorf("manifest load error: %w", err)
+	}
+
+	err = m.unmarshal(data)
 	if err != nil {
 		return fmt.Errorf("manifest unmarshal error: %w", err)
 	}
 
+	m.reference = reference
+
 	return nil
 }
@@ -0,0 +1,199 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ethersphere/bee/pkg/storage"
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/ethersphere/manifest/simple"
+)
+
+const (
+	// ManifestSimpleCompactContentType represents content type used for
+	// noting that specific file should be processed as 'simple' manifest
+	// serialized in the compact binary format
+	ManifestSimpleCompactContentType = "application/bzz-manifest-simple+octet-stream"
+)
+
+// simpleCompactMagic starts every simple manifest in the compact format. The
+// leading zero byte can not start a JSON document.
+var simpleCompactMagic = []byte("\x00bzzsimple\x01")
+
+// errInvalidCompactManifest is returned when the data is not a simple
+// manifest in the compact format.
+var errInvalidCompactManifest = errors.New("invalid compact simple manifest")
+
+// NewSimpleCompactManifest creates a new simple manifest which is serialized
+// in the compact binary format instead of JSON. Entries are the same as in a
+// simple manifest, but references are stored as raw bytes and the encoding
+// needs no field names or quoting, which makes large flat manifests smaller.
+func NewSimpleCompactManifest(
+	encrypted bool,
+	storer storage.Storer,
+) (Interface, error) {
+	return &simpleManifest{
+		manifest:  simple.NewManifest(),
+		compact:   true,
+		encrypted: encrypted,
+		storer:    storer,
+	}, nil
+}
+
+// NewSimpleCompactManifestReference loads existing simple manifest in the
+// compact format.
+func NewSimpleCompactManifestReference(
+	ctx context.Context,
+	reference swarm.Address,
+	encrypted bool,
+	storer storage.Storer,
+) (Interface, error) {
+	m := &simpleManifest{
+		manifest:  simple.NewManifest(),
+		compact:   true,
+		encrypted: encrypted,
+		storer:    storer,
+	}
+	err := m.load(ctx, reference)
+	return m, err
+}
+
+// marshalCompact serializes the entries of the manifest sorted by path. Each
+// entry is the length prefixed path and reference, followed by the number of
+// metadata pairs and the length prefixed keys and values, all lengths being
+// unsigned varints.
+func (m *simpleManifest) marshalCompact() ([]byte, error) {
+	paths, err := m.paths()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	buf := bytes.NewBuffer(nil)
+	buf.Write(simpleCompactMagic)
+
+	putUvarint := func(v uint64) {
+		var b [binary.MaxVarintLen64]byte
+		buf.Write(b[:binary.PutUvarint(b[:], v)])
+	}
+	putBytes := func(b []byte) {
+		putUvarint(uint64(len(b)))
+		buf.Write(b)
+	}
+
+	putUvarint(uint64(len(paths)))
+	for _, p := range paths {
+		e, err := m.manifest.Lookup(p)
+		if err != nil {
+			return nil, err
+		}
+		reference, err := hex.DecodeString(e.Reference())
+		if err != nil {
+			return nil, fmt.Errorf("entry %s: %w", p, err)
+		}
+
+		putBytes([]byte(p))
+		putBytes(reference)
+
+		metadata := e.Metadata()
+		keys := make([]string, 0, len(metadata))
+		for k := range metadata {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		putUvarint(uint64(len(keys)))
+		for _, k := range keys {
+			putBytes([]byte(k))
+			putBytes([]byte(metadata[k]))
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// unmarshalCompact replaces the entries of the manifest with the ones in the
+// compact serialization.
+func (m *simpleManifest) unmarshalCompact(data []byte) error {
+	if !isSimpleCompact(data) {
+		return errInvalidCompactManifest
+	}
+	r := bytes.NewReader(data[len(simpleCompactMagic):])
+
+	readBytes := func() ([]byte, error) {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(r.Len()) {
+			return nil, errInvalidCompactManifest
+		}
+		b := make([]byte, n)
+		_, err = r.Read(b)
+		return b, err
+	}
+
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return errInvalidCompactManifest
+	}
+
+	manifest := simple.NewManifest()
+	for i := uint64(0); i < count; i++ {
+		p, err := readBytes()
+		if err != nil {
+			return errInvalidCompactManifest
+		}
+		reference, err := readBytes()
+		if err != nil {
+			return errInvalidCompactManifest
+		}
+
+		pairs, err := binary.ReadUvarint(r)
+		if err != nil {
+			return errInvalidCompactManifest
+		}
+		var metadata map[string]string
+		if pairs > 0 {
+			metadata = make(map[string]string)
+		}
+		for j := uint64(0); j < pairs; j++ {
+			k, err := readBytes()
+			if err != nil {
+				return errInvalidCompactManifest
+			}
+			v, err := readBytes()
+			if err != nil {
+				return errInvalidCompactManifest
+			}
+			metadata[string(k)] = string(v)
+		}
+
+		err = manifest.Add(string(p), hex.EncodeToString(reference), metadata)
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.Len() > 0 {
+		return errInvalidCompactManifest
+	}
+
+	m.manifest = manifest
+
+	return nil
+}
+
+// isSimpleCompact checks for the magic bytes of the compact format.
+func isSimpleCompact(data []byte) bool {
+	return bytes.HasPrefix(data, simpleCompactMagic)
+}
@@ -0,0 +1,82 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/file/loadsave"
+	"github.com/ethersphere/bee/pkg/manifest"
+	"github.com/ethersphere/bee/pkg/storage"
+	"github.com/ethersphere/bee/pkg/storage/mock"
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+func TestSimpleCompactSize(t *testing.T) {
+	ctx := context.Background()
+	storer := mock.NewStorer()
+	ls := loadsave.NewLoader(ctx, storer)
+
+	entries := make(map[string]manifest.Entry)
+	for i := 0; i < 1000; i++ {
+		reference := swarm.MustParseHexAddress(fmt.Sprintf("%064x", i+1))
+		entries[fmt.Sprintf("file%d.txt", i)] = manifest.NewEntry(reference, map[string]string{
+			manifest.EntryMetadataContentTypeKey: "text/plain",
+		})
+	}
+
+	sizes := make(map[string]int)
+	refs := make(map[string]swarm.Address)
+	for _, manifestType := range []string{manifest.ManifestSimpleContentType, manifest.ManifestSimpleCompactContentType} {
+		m, err := manifest.NewManifest(manifestType, false, storer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := m.AddBatch(entries); err != nil {
+			t.Fatal(err)
+		}
+		ref, err := m.Store(ctx, storage.ModePutUpload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ls.Load(ref.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes[manifestType] = len(data)
+		refs[manifestType] = ref
+	}
+
+	simpleSize, compactSize := sizes[manifest.ManifestSimpleContentType], sizes[manifest.ManifestSimpleCompactContentType]
+	if compactSize >= simpleSize {
+		t.Fatalf("compact manifest of %d bytes not smaller than the simple manifest of %d bytes", compactSize, simpleSize)
+	}
+
+	loaded, err := manifest.NewManifestReference(ctx, manifest.ManifestSimpleCompactContentType, refs[manifest.ManifestSimpleCompactContentType], false, storer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p, want := range entries {
+		entry, err := loaded.Lookup(p)
+		if err != nil {
+			t.Fatalf("lookup %s: %v", p, err)
+		}
+		if !entry.Reference().Equal(want.Reference()) {
+			t.Fatalf("%s: got reference %s, want %s", p, entry.Reference(), want.Reference())
+		}
+		if !reflect.DeepEqual(entry.Metadata(), want.Metadata()) {
+			t.Fatalf("%s: got metadata %v, want %v", p, entry.Metadata(), want.Metadata())
+		}
+	}
+
+	// the formats are not interchangeable
+	_, err = manifest.NewManifestReference(ctx, manifest.ManifestSimpleCompactContentType, refs[manifest.ManifestSimpleContentType], false, storer)
+	if err == nil {
+		t.Fatal("loaded a JSON simple manifest as a compact one")
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/ethersphere/bee/pkg/file"
+	"github.com/ethersphere/bee/pkg/file/loadsave"
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/ethersphere/manifest/mantaray"
+)
+
+// ValidationError is a part of the manifest which could not be loaded.
+type ValidationError struct {
+	Path      string        // path of the entry, empty for a manifest node
+	Reference swarm.Address // reference which could not be loaded
+	Err       error
+}
+
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("manifest node %s: %v", e.Reference, e.Err)
+	}
+	return fmt.Sprintf("entry %s: reference %s: %v", e.Path, e.Reference, e.Err)
+}
+
+// validateEntries loads the data of every entry through the loader and
+// returns the entries which could not be loaded. Entries without a reference,
+// like the one holding the root metadata, are skipped. It returns an error
+// only if the context is done.
+func validateEntries(ctx context.Context, loader file.Loader, entries []PathEntry) ([]ValidationError, error) {
+	var failures []ValidationError
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		reference := e.Entry.Reference()
+		if reference.IsZero() {
+			continue
+		}
+		if err := loadAll(loader, reference); err != nil {
+			failures = append(failures, ValidationError{
+				Path:      e.Path,
+				Reference: reference,
+				Err:       err,
+			})
+		}
+	}
+	return failures, nil
+}
+
+// loadAll loads all the data on the reference, streaming it if the loader
+// supports it.
+func loadAll(loader file.Loader, reference swarm.Address) error {
+	rl, ok := loader.(loadsave.ReadLoader)
+	if !ok {
+		_, err := loader.Load(reference.Bytes())
+		return err
+	}
+
+	r, _, err := rl.LoadReader(reference.Bytes())
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	_, err = io.Copy(ioutil.Discard, r)
+	return err
+}
+
+// failureRecordingLoader records the reference of the last manifest node
+// which failed to load.
+type failureRecordingLoader struct {
+	mantaray.LoadSaver
+	failed []byte
+}
+
+func (l *failureRecordingLoader) Load(reference []byte) ([]byte, error) {
+	data, err := l.LoadSaver.Load(reference)
+	if err != nil {
+		l.failed = reference
+	}
+	return data, err
+}
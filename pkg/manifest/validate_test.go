@@ -0,0 +1,90 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manifest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/file/loadsave"
+	"github.com/ethersphere/bee/pkg/manifest"
+	"github.com/ethersphere/bee/pkg/storage"
+	"github.com/ethersphere/bee/pkg/storage/mock"
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+func TestValidate(t *testing.T) {
+	missing := map[string]swarm.Address{
+		"img/logo.png": swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111"),
+		"robots.txt":   swarm.MustParseHexAddress("2222222222222222222222222222222222222222222222222222222222222222"),
+	}
+
+	for _, manifestType := range manifestTypes {
+		t.Run(manifestType, func(t *testing.T) {
+			ctx := context.Background()
+			storer := mock.NewStorer()
+			ls := loadsave.New(ctx, storer, storage.ModePutUpload, false)
+
+			m, err := manifest.NewManifest(manifestType, false, storer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range []string{"index.html", "css/style.css"} {
+				ref, err := ls.Save([]byte("content of " + p))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := m.Add(p, manifest.NewEntry(swarm.NewAddress(ref), nil)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for p, ref := range missing {
+				if err := m.Add(p, manifest.NewEntry(ref, nil)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// entries without a reference are not validated
+			if err := m.SetRootMetadata(map[string]string{"website-index-document": "index.html"}); err != nil {
+				t.Fatal(err)
+			}
+
+			reference, err := m.Store(ctx, storage.ModePutUpload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := manifest.NewManifestReference(ctx, manifestType, reference, false, storer)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			failures, err := loaded.Validate(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(failures) != len(missing) {
+				t.Fatalf("got %d validation errors, want %d: %v", len(failures), len(missing), failures)
+			}
+			for _, f := range failures {
+				want, ok := missing[f.Path]
+				if !ok {
+					t.Fatalf("got validation error for path %q: %v", f.Path, f)
+				}
+				if !f.Reference.Equal(want) {
+					t.Fatalf("%s: got reference %s, want %s", f.Path, f.Reference, want)
+				}
+				if f.Err == nil {
+					t.Fatalf("%s: missing error", f.Path)
+				}
+			}
+
+			cctx, cancel := context.WithCancel(ctx)
+			cancel()
+			if _, err := loaded.Validate(cctx); !errors.Is(err, context.Canceled) {
+				t.Fatalf("got error %v, want %v", err, context.Canceled)
+			}
+		})
+	}
+}
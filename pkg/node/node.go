@@ -78,6 +78,7 @@ type Bee struct {
 	pullerCloser          io.Closer
 	pullSyncCloser        io.Closer
 	pssCloser             io.Closer
+	cashoutCloser         io.Closer
 	recoveryHandleCleanup func()
 }
 
@@ -221,10 +222,14 @@ func NewBee(addr string, swarmAddress swarm.Address, publicKey ecdsa.PublicKey,
 
 		chequeStore = chequebook.NewChequeStore(stateStore, swapBackend, chequebookFactory, chainID.Int64(), overlayEthAddress, chequebook.NewSimpleSwapBindings, chequebook.RecoverCheque)
 
-		cashoutService, err = chequebook.NewCashoutService(stateStore, chequebook.NewSimpleSwapBindings, swapBackend, transactionService, chequeStore)
+		cashoutService, err = chequebook.NewCashoutService(logger, stateStore, chequebook.NewSimpleSwapBindings, swapBackend, transactionService, chequeStore)
 		if err != nil {
 			return nil, err
 		}
+		if err = cashoutService.Start(); err != nil {
+			return nil, fmt.Errorf("cashout service: %w", err)
+		}
+		b.cashoutCloser = cashoutService
 	}
 
 	p2ps, err := libp2p.New(p2pCtx, signer, networkID, swarmAddress, addr, addressbook, stateStore, logger, tracer, libp2p.Options{
@@ -467,6 +472,10 @@ func NewBee(addr string, swarmAddress swarm.Address, publicKey ecdsa.PublicKey,
 			debugAPIService.MustRegisterMetrics(l.Metrics()...)
 		}
 
+		if c, ok := cashoutService.(metrics.Collector); ok {
+			debugAPIService.MustRegisterMetrics(c.Metrics()...)
+		}
+
 		if l, ok := settlement.(metrics.Collector); ok {
 			debugAPIService.MustRegisterMetrics(l.Metrics()...)
 		}
@@ -566,6 +575,12 @@ func (b *Bee) Shutdown(ctx context.Context) error {
 		errs.add(fmt.Errorf("tag persistence: %w", err))
 	}
 
+	if b.cashoutCloser != nil {
+		if err := b.cashoutCloser.Close(); err != nil {
+			errs.add(fmt.Errorf("cashout service: %w", err))
+		}
+	}
+
 	if err := b.stateStoreCloser.Close(); err != nil {
 		errs.add(fmt.Errorf("statestore: %w", err))
 	}
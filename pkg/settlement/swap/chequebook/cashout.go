@@ -6,14 +6,23 @@ package chequebook
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"math/big"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethersphere/bee/pkg/logging"
 	"github.com/ethersphere/bee/pkg/settlement/swap/transaction"
 	"github.com/ethersphere/bee/pkg/storage"
 	"github.com/ethersphere/sw3-bindings/v2/simpleswapfactory"
@@ -22,31 +31,247 @@ import (
 var (
 	// ErrNoCashout is the error if there has not been any cashout action for the chequebook
 	ErrNoCashout = errors.New("no prior cashout")
+	// ErrCashoutCooldown is the error if the last cashout for the chequebook is too recent
+	ErrCashoutCooldown = errors.New("cashout cooldown period has not passed")
+	// ErrNothingToCash is the error if the last cheque of the chequebook has already been cashed completely
+	ErrNothingToCash = errors.New("nothing to cash")
+	// ErrInvalidCashoutAmount is the error if a cashout amount is not positive or exceeds the uncashed part of the cheque
+	ErrInvalidCashoutAmount = errors.New("invalid cashout amount")
+	// ErrPartialCashoutUnsigned is the error if a cashout amount is less than the uncashed part of the cheque, as the cheque is only signed for its cumulative payout
+	ErrPartialCashoutUnsigned = errors.New("no cheque signed for the partial cashout")
+	// ErrUnprofitable is the error if the projected profit of a cashout is below the configured minimum
+	ErrUnprofitable = errors.New("cashout is unprofitable")
+	// ErrNotAChequebook is the error if the address to cash a cheque from is not a chequebook contract
+	ErrNotAChequebook = errors.New("not a chequebook")
+	// ErrNoDefaultRecipient is the error if a cheque is cashed to the default recipient but none is configured
+	ErrNoDefaultRecipient = errors.New("no default cashout recipient")
+	// ErrNoChequeCashedEvent is the error if the receipt of a successful cashout transaction has no ChequeCashed event of the chequebook
+	ErrNoChequeCashedEvent = errors.New("no ChequeCashed event in cashout receipt")
+)
+
+const (
+	cashoutActionPrefix   = "cashout_"
+	cashoutSequencePrefix = "cashoutsequence_"
+	// defaultCashoutHistoryLimit is the default number of cashout actions retained per chequebook
+	defaultCashoutHistoryLimit = 10
+	// defaultReceiptPollInterval is the default interval in which the receipts of pending cashouts are fetched
+	defaultReceiptPollInterval = 5 * time.Second
+	// defaultReceiptTimeout is the default time after which fetching the receipt of a pending cashout is abandoned until the next poll
+	defaultReceiptTimeout = 10 * time.Second
+	// cashChequesWorkers is the number of cashout requests of a CashCheques call which are processed concurrently
+	cashChequesWorkers = 5
+	// pollErrorLogInterval is the minimum interval in which the same receipt polling error of a chequebook is logged
+	pollErrorLogInterval = time.Minute
 )
 
 // CashoutService is the service responsible for managing cashout actions
 type CashoutService interface {
 	// CashCheque sends a cashing transaction for the last cheque of the chequebook
 	CashCheque(ctx context.Context, chequebook common.Address, recipient common.Address) (common.Hash, error)
+	// CashChequeToDefault sends a cashing transaction for the last cheque of the chequebook to the configured default recipient
+	CashChequeToDefault(ctx context.Context, chequebook common.Address) (common.Hash, error)
+	// CashChequeWithOptions sends a cashing transaction for the last cheque of the chequebook with the given transaction options
+	CashChequeWithOptions(ctx context.Context, chequebook common.Address, recipient common.Address, opts CashoutTxOptions) (common.Hash, error)
+	// CashChequeAmount sends a cashing transaction for the given amount of the last cheque of the chequebook, which must be all of its uncashed amount
+	CashChequeAmount(ctx context.Context, chequebook common.Address, recipient common.Address, amount *big.Int) (common.Hash, error)
+	// CashSpecificCheque sends a cashing transaction for the given cheque of the chequebook instead of the last one
+	CashSpecificCheque(ctx context.Context, chequebook common.Address, recipient common.Address, cheque *SignedCheque) (common.Hash, error)
+	// PrepareCashCheque returns the cashing transaction for the last cheque of the chequebook without sending it
+	PrepareCashCheque(ctx context.Context, chequebook common.Address, recipient common.Address) (*transaction.TxRequest, error)
+	// CashCheques cashes the last cheques of several chequebooks concurrently, returning the result of every request
+	CashCheques(ctx context.Context, requests []CashRequest) ([]CashResult, error)
 	// CashoutStatus gets the status of the latest cashout transaction for the chequebook
 	CashoutStatus(ctx context.Context, chequebookAddress common.Address) (*CashoutStatus, error)
+	// CashoutStatusWithConfirmations gets the status of the latest cashout transaction for the chequebook including the number of confirmations
+	CashoutStatusWithConfirmations(ctx context.Context, chequebookAddress common.Address) (*CashoutStatus, error)
+	// CashoutHistory gets the status of up to limit of the most recent cashout transactions for the chequebook, newest first
+	CashoutHistory(ctx context.Context, chequebookAddress common.Address, limit int) ([]*CashoutStatus, error)
+	// TotalCashedOut sums the payouts of the confirmed cashouts of the chequebook which did not bounce
+	TotalCashedOut(chequebook common.Address) (*big.Int, error)
+	// UncashedAmount gets the part of the last cheque of the chequebook which has not been paid out on-chain yet
+	UncashedAmount(ctx context.Context, chequebook common.Address) (*big.Int, error)
+	// ProfitabilityReport estimates the profit of cashing out every chequebook with an uncashed balance
+	ProfitabilityReport(ctx context.Context) ([]ChequebookProfit, error)
+	// SetCashoutCooldown sets the minimum time between cashouts of the same chequebook
+	SetCashoutCooldown(cooldown time.Duration)
+	// SetNotifyCashedFunc sets the function called for every confirmed cashout which did not bounce
+	SetNotifyCashedFunc(f NotifyCashedFunc)
+	// SetNotifyPendingFunc sets the function called for every sent or resumed cashout transaction
+	SetNotifyPendingFunc(f NotifyPendingFunc)
+	// Start resumes monitoring of unconfirmed cashout transactions
+	Start() error
+	io.Closer
 }
 
 type cashoutService struct {
+	lock                  sync.Mutex
+	sendLock              sync.Mutex // serializes sending cashouts so that their nonces do not collide
+	logger                logging.Logger
 	store                 storage.StateStorer
 	simpleSwapBindingFunc SimpleSwapBindingFunc
+	bindingsMu            sync.Mutex                           // protects bindings
+	bindings              map[common.Address]SimpleSwapBinding // bindings of the chequebooks by address
 	backend               transaction.Backend
 	transactionService    transaction.Service
 	chequebookABI         abi.ABI
 	chequeStore           ChequeStore
+	cashoutCooldown       time.Duration
+	externalSigner        ExternalSignerFunc
+	externalSender        common.Address
+	notifyCashedFunc      NotifyCashedFunc
+	notifyPendingFunc     NotifyPendingFunc
+	minProfit             *big.Int
+	defaultRecipient      *common.Address // recipient of CashChequeToDefault or nil if there is none
+
+	historyLimit        uint64
+	rebroadcastTimeout  time.Duration
+	rebroadcastBump     int
+	receiptPollInterval time.Duration
+	receiptTimeout      time.Duration
+	pending             map[common.Hash]pendingCashout // pending cashout transactions and their actions
+	sending             map[common.Address]int         // number of cashouts of the chequebooks which are being sent
+	monitorCtx          context.Context
+	monitorCtxCancel    context.CancelFunc
+	wg                  sync.WaitGroup
+	metrics             metrics
+}
+
+// CashoutTxOptions are the options of a cashout transaction. Nil fields are
+// determined by the transaction service.
+type CashoutTxOptions struct {
+	GasPrice *big.Int // gas price or nil if the suggested gas price should be used
+	GasLimit *uint64  // gas limit or nil if it should be estimated
+}
+
+// CashRequest is a request to cash the last cheque of a chequebook in a CashCheques call
+type CashRequest struct {
+	Chequebook common.Address
+	Recipient  common.Address
+	Amount     *big.Int // expected uncashed amount of the cheque or nil if it should not be checked
+	Options    CashoutTxOptions
+}
+
+// CashResult is the outcome of a CashRequest
+type CashResult struct {
+	Chequebook common.Address
+	TxHash     common.Hash // hash of the sent transaction if Err is nil
+	Err        error
+}
+
+// pendingCashout identifies the stored action of a pending cashout transaction
+type pendingCashout struct {
+	chequebook common.Address
+	seq        uint64 // sequence number of the action within the history of the chequebook
+}
+
+// NotifyCashedFunc is called with the result of a confirmed cashout which did not bounce
+type NotifyCashedFunc func(chequebook common.Address, result *CashChequeResult) error
+
+// NotifyPendingFunc is called with the hash of a cashout transaction once it
+// has been sent and while it is still pending
+type NotifyPendingFunc func(chequebook common.Address, txHash common.Hash) error
+
+// CashoutOption is a function that applies an option to the CashoutService.
+type CashoutOption func(*cashoutService)
+
+// WithCashoutHistoryLimit sets the number of cashout actions retained per
+// chequebook. Older actions are removed from the store.
+func WithCashoutHistoryLimit(limit uint64) CashoutOption {
+	return func(s *cashoutService) {
+		s.historyLimit = limit
+	}
+}
+
+// WithRebroadcast makes the service replace cashout transactions which were
+// not mined within the timeout with a transaction with the same nonce and a
+// gas price increased by bumpPercent percent.
+func WithRebroadcast(timeout time.Duration, bumpPercent int) CashoutOption {
+	return func(s *cashoutService) {
+		s.rebroadcastTimeout = timeout
+		s.rebroadcastBump = bumpPercent
+	}
+}
+
+// WithMinProfit makes the service refuse cashouts whose projected profit,
+// the cashed amount minus the estimated transaction cost, is below min.
+// Refused cashouts fail with an *UnprofitableError. The service cashes with
+// cashChequeBeneficiary, which pays no bounty to its caller, so the caller
+// payout is not part of the profit.
+func WithMinProfit(min *big.Int) CashoutOption {
+	return func(s *cashoutService) {
+		s.minProfit = min
+	}
+}
+
+// UnprofitableError is returned for cashouts refused because of the minimum
+// set with WithMinProfit. It matches ErrUnprofitable with errors.Is.
+type UnprofitableError struct {
+	Estimate ChequebookProfit // estimate the decision was based on
+	Min      *big.Int         // configured minimum profit
+}
+
+func (e *UnprofitableError) Error() string {
+	return fmt.Sprintf("%v: projected profit %d below minimum %d", ErrUnprofitable, e.Estimate.NetProfit, e.Min)
+}
+
+func (e *UnprofitableError) Unwrap() error {
+	return ErrUnprofitable
+}
+
+// WithReceiptPollInterval sets the interval in which the receipts of all
+// pending cashout transactions are fetched.
+func WithReceiptPollInterval(interval time.Duration) CashoutOption {
+	return func(s *cashoutService) {
+		s.receiptPollInterval = interval
+	}
+}
+
+// WithReceiptTimeout sets the time after which fetching the receipt of a
+// single pending cashout transaction is abandoned until the next poll, so
+// that an unresponsive node does not stall the receipts of the others.
+func WithReceiptTimeout(timeout time.Duration) CashoutOption {
+	return func(s *cashoutService) {
+		s.receiptTimeout = timeout
+	}
+}
+
+// ExternalSignerFunc signs a prepared cashout transaction outside of the
+// transaction service, e.g. with a key held in an HSM or by a remote signer.
+type ExternalSignerFunc func(ctx context.Context, tx *types.Transaction) (*types.Transaction, error)
+
+// WithExternalSigner makes the service sign cashout transactions with the
+// signer and broadcast them itself instead of using the transaction service.
+// The sender is the address of the signing key, which is used to determine
+// the nonce and estimate the gas.
+func WithExternalSigner(sender common.Address, signer ExternalSignerFunc) CashoutOption {
+	return func(s *cashoutService) {
+		s.externalSender = sender
+		s.externalSigner = signer
+	}
+}
+
+// WithDefaultRecipient sets the recipient of cheques cashed with
+// CashChequeToDefault, e.g. a treasury address all cashouts go to.
+func WithDefaultRecipient(recipient common.Address) CashoutOption {
+	return func(s *cashoutService) {
+		s.defaultRecipient = &recipient
+	}
 }
 
 // CashoutStatus is the action plus its result
 type CashoutStatus struct {
-	TxHash   common.Hash
-	Cheque   SignedCheque // the cheque that was used to cashout which may be different from the latest cheque
-	Result   *CashChequeResult
-	Reverted bool
+	TxHash      common.Hash
+	Cheque      SignedCheque // the cheque that was used to cashout which may be different from the latest cheque
+	Amount      *big.Int     // amount requested with CashChequeAmount or nil if none was requested
+	Outstanding *big.Int     // part of the cheque which remains uncashed after this cashout, 0 as cheques are cashed completely
+	GasCost     *big.Int     // cost of the mined transaction or nil if not mined or unknown
+	Result      *CashChequeResult
+	Reverted    bool
+	Failed      string // reason why the mined transaction has no result, e.g. a missing ChequeCashed event
+	// Confirmations is the number of blocks including and following the one
+	// in which the transaction was mined. It is only filled in by
+	// CashoutStatusWithConfirmations and is 0 while the transaction is pending.
+	Confirmations uint64
 }
 
 // CashChequeResult summarizes the result of a CashCheque or CashChequeBeneficiary call
@@ -60,80 +285,1114 @@ type CashChequeResult struct {
 	Bounced          bool           // indicates wether parts of the cheque bounced
 }
 
+// ChequebookProfit is the estimated outcome of cashing the last cheque of a chequebook
+type ChequebookProfit struct {
+	Chequebook   common.Address
+	Uncashed     *big.Int // part of the last cheque which has not been paid out yet
+	GasCost      *big.Int // estimated cost of the cashout transaction
+	CallerPayout *big.Int // payout for the caller, always 0 as cashChequeBeneficiary pays no bounty
+	NetProfit    *big.Int // Uncashed + CallerPayout - GasCost
+}
+
 // cashoutAction is the data we store for a cashout
 type cashoutAction struct {
-	TxHash common.Hash
-	Cheque SignedCheque // the cheque that was used to cashout which may be different from the latest cheque
+	TxHash      common.Hash
+	Cheque      SignedCheque      // the cheque that was used to cashout which may be different from the latest cheque
+	Timestamp   int64             // unix time at which the cashout transaction was sent
+	Amount      *big.Int          // amount requested with CashChequeAmount or nil if none was requested
+	Outstanding *big.Int          // part of the cheque which remains uncashed after this cashout
+	GasPrice    *big.Int          // gas price requested for the transaction, reused when it is resubmitted
+	GasLimit    uint64            // gas limit requested for the transaction, reused when it is resubmitted
+	Replaced    []common.Hash     // hashes of earlier transactions of this cashout which were replaced by TxHash
+	Broadcast   int64             // unix time at which TxHash was sent if it replaced an earlier transaction
+	GasCost     *big.Int          // cost of the mined transaction if known
+	Result      *CashChequeResult // the result once the transaction was confirmed
+	Reverted    bool              // whether the confirmed transaction was reverted
+	Failed      string            // reason why the confirmed transaction has no result although it was not reverted
+}
+
+// confirmed returns true if the outcome of the cashout transaction is known
+// and final
+func (a *cashoutAction) confirmed() bool {
+	return a.Result != nil || a.Reverted || a.Failed != ""
 }
 
 // NewCashoutService creates a new CashoutService
 func NewCashoutService(
+	logger logging.Logger,
 	store storage.StateStorer,
 	simpleSwapBindingFunc SimpleSwapBindingFunc,
 	backend transaction.Backend,
 	transactionService transaction.Service,
 	chequeStore ChequeStore,
+	opts ...CashoutOption,
 ) (CashoutService, error) {
 	chequebookABI, err := abi.JSON(strings.NewReader(simpleswapfactory.ERC20SimpleSwapABI))
 	if err != nil {
 		return nil, err
 	}
 
-	return &cashoutService{
+	monitorCtx, monitorCtxCancel := context.WithCancel(context.Background())
+
+	s := &cashoutService{
+		logger:                logger,
 		store:                 store,
 		simpleSwapBindingFunc: simpleSwapBindingFunc,
+		bindings:              make(map[common.Address]SimpleSwapBinding),
 		backend:               backend,
 		transactionService:    transactionService,
 		chequebookABI:         chequebookABI,
 		chequeStore:           chequeStore,
-	}, nil
+		historyLimit:          defaultCashoutHistoryLimit,
+		receiptPollInterval:   defaultReceiptPollInterval,
+		receiptTimeout:        defaultReceiptTimeout,
+		pending:               make(map[common.Hash]pendingCashout),
+		sending:               make(map[common.Address]int),
+		monitorCtx:            monitorCtx,
+		monitorCtxCancel:      monitorCtxCancel,
+		metrics:               newMetrics(),
+	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	return s, nil
+}
+
+// cashoutActionKey computes the store key for the cashout action with the given sequence number for the chequebook
+func cashoutActionKey(chequebook common.Address, seq uint64) string {
+	return fmt.Sprintf("%s%x_%d", cashoutActionPrefix, chequebook, seq)
+}
+
+// legacyCashoutActionKey computes the store key under which only the last
+// cashout action for the chequebook was stored before the history was kept
+func legacyCashoutActionKey(chequebook common.Address) string {
+	return fmt.Sprintf("%s%x", cashoutActionPrefix, chequebook)
+}
+
+// cashoutSequenceKey computes the store key for the sequence number of the last cashout action for the chequebook
+func cashoutSequenceKey(chequebook common.Address) string {
+	return fmt.Sprintf("%s%x", cashoutSequencePrefix, chequebook)
+}
+
+// parseCashoutActionKey parses the chequebook and sequence number from a
+// cashout action key. The sequence number of legacy keys is 0.
+func parseCashoutActionKey(key []byte) (chequebook common.Address, seq uint64, err error) {
+	k := strings.TrimPrefix(string(key), cashoutActionPrefix)
+
+	split := strings.Split(k, "_")
+	switch len(split) {
+	case 1:
+		return common.HexToAddress(split[0]), 0, nil
+	case 2:
+		seq, err := strconv.ParseUint(split[1], 10, 64)
+		if err != nil {
+			return common.Address{}, 0, err
+		}
+		return common.HexToAddress(split[0]), seq, nil
+	default:
+		return common.Address{}, 0, errors.New("invalid cashout key")
+	}
+}
+
+// lastSequence returns the sequence number of the last cashout action for
+// the chequebook or 0 if there was none.
+func (s *cashoutService) lastSequence(chequebook common.Address) (uint64, error) {
+	var seq uint64
+	err := s.store.Get(cashoutSequenceKey(chequebook), &seq)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return seq, nil
+}
+
+// lastAction returns the last cashout action for the chequebook or
+// storage.ErrNotFound if there was none.
+func (s *cashoutService) lastAction(chequebook common.Address) (*cashoutAction, error) {
+	seq, err := s.lastSequence(chequebook)
+	if err != nil {
+		return nil, err
+	}
+	if seq == 0 {
+		return nil, storage.ErrNotFound
+	}
+
+	var action *cashoutAction
+	err = s.store.Get(cashoutActionKey(chequebook, seq), &action)
+	if err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+// Start resumes monitoring of all unconfirmed cashout transactions and
+// starts the receipt poller. Actions stored under legacy keys are moved
+// into the history. Resumed transactions are only added to the set checked by
+// the single poller, so no goroutine is started per pending cashout no matter
+// how many there are. The pending notification function is called for every
+// resumed cashout.
+func (s *cashoutService) Start() error {
+	pending := make(map[common.Hash]pendingCashout)
+	legacy := make(map[common.Address]*cashoutAction)
+	// the current transactions of the resumed cashouts, which are notified
+	resumed := make(map[common.Hash]common.Address)
+	err := s.store.Iterate(cashoutActionPrefix, func(key, val []byte) (stop bool, err error) {
+		chequebook, seq, err := parseCashoutActionKey(key)
+		if err != nil {
+			return true, fmt.Errorf("parse cashout key: %s: %w", string(key), err)
+		}
+
+		var action cashoutAction
+		if err := json.Unmarshal(val, &action); err != nil {
+			return true, err
+		}
+
+		if seq == 0 {
+			legacy[chequebook] = &action
+			return false, nil
+		}
+
+		if !action.confirmed() {
+			p := pendingCashout{chequebook: chequebook, seq: seq}
+			pending[action.TxHash] = p
+			resumed[action.TxHash] = chequebook
+			for _, txHash := range action.Replaced {
+				pending[txHash] = p
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.resume(pending, legacy, resumed)
+	if err != nil {
+		return err
+	}
+
+	for txHash, chequebook := range resumed {
+		s.notifyPending(chequebook, txHash)
+	}
+
+	return nil
+}
+
+// resume migrates the legacy actions, adds the pending transactions to the
+// ones checked by the receipt poller and starts it.
+func (s *cashoutService) resume(pending map[common.Hash]pendingCashout, legacy map[common.Address]*cashoutAction, resumed map[common.Hash]common.Address) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for chequebook, action := range legacy {
+		seq, err := s.putAction(chequebook, action)
+		if err != nil {
+			return fmt.Errorf("migrate cashout for chequebook %x: %w", chequebook, err)
+		}
+		if err := s.store.Delete(legacyCashoutActionKey(chequebook)); err != nil {
+			return err
+		}
+		if !action.confirmed() {
+			pending[action.TxHash] = pendingCashout{chequebook: chequebook, seq: seq}
+			resumed[action.TxHash] = chequebook
+		}
+	}
+
+	for txHash, p := range pending {
+		s.pending[txHash] = p
+	}
+
+	s.wg.Add(1)
+	go s.pollReceipts()
+
+	return nil
+}
+
+// Close stops the receipt poller.
+func (s *cashoutService) Close() error {
+	s.monitorCtxCancel()
+	s.wg.Wait()
+	return nil
+}
+
+// pollReceipts periodically checks the receipts of all pending cashout
+// transactions. A single poller is used for all chequebooks instead of one
+// goroutine per transaction.
+func (s *cashoutService) pollReceipts() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.receiptPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.monitorCtx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s.checkPendingReceipts(s.monitorCtx)
+	}
+}
+
+// checkPendingReceipts fetches the receipts for all pending cashout
+// transactions in one pass and processes the ones which have been mined. The
+// backend has no batch request, so every transaction is handled with its own
+// receipt timeout.
+func (s *cashoutService) checkPendingReceipts(ctx context.Context) {
+	s.lock.Lock()
+	pending := make(map[common.Hash]pendingCashout, len(s.pending))
+	for txHash, p := range s.pending {
+		pending[txHash] = p
+	}
+	s.lock.Unlock()
+
+	for txHash, p := range pending {
+		if ctx.Err() != nil {
+			return
+		}
+
+		s.checkPendingReceipt(ctx, p, txHash)
+	}
+}
+
+// checkPendingReceipt fetches and processes the receipt of a single pending
+// cashout transaction within the receipt timeout.
+func (s *cashoutService) checkPendingReceipt(ctx context.Context, p pendingCashout, txHash common.Hash) {
+	ctx, cancel := context.WithTimeout(ctx, s.receiptTimeout)
+	defer cancel()
+
+	receipt, err := s.backend.TransactionReceipt(ctx, txHash)
+	if err != nil || receipt == nil {
+		// some node implementations return an error if the transaction is not yet mined
+		if s.rebroadcastTimeout > 0 {
+			if err := s.rebroadcastIfStuck(ctx, p, txHash); err != nil {
+				s.logger.ErrorfThrottled(fmt.Sprintf("cashout rebroadcast %x", p.chequebook), pollErrorLogInterval, "could not rebroadcast cashout for chequebook %x: %v", p.chequebook, err)
+			}
+		}
+		return
+	}
+
+	err = s.processCashChequeBeneficiaryReceipt(ctx, p, txHash, receipt)
+	if err != nil {
+		s.logger.ErrorfThrottled(fmt.Sprintf("cashout receipt %x", p.chequebook), pollErrorLogInterval, "could not process cashout receipt for chequebook %x: %v", p.chequebook, err)
+		// the outcome was not stored, the receipt is processed again
+		return
+	}
+
+	// once one transaction of the cashout is mined, the ones it
+	// replaced or which replaced it can not be mined anymore
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for h, o := range s.pending {
+		if o == p {
+			delete(s.pending, h)
+		}
+	}
+}
+
+// rebroadcastIfStuck replaces the pending cashout transaction with one with
+// the same nonce and a higher gas price if it was not mined within the
+// rebroadcast timeout. Both transactions remain monitored as either of them
+// might get mined. The lock is not held while the backend is queried.
+func (s *cashoutService) rebroadcastIfStuck(ctx context.Context, p pendingCashout, txHash common.Hash) error {
+	s.lock.Lock()
+	action, err := s.replaceableAction(p, txHash)
+	s.lock.Unlock()
+	if err != nil || action == nil {
+		return err
+	}
+
+	broadcast := action.Broadcast
+	if broadcast == 0 {
+		broadcast = action.Timestamp
+	}
+	if time.Since(time.Unix(broadcast, 0)) < s.rebroadcastTimeout {
+		return nil
+	}
+
+	tx, isPending, err := s.backend.TransactionByHash(ctx, txHash)
+	if err != nil {
+		return err
+	}
+	if !isPending {
+		return nil
+	}
+
+	gasPrice := big.NewInt(0).Mul(tx.GasPrice(), big.NewInt(int64(100+s.rebroadcastBump)))
+	gasPrice.Div(gasPrice, big.NewInt(100))
+	if gasPrice.Cmp(tx.GasPrice()) <= 0 {
+		gasPrice.Add(tx.GasPrice(), big.NewInt(1))
+	}
+
+	nonce := tx.Nonce()
+	request := &transaction.TxRequest{
+		To:       p.chequebook,
+		Data:     tx.Data(),
+		GasPrice: gasPrice,
+		GasLimit: tx.Gas(),
+		Value:    big.NewInt(0),
+		Nonce:    &nonce,
+	}
+
+	newTxHash, err := s.sendTransaction(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	s.logger.Debugf("replaced stuck cashout transaction %x for chequebook %x with %x", txHash, p.chequebook, newTxHash)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// the action may have changed while the transaction was sent, the
+	// replacement has the same nonce and needs no monitoring if the replaced
+	// transaction is no longer the pending one
+	action, err = s.replaceableAction(p, txHash)
+	if err != nil || action == nil {
+		return err
+	}
+
+	action.Replaced = append(action.Replaced, txHash)
+	action.TxHash = newTxHash
+	action.GasPrice = gasPrice
+	action.Broadcast = time.Now().Unix()
+
+	err = s.store.Put(cashoutActionKey(p.chequebook, p.seq), action)
+	if err != nil {
+		return err
+	}
+
+	s.pending[newTxHash] = p
+
+	return nil
+}
+
+// replaceableAction returns the action of the pending cashout if txHash is
+// its latest transaction and it is not confirmed yet, and nil otherwise. It
+// must be called with the lock held.
+func (s *cashoutService) replaceableAction(p pendingCashout, txHash common.Hash) (*cashoutAction, error) {
+	var action *cashoutAction
+	err := s.store.Get(cashoutActionKey(p.chequebook, p.seq), &action)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// only the latest transaction of the cashout is replaced
+	if action.TxHash != txHash || action.confirmed() {
+		return nil, nil
+	}
+
+	return action, nil
+}
+
+// processCashChequeBeneficiaryReceipt stores the outcome of a mined cashout
+// transaction and notifies about successful cashouts. Errors of the
+// notification are only logged as the outcome is already stored.
+func (s *cashoutService) processCashChequeBeneficiaryReceipt(ctx context.Context, p pendingCashout, txHash common.Hash, receipt *types.Receipt) error {
+	var gasCost *big.Int
+	tx, _, err := s.backend.TransactionByHash(ctx, txHash)
+	if err != nil {
+		s.logger.Debugf("could not get cashout transaction %x to compute its cost: %v", txHash, err)
+	} else {
+		gasCost = transactionCost(tx, receipt)
+	}
+
+	action, err := s.storeCashoutOutcome(p, txHash, receipt, gasCost)
+	if err != nil {
+		return err
+	}
+	if action == nil {
+		return nil
+	}
+
+	s.recordOutcome(action)
+
+	if action.Failed != "" {
+		s.logger.Errorf("cashout transaction %x for chequebook %x has no result: %s", action.TxHash, p.chequebook, action.Failed)
+		return nil
+	}
+
+	result := action.Result
+	if action.Reverted || result.Bounced {
+		return nil
+	}
+
+	s.lock.Lock()
+	notifyCashedFunc := s.notifyCashedFunc
+	s.lock.Unlock()
+
+	if notifyCashedFunc != nil {
+		if err := notifyCashedFunc(p.chequebook, result); err != nil {
+			s.logger.Errorf("cashout notification for chequebook %x: %v", p.chequebook, err)
+		}
+	}
+
+	return nil
+}
+
+// storeCashoutOutcome stores the outcome of a mined cashout transaction. It
+// returns the updated action if the transaction is still the transaction of
+// its action and the outcome was not stored before.
+func (s *cashoutService) storeCashoutOutcome(p pendingCashout, txHash common.Hash, receipt *types.Receipt, gasCost *big.Int) (*cashoutAction, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	chequebook := p.chequebook
+
+	var action *cashoutAction
+	err := s.store.Get(cashoutActionKey(chequebook, p.seq), &action)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			// the action was dropped from the history
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if action.confirmed() {
+		return nil, nil
+	}
+
+	// the receipt belongs to a transaction which replaced or was replaced by
+	// the latest one, the mined one becomes the transaction of the action
+	if action.TxHash != txHash {
+		replaced := false
+		for _, h := range action.Replaced {
+			if h == txHash {
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			return nil, nil
+		}
+		action.TxHash = txHash
+	}
+
+	action.GasCost = gasCost
+	if receipt.Status == types.ReceiptStatusFailed {
+		action.Reverted = true
+	} else {
+		result, err := s.parseCashChequeBeneficiaryReceipt(chequebook, receipt)
+		switch {
+		case err == nil:
+			action.Result = result
+		case errors.Is(err, ErrNoChequeCashedEvent):
+			// the receipt will not change, the failure is stored so that
+			// the cashout is not resumed or polled again
+			action.Failed = err.Error()
+		default:
+			return nil, err
+		}
+	}
+
+	err = s.store.Put(cashoutActionKey(chequebook, p.seq), action)
+	if err != nil {
+		return nil, err
+	}
+
+	return action, nil
 }
 
-// cashoutActionKey computes the store key for the last cashout action for the chequebook
-func cashoutActionKey(chequebook common.Address) string {
-	return fmt.Sprintf("cashout_%x", chequebook)
+// SetCashoutCooldown sets the minimum time between cashouts of the same chequebook
+func (s *cashoutService) SetCashoutCooldown(cooldown time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.cashoutCooldown = cooldown
+}
+
+// SetNotifyCashedFunc sets the function called for every confirmed cashout which did not bounce
+func (s *cashoutService) SetNotifyCashedFunc(f NotifyCashedFunc) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.notifyCashedFunc = f
+}
+
+// SetNotifyPendingFunc sets the function called for every cashout transaction
+// once it has been sent and stored, and for every pending cashout which is
+// resumed by Start
+func (s *cashoutService) SetNotifyPendingFunc(f NotifyPendingFunc) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.notifyPendingFunc = f
+}
+
+// notifyPending calls the pending notification function if one is set. It
+// must be called without the lock held.
+func (s *cashoutService) notifyPending(chequebook common.Address, txHash common.Hash) {
+	s.lock.Lock()
+	notifyPendingFunc := s.notifyPendingFunc
+	s.lock.Unlock()
+
+	if notifyPendingFunc == nil {
+		return
+	}
+	if err := notifyPendingFunc(chequebook, txHash); err != nil {
+		s.logger.Errorf("pending cashout notification for chequebook %x: %v", chequebook, err)
+	}
+}
+
+// checkCooldown returns ErrCashoutCooldown if the last cashout which was not
+// reverted and did not fail happened within the cooldown period. It must be
+// called with the lock held.
+func (s *cashoutService) checkCooldown(chequebook common.Address) error {
+	if s.cashoutCooldown == 0 {
+		return nil
+	}
+
+	action, err := s.lastAction(chequebook)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if action.Reverted || action.Failed != "" {
+		return nil
+	}
+
+	if time.Since(time.Unix(action.Timestamp, 0)) < s.cashoutCooldown {
+		return ErrCashoutCooldown
+	}
+
+	return nil
 }
 
 // CashCheque sends a cashout transaction for the last cheque of the chequebook
 func (s *cashoutService) CashCheque(ctx context.Context, chequebook common.Address, recipient common.Address) (common.Hash, error) {
-	cheque, err := s.chequeStore.LastCheque(chequebook)
+	return s.cashCheque(ctx, chequebook, recipient, nil, nil, CashoutTxOptions{})
+}
+
+// CashChequeToDefault sends a cashout transaction for the last cheque of the
+// chequebook to the recipient set with WithDefaultRecipient. It returns
+// ErrNoDefaultRecipient if none is set.
+func (s *cashoutService) CashChequeToDefault(ctx context.Context, chequebook common.Address) (common.Hash, error) {
+	if s.defaultRecipient == nil {
+		return common.Hash{}, ErrNoDefaultRecipient
+	}
+	return s.cashCheque(ctx, chequebook, *s.defaultRecipient, nil, nil, CashoutTxOptions{})
+}
+
+// CashChequeWithOptions sends a cashout transaction for the last cheque of
+// the chequebook with the given gas price and gas limit
+func (s *cashoutService) CashChequeWithOptions(ctx context.Context, chequebook common.Address, recipient common.Address, opts CashoutTxOptions) (common.Hash, error) {
+	return s.cashCheque(ctx, chequebook, recipient, nil, nil, opts)
+}
+
+// CashChequeAmount sends a cashout transaction for the given amount of the
+// last cheque of the chequebook. The chequebook only pays out cumulative
+// payouts the issuer signed, so a cheque can not be cashed in part. An amount
+// above the uncashed amount fails with ErrInvalidCashoutAmount, one below it
+// with ErrPartialCashoutUnsigned, both before any transaction is sent. An
+// earlier cheque with a lower cumulative payout can be cashed with
+// CashSpecificCheque instead.
+func (s *cashoutService) CashChequeAmount(ctx context.Context, chequebook common.Address, recipient common.Address, amount *big.Int) (common.Hash, error) {
+	if amount == nil || amount.Sign() <= 0 {
+		return common.Hash{}, ErrInvalidCashoutAmount
+	}
+	return s.cashCheque(ctx, chequebook, recipient, nil, amount, CashoutTxOptions{})
+}
+
+// CashSpecificCheque sends a cashout transaction for the given cheque instead
+// of the last cheque of the chequebook, e.g. if the last cheque can not be
+// cashed. The cheque must be issued by the chequebook and must not be paid
+// out completely yet. It is recorded in the cashout action, so that the
+// status reflects the cheque which was actually cashed.
+func (s *cashoutService) CashSpecificCheque(ctx context.Context, chequebook common.Address, recipient common.Address, cheque *SignedCheque) (common.Hash, error) {
+	if cheque == nil || cheque.CumulativePayout == nil {
+		return common.Hash{}, ErrChequeInvalid
+	}
+	if cheque.Chequebook != chequebook {
+		return common.Hash{}, fmt.Errorf("%w: cheque of chequebook %x, want %x", ErrChequeInvalid, cheque.Chequebook, chequebook)
+	}
+	return s.cashCheque(ctx, chequebook, recipient, cheque, nil, CashoutTxOptions{})
+}
+
+// CashCheques cashes the last cheques of the chequebooks in the requests.
+// Up to cashChequesWorkers requests are prepared concurrently, while the
+// transactions are still sent one at a time so that their nonces do not
+// collide. The results are in the order of the requests. Once the context is
+// cancelled no further transactions are sent and the remaining requests fail
+// with the context error, which is also returned.
+func (s *cashoutService) CashCheques(ctx context.Context, requests []CashRequest) ([]CashResult, error) {
+	results := make([]CashResult, len(requests))
+
+	sem := make(chan struct{}, cashChequesWorkers)
+	var wg sync.WaitGroup
+
+	for i, r := range requests {
+		results[i].Chequebook = r.Chequebook
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, r CashRequest) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if err := ctx.Err(); err != nil {
+				results[i].Err = err
+				return
+			}
+
+			if r.Amount != nil && r.Amount.Sign() <= 0 {
+				results[i].Err = ErrInvalidCashoutAmount
+				return
+			}
+
+			results[i].TxHash, results[i].Err = s.cashCheque(ctx, r.Chequebook, r.Recipient, nil, r.Amount, r.Options)
+		}(i, r)
+	}
+
+	wg.Wait()
+
+	return results, ctx.Err()
+}
+
+// PrepareCashCheque validates the last cheque of the chequebook and packs the
+// cashout call exactly as CashCheque does, but returns the transaction request
+// instead of sending it, e.g. so that it can be signed externally. No cashout
+// action is stored, so the status of the cashout is not tracked.
+func (s *cashoutService) PrepareCashCheque(ctx context.Context, chequebook common.Address, recipient common.Address) (*transaction.TxRequest, error) {
+	request, _, err := s.prepareCashout(ctx, chequebook, recipient, nil, nil, CashoutTxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// cashCheque cashes the given cheque or the last cheque of the chequebook if
+// it is nil. The whole cheque is cashed if amount is nil and only the given
+// amount otherwise.
+func (s *cashoutService) cashCheque(ctx context.Context, chequebook common.Address, recipient common.Address, cheque *SignedCheque, amount *big.Int, opts CashoutTxOptions) (common.Hash, error) {
+	request, action, err := s.prepareCashout(ctx, chequebook, recipient, cheque, amount, opts)
 	if err != nil {
 		return common.Hash{}, err
 	}
 
-	callData, err := s.chequebookABI.Pack("cashChequeBeneficiary", recipient, cheque.CumulativePayout, cheque.Signature)
+	txHash, err := s.sendCashout(ctx, chequebook, request, action)
 	if err != nil {
 		return common.Hash{}, err
 	}
 
+	s.notifyPending(chequebook, txHash)
+
+	return txHash, nil
+}
+
+// prepareCashout validates the cashout and returns the transaction request
+// together with the action which is stored once the request was sent.
+func (s *cashoutService) prepareCashout(ctx context.Context, chequebook common.Address, recipient common.Address, cheque *SignedCheque, amount *big.Int, opts CashoutTxOptions) (*transaction.TxRequest, *cashoutAction, error) {
+	// fail early without querying the chain
+	s.lock.Lock()
+	err := s.checkCooldown(chequebook)
+	s.lock.Unlock()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cheque == nil {
+		cheque, err = s.chequeStore.LastCheque(chequebook)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	err = s.checkChequebook(ctx, chequebook)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	uncashed, err := s.uncashedAmount(ctx, cheque)
+	if err != nil {
+		return nil, nil, err
+	}
+	// a transaction would only revert
+	if uncashed.Sign() <= 0 {
+		return nil, nil, ErrNothingToCash
+	}
+
+	if amount != nil {
+		if amount.Cmp(uncashed) > 0 {
+			return nil, nil, fmt.Errorf("%w: requested %d, uncashed %d", ErrInvalidCashoutAmount, amount, uncashed)
+		}
+		// the signature only covers the cumulative payout of the cheque, a
+		// lower one would make the transaction revert
+		if amount.Cmp(uncashed) < 0 {
+			return nil, nil, fmt.Errorf("%w: requested %d, uncashed %d", ErrPartialCashoutUnsigned, amount, uncashed)
+		}
+	}
+
+	callData, err := s.chequebookABI.Pack("cashChequeBeneficiary", recipient, cheque.CumulativePayout, cheque.Signature)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var gasLimit uint64
+	if opts.GasLimit != nil {
+		gasLimit = *opts.GasLimit
+	}
+
+	if s.minProfit != nil {
+		cashed := uncashed
+		if amount != nil {
+			cashed = amount
+		}
+		err = s.checkProfit(ctx, cheque, cashed, callData, opts.GasPrice)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
 	request := &transaction.TxRequest{
 		To:       chequebook,
 		Data:     callData,
-		GasPrice: nil,
-		GasLimit: 0,
+		GasPrice: opts.GasPrice,
+		GasLimit: gasLimit,
 		Value:    big.NewInt(0),
 	}
 
-	txHash, err := s.transactionService.Send(ctx, request)
+	return request, &cashoutAction{
+		Cheque:      *cheque,
+		Amount:      amount,
+		Outstanding: big.NewInt(0),
+		GasPrice:    opts.GasPrice,
+		GasLimit:    gasLimit,
+	}, nil
+}
+
+// sendCashout sends the cashout transaction unless another cashout of the
+// chequebook was sent within the cooldown period, and stores the action with
+// the hash and time of the transaction. The lock is not held while the
+// transaction is sent, instead the chequebook is reserved so that no other
+// cashout of it passes the cooldown check in the meantime.
+func (s *cashoutService) sendCashout(ctx context.Context, chequebook common.Address, request *transaction.TxRequest, action *cashoutAction) (common.Hash, error) {
+	err := s.reserveCashout(chequebook)
 	if err != nil {
 		return common.Hash{}, err
 	}
 
-	err = s.store.Put(cashoutActionKey(chequebook), &cashoutAction{
-		TxHash: txHash,
-		Cheque: *cheque,
-	})
+	var txHash common.Hash
+	s.sendLock.Lock()
+	if err = ctx.Err(); err == nil {
+		txHash, err = s.sendTransaction(ctx, request)
+	}
+	s.sendLock.Unlock()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.sending[chequebook]--
+	if s.sending[chequebook] == 0 {
+		delete(s.sending, chequebook)
+	}
+
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	action.TxHash = txHash
+	action.Timestamp = time.Now().Unix()
+
+	seq, err := s.putAction(chequebook, action)
 	if err != nil {
 		return common.Hash{}, err
 	}
 
+	s.pending[txHash] = pendingCashout{chequebook: chequebook, seq: seq}
+
 	return txHash, nil
 }
 
+// reserveCashout checks the cooldown of the chequebook and reserves it for a
+// cashout until sendCashout has stored the action. With a cooldown, a
+// chequebook can not be reserved while another cashout of it is being sent.
+func (s *cashoutService) reserveCashout(chequebook common.Address) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// another cashout of the chequebook may have been sent in the meantime
+	if s.cashoutCooldown > 0 && s.sending[chequebook] > 0 {
+		return ErrCashoutCooldown
+	}
+	err := s.checkCooldown(chequebook)
+	if err != nil {
+		return err
+	}
+
+	s.sending[chequebook]++
+
+	return nil
+}
+
+// putAction appends the action to the history of the chequebook and removes
+// the action which no longer fits into the history. It returns the sequence
+// number of the new action and must be called with the lock held.
+func (s *cashoutService) putAction(chequebook common.Address, action *cashoutAction) (uint64, error) {
+	last, err := s.lastSequence(chequebook)
+	if err != nil {
+		return 0, err
+	}
+	seq := last + 1
+
+	err = s.store.Put(cashoutActionKey(chequebook, seq), action)
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.store.Put(cashoutSequenceKey(chequebook), seq)
+	if err != nil {
+		return 0, err
+	}
+
+	if s.historyLimit > 0 && seq > s.historyLimit {
+		dropped := seq - s.historyLimit
+		err = s.store.Delete(cashoutActionKey(chequebook, dropped))
+		if err != nil {
+			return 0, err
+		}
+		for txHash, p := range s.pending {
+			if p.chequebook == chequebook && p.seq == dropped {
+				delete(s.pending, txHash)
+			}
+		}
+	}
+
+	return seq, nil
+}
+
+// sendTransaction sends the request through the transaction service or, if
+// an external signer is configured, signs and broadcasts it directly.
+func (s *cashoutService) sendTransaction(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+	if s.externalSigner == nil {
+		return s.transactionService.Send(ctx, request)
+	}
+
+	tx, err := transaction.PrepareTransaction(ctx, request, s.externalSender, s.backend)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	signedTx, err := s.externalSigner(ctx, tx)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("external signer: %w", err)
+	}
+
+	err = s.backend.SendTransaction(ctx, signedTx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	return signedTx.Hash(), nil
+}
+
+// binding returns the binding of the chequebook, creating it on first use.
+// Bindings do not change for an address, so they are kept for the lifetime of
+// the service.
+func (s *cashoutService) binding(chequebook common.Address) (SimpleSwapBinding, error) {
+	s.bindingsMu.Lock()
+	defer s.bindingsMu.Unlock()
+
+	if binding, ok := s.bindings[chequebook]; ok {
+		return binding, nil
+	}
+
+	binding, err := s.simpleSwapBindingFunc(chequebook, s.backend)
+	if err != nil {
+		return nil, err
+	}
+	s.bindings[chequebook] = binding
+
+	return binding, nil
+}
+
+// checkChequebook reads the issuer of the chequebook to make sure that the
+// address is a chequebook contract before gas is spent on a transaction which
+// would revert. Other failures of the call are returned as they are, as they
+// may be caused by the connection to the chain.
+func (s *cashoutService) checkChequebook(ctx context.Context, chequebook common.Address) error {
+	binding, err := s.binding(chequebook)
+	if err != nil {
+		return err
+	}
+
+	issuer, err := binding.Issuer(&bind.CallOpts{
+		Context: ctx,
+	})
+	if err != nil {
+		if errors.Is(err, bind.ErrNoCode) {
+			return fmt.Errorf("%w: no contract code at %x", ErrNotAChequebook, chequebook)
+		}
+		return fmt.Errorf("check chequebook %x: %w", chequebook, err)
+	}
+	if issuer == (common.Address{}) {
+		return fmt.Errorf("%w: no issuer of %x", ErrNotAChequebook, chequebook)
+	}
+
+	return nil
+}
+
+// UncashedAmount gets the part of the last cheque of the chequebook which has
+// not been paid out on-chain yet. It returns an error wrapping ErrNoCheque if
+// no cheque was received from the chequebook.
+func (s *cashoutService) UncashedAmount(ctx context.Context, chequebook common.Address) (*big.Int, error) {
+	cheque, err := s.chequeStore.LastCheque(chequebook)
+	if err != nil {
+		return nil, fmt.Errorf("uncashed amount of chequebook %x: %w", chequebook, err)
+	}
+
+	return s.uncashedAmount(ctx, cheque)
+}
+
+// uncashedAmount computes the part of the cheque which has not been paid out on-chain yet
+func (s *cashoutService) uncashedAmount(ctx context.Context, cheque *SignedCheque) (*big.Int, error) {
+	binding, err := s.binding(cheque.Chequebook)
+	if err != nil {
+		return nil, err
+	}
+
+	paidOut, err := binding.PaidOut(&bind.CallOpts{
+		Context: ctx,
+	}, cheque.Beneficiary)
+	if err != nil {
+		return nil, err
+	}
+
+	return big.NewInt(0).Sub(cheque.CumulativePayout, paidOut), nil
+}
+
+// checkProfit estimates the profit of cashing the amount with the call data
+// and returns an *UnprofitableError if it is below the configured minimum.
+func (s *cashoutService) checkProfit(ctx context.Context, cheque *SignedCheque, cashed *big.Int, callData []byte, gasPrice *big.Int) error {
+	from := cheque.Beneficiary
+	if s.externalSigner != nil {
+		from = s.externalSender
+	}
+
+	gasCost, err := s.estimateCashoutCost(ctx, cheque.Chequebook, from, callData, gasPrice)
+	if err != nil {
+		return fmt.Errorf("estimate cashout cost: %w", err)
+	}
+
+	// cashChequeBeneficiary does not pay out a bounty to the caller
+	callerPayout := big.NewInt(0)
+
+	netProfit := big.NewInt(0).Add(cashed, callerPayout)
+	netProfit.Sub(netProfit, gasCost)
+
+	if netProfit.Cmp(s.minProfit) < 0 {
+		return &UnprofitableError{
+			Estimate: ChequebookProfit{
+				Chequebook:   cheque.Chequebook,
+				Uncashed:     cashed,
+				GasCost:      gasCost,
+				CallerPayout: callerPayout,
+				NetProfit:    netProfit,
+			},
+			Min: s.minProfit,
+		}
+	}
+
+	return nil
+}
+
+// estimateCashoutCost estimates the cost of sending the call data to the
+// chequebook from the given address. If gasPrice is nil the suggested gas
+// price is used.
+func (s *cashoutService) estimateCashoutCost(ctx context.Context, chequebook common.Address, from common.Address, callData []byte, gasPrice *big.Int) (*big.Int, error) {
+	gasLimit, err := s.backend.EstimateGas(ctx, ethereum.CallMsg{
+		From: from,
+		To:   &chequebook,
+		Data: callData,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if gasPrice == nil {
+		gasPrice, err = s.backend.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return big.NewInt(0).Mul(gasPrice, big.NewInt(int64(gasLimit))), nil
+}
+
+// ProfitabilityReport estimates the profit of cashing out every chequebook with an uncashed balance.
+// The report is sorted by descending net profit. No transactions are sent.
+func (s *cashoutService) ProfitabilityReport(ctx context.Context) ([]ChequebookProfit, error) {
+	cheques, err := s.chequeStore.LastCheques()
+	if err != nil {
+		return nil, err
+	}
+
+	var report []ChequebookProfit
+	for chequebook, cheque := range cheques {
+		uncashed, err := s.uncashedAmount(ctx, cheque)
+		if err != nil {
+			return nil, fmt.Errorf("uncashed amount for chequebook %x: %w", chequebook, err)
+		}
+
+		if uncashed.Cmp(big.NewInt(0)) <= 0 {
+			continue
+		}
+
+		// the beneficiary cashes to itself, so it is also the recipient and the caller
+		callData, err := s.chequebookABI.Pack("cashChequeBeneficiary", cheque.Beneficiary, cheque.CumulativePayout, cheque.Signature)
+		if err != nil {
+			return nil, err
+		}
+
+		gasCost, err := s.estimateCashoutCost(ctx, chequebook, cheque.Beneficiary, callData, nil)
+		if err != nil {
+			return nil, fmt.Errorf("estimate cashout cost for chequebook %x: %w", chequebook, err)
+		}
+
+		// cashChequeBeneficiary does not pay out a bounty to the caller
+		callerPayout := big.NewInt(0)
+
+		netProfit := big.NewInt(0).Add(uncashed, callerPayout)
+		netProfit.Sub(netProfit, gasCost)
+
+		report = append(report, ChequebookProfit{
+			Chequebook:   chequebook,
+			Uncashed:     uncashed,
+			GasCost:      gasCost,
+			CallerPayout: callerPayout,
+			NetProfit:    netProfit,
+		})
+	}
+
+	sort.Slice(report, func(i, j int) bool {
+		return report[i].NetProfit.Cmp(report[j].NetProfit) > 0
+	})
+
+	return report, nil
+}
+
 // CashoutStatus gets the status of the latest cashout transaction for the chequebook
 func (s *cashoutService) CashoutStatus(ctx context.Context, chequebookAddress common.Address) (*CashoutStatus, error) {
-	var action *cashoutAction
-	err := s.store.Get(cashoutActionKey(chequebookAddress), &action)
+	action, err := s.lastAction(chequebookAddress)
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
 			return nil, ErrNoCashout
@@ -141,17 +1400,132 @@ func (s *cashoutService) CashoutStatus(ctx context.Context, chequebookAddress co
 		return nil, err
 	}
 
-	_, pending, err := s.backend.TransactionByHash(ctx, action.TxHash)
+	return s.actionStatus(ctx, chequebookAddress, action)
+}
+
+// CashoutStatusWithConfirmations gets the status of the latest cashout
+// transaction for the chequebook like CashoutStatus and queries the chain for
+// the number of blocks confirming the transaction once it was mined.
+func (s *cashoutService) CashoutStatusWithConfirmations(ctx context.Context, chequebookAddress common.Address) (*CashoutStatus, error) {
+	status, err := s.CashoutStatus(ctx, chequebookAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	if status.Result == nil && !status.Reverted && status.Failed == "" {
+		return status, nil
+	}
+
+	receipt, err := s.backend.TransactionReceipt(ctx, status.TxHash)
+	if err != nil {
+		return nil, err
+	}
+
+	blockNumber, err := s.backend.BlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if receipt.BlockNumber != nil && receipt.BlockNumber.IsUint64() && receipt.BlockNumber.Uint64() <= blockNumber {
+		status.Confirmations = blockNumber - receipt.BlockNumber.Uint64() + 1
+	}
+
+	return status, nil
+}
+
+// CashoutHistory gets the status of up to limit of the most recent cashout
+// transactions for the chequebook, newest first. A limit of 0 returns the
+// whole retained history.
+func (s *cashoutService) CashoutHistory(ctx context.Context, chequebookAddress common.Address, limit int) ([]*CashoutStatus, error) {
+	last, err := s.lastSequence(chequebookAddress)
+	if err != nil {
+		return nil, err
+	}
+	if last == 0 {
+		return nil, ErrNoCashout
+	}
+
+	var history []*CashoutStatus
+	for seq := last; seq > 0 && (limit <= 0 || len(history) < limit); seq-- {
+		var action *cashoutAction
+		err := s.store.Get(cashoutActionKey(chequebookAddress, seq), &action)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				// older actions were dropped from the history
+				break
+			}
+			return nil, err
+		}
+
+		status, err := s.actionStatus(ctx, chequebookAddress, action)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, status)
+	}
+
+	return history, nil
+}
+
+// TotalCashedOut sums the total payouts of the confirmed cashouts of the
+// chequebook which neither bounced nor were reverted. It reads the cashout
+// actions this node stored, not the chain, so only cashouts within the
+// retained history are counted.
+func (s *cashoutService) TotalCashedOut(chequebook common.Address) (*big.Int, error) {
+	last, err := s.lastSequence(chequebook)
+	if err != nil {
+		return nil, err
+	}
+
+	total := big.NewInt(0)
+	for seq := last; seq > 0; seq-- {
+		var action *cashoutAction
+		err := s.store.Get(cashoutActionKey(chequebook, seq), &action)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				// older actions were dropped from the history
+				break
+			}
+			return nil, err
+		}
+
+		if action.Reverted || action.Result == nil || action.Result.Bounced || action.Result.TotalPayout == nil {
+			continue
+		}
+		total.Add(total, action.Result.TotalPayout)
+	}
+
+	return total, nil
+}
+
+// actionStatus gets the status of the cashout action, querying the backend if its outcome is not stored yet
+func (s *cashoutService) actionStatus(ctx context.Context, chequebookAddress common.Address, action *cashoutAction) (*CashoutStatus, error) {
+	if action.confirmed() {
+		return &CashoutStatus{
+			TxHash:      action.TxHash,
+			Cheque:      action.Cheque,
+			Amount:      action.Amount,
+			Outstanding: action.Outstanding,
+			GasCost:     action.GasCost,
+			Result:      action.Result,
+			Reverted:    action.Reverted,
+			Failed:      action.Failed,
+		}, nil
+	}
+
+	tx, pending, err := s.backend.TransactionByHash(ctx, action.TxHash)
 	if err != nil {
 		return nil, err
 	}
 
 	if pending {
 		return &CashoutStatus{
-			TxHash:   action.TxHash,
-			Cheque:   action.Cheque,
-			Result:   nil,
-			Reverted: false,
+			TxHash:      action.TxHash,
+			Cheque:      action.Cheque,
+			Amount:      action.Amount,
+			Outstanding: action.Outstanding,
+			Result:      nil,
+			Reverted:    false,
 		}, nil
 	}
 
@@ -162,33 +1536,64 @@ func (s *cashoutService) CashoutStatus(ctx context.Context, chequebookAddress co
 
 	if receipt.Status == types.ReceiptStatusFailed {
 		return &CashoutStatus{
-			TxHash:   action.TxHash,
-			Cheque:   action.Cheque,
-			Result:   nil,
-			Reverted: true,
+			TxHash:      action.TxHash,
+			Cheque:      action.Cheque,
+			Amount:      action.Amount,
+			Outstanding: action.Outstanding,
+			GasCost:     transactionCost(tx, receipt),
+			Result:      nil,
+			Reverted:    true,
 		}, nil
 	}
 
 	result, err := s.parseCashChequeBeneficiaryReceipt(chequebookAddress, receipt)
 	if err != nil {
-		return nil, err
+		if !errors.Is(err, ErrNoChequeCashedEvent) {
+			return nil, err
+		}
+		return &CashoutStatus{
+			TxHash:      action.TxHash,
+			Cheque:      action.Cheque,
+			Amount:      action.Amount,
+			Outstanding: action.Outstanding,
+			GasCost:     transactionCost(tx, receipt),
+			Failed:      err.Error(),
+		}, nil
 	}
 
 	return &CashoutStatus{
-		TxHash:   action.TxHash,
-		Cheque:   action.Cheque,
-		Result:   result,
-		Reverted: false,
+		TxHash:      action.TxHash,
+		Cheque:      action.Cheque,
+		Amount:      action.Amount,
+		Outstanding: action.Outstanding,
+		GasCost:     transactionCost(tx, receipt),
+		Result:      result,
+		Reverted:    false,
 	}, nil
 }
 
-// parseCashChequeBeneficiaryReceipt processes the receipt from a CashChequeBeneficiary transaction
+// transactionCost computes the cost of a mined transaction. The receipts of
+// the used go-ethereum version do not carry the effective gas price, so the
+// gas price of the transaction is used, which is the effective one for all
+// transactions before EIP-1559. It returns nil if the transaction is unknown.
+func transactionCost(tx *types.Transaction, receipt *types.Receipt) *big.Int {
+	if tx == nil || tx.GasPrice() == nil {
+		return nil
+	}
+	return big.NewInt(0).Mul(tx.GasPrice(), new(big.Int).SetUint64(receipt.GasUsed))
+}
+
+// parseCashChequeBeneficiaryReceipt processes the receipt from a
+// CashChequeBeneficiary transaction. It returns ErrNoChequeCashedEvent if the
+// receipt has no ChequeCashed event of the chequebook, e.g. because the call
+// went through a contract which does not emit it.
 func (s *cashoutService) parseCashChequeBeneficiaryReceipt(chequebookAddress common.Address, receipt *types.Receipt) (*CashChequeResult, error) {
 	result := &CashChequeResult{
 		Bounced: false,
 	}
+	cashed := false
 
-	binding, err := s.simpleSwapBindingFunc(chequebookAddress, s.backend)
+	binding, err := s.binding(chequebookAddress)
 	if err != nil {
 		return nil, err
 	}
@@ -204,15 +1609,20 @@ func (s *cashoutService) parseCashChequeBeneficiaryReceipt(chequebookAddress com
 			result.TotalPayout = event.TotalPayout
 			result.CumulativePayout = event.CumulativePayout
 			result.Recipient = event.Recipient
+			cashed = true
 		} else if _, err := binding.ParseChequeBounced(*log); err == nil {
 			result.Bounced = true
 		}
 	}
 
+	if !cashed {
+		return nil, fmt.Errorf("%w: transaction %x", ErrNoChequeCashedEvent, receipt.TxHash)
+	}
+
 	return result, nil
 }
 
-// Equal compares to CashChequeResults
+// Equal compares to CashChequeResults. Nil amounts are treated as zero.
 func (r *CashChequeResult) Equal(o *CashChequeResult) bool {
 	if r.Beneficiary != o.Beneficiary {
 		return false
@@ -223,17 +1633,28 @@ func (r *CashChequeResult) Equal(o *CashChequeResult) bool {
 	if r.Caller != o.Caller {
 		return false
 	}
-	if r.CallerPayout.Cmp(o.CallerPayout) != 0 {
+	if cmpAmounts(r.CallerPayout, o.CallerPayout) != 0 {
 		return false
 	}
-	if r.CumulativePayout.Cmp(o.CumulativePayout) != 0 {
+	if cmpAmounts(r.CumulativePayout, o.CumulativePayout) != 0 {
 		return false
 	}
 	if r.Recipient != o.Recipient {
 		return false
 	}
-	if r.TotalPayout.Cmp(o.TotalPayout) != 0 {
+	if cmpAmounts(r.TotalPayout, o.TotalPayout) != 0 {
 		return false
 	}
 	return true
 }
+
+// cmpAmounts compares two amounts like big.Int.Cmp, treating nil as zero.
+func cmpAmounts(a, b *big.Int) int {
+	if a == nil {
+		a = big.NewInt(0)
+	}
+	if b == nil {
+		b = big.NewInt(0)
+	}
+	return a.Cmp(b)
+}
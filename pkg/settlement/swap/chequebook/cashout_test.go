@@ -5,21 +5,38 @@
 package chequebook_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"io/ioutil"
 	"math/big"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethersphere/bee/pkg/crypto"
+	"github.com/ethersphere/bee/pkg/logging"
+	"github.com/ethersphere/bee/pkg/metrics"
 	"github.com/ethersphere/bee/pkg/settlement/swap/chequebook"
 	chequestoremock "github.com/ethersphere/bee/pkg/settlement/swap/chequestore/mock"
 	"github.com/ethersphere/bee/pkg/settlement/swap/transaction"
 	"github.com/ethersphere/bee/pkg/settlement/swap/transaction/backendmock"
 	transactionmock "github.com/ethersphere/bee/pkg/settlement/swap/transaction/mock"
 	storemock "github.com/ethersphere/bee/pkg/statestore/mock"
+	"github.com/ethersphere/bee/pkg/storage"
 	"github.com/ethersphere/sw3-bindings/v2/simpleswapfactory"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 )
 
 func TestCashout(t *testing.T) {
@@ -41,9 +58,11 @@ func TestCashout(t *testing.T) {
 
 	store := storemock.NewStateStore()
 	cashoutService, err := chequebook.NewCashoutService(
+		logging.New(ioutil.Discard, 0),
 		store,
 		func(common.Address, bind.ContractBackend) (chequebook.SimpleSwapBinding, error) {
 			return &simpleSwapBindingMock{
+				paidOut: noPaidOut,
 				parseChequeCashed: func(l types.Log) (*simpleswapfactory.ERC20SimpleSwapChequeCashed, error) {
 					if l.Topics[0] != log1Topic {
 						t.Fatalf("parsing wrong log. wanted %v, got %v", log1Topic, l.Topics[0])
@@ -170,9 +189,11 @@ func TestCashoutBounced(t *testing.T) {
 
 	store := storemock.NewStateStore()
 	cashoutService, err := chequebook.NewCashoutService(
+		logging.New(ioutil.Discard, 0),
 		store,
 		func(common.Address, bind.ContractBackend) (chequebook.SimpleSwapBinding, error) {
 			return &simpleSwapBindingMock{
+				paidOut: noPaidOut,
 				parseChequeCashed: func(l types.Log) (*simpleswapfactory.ERC20SimpleSwapChequeCashed, error) {
 					if l.Topics[0] != log1Topic {
 						return nil, errors.New("")
@@ -306,9 +327,12 @@ func TestCashoutStatusReverted(t *testing.T) {
 
 	store := storemock.NewStateStore()
 	cashoutService, err := chequebook.NewCashoutService(
+		logging.New(ioutil.Discard, 0),
 		store,
 		func(common.Address, bind.ContractBackend) (chequebook.SimpleSwapBinding, error) {
-			return &simpleSwapBindingMock{}, nil
+			return &simpleSwapBindingMock{
+				paidOut: noPaidOut,
+			}, nil
 		},
 		backendmock.New(
 			backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error) {
@@ -388,9 +412,12 @@ func TestCashoutStatusPending(t *testing.T) {
 
 	store := storemock.NewStateStore()
 	cashoutService, err := chequebook.NewCashoutService(
+		logging.New(ioutil.Discard, 0),
 		store,
 		func(common.Address, bind.ContractBackend) (chequebook.SimpleSwapBinding, error) {
-			return &simpleSwapBindingMock{}, nil
+			return &simpleSwapBindingMock{
+				paidOut: noPaidOut,
+			}, nil
 		},
 		backendmock.New(
 			backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error) {
@@ -448,3 +475,2344 @@ func TestCashoutStatusPending(t *testing.T) {
 		t.Fatalf("got result for pending cashout: %v", status.Result)
 	}
 }
+
+// cashoutTestConfig holds the dependencies of a cashout service created by
+// newTestCashoutService.
+type cashoutTestConfig struct {
+	store           storage.StateStorer
+	bindingFunc     chequebook.SimpleSwapBindingFunc
+	backendOpts     []backendmock.Option
+	transactionOpts []transactionmock.Option
+	chequeStoreOpts []chequestoremock.Option
+	opts            []chequebook.CashoutOption
+}
+
+// cashoutTestOption configures a cashout service created by newTestCashoutService.
+type cashoutTestOption func(*cashoutTestConfig)
+
+// withStore makes the service use the store, e.g. to share it across restarts.
+func withStore(store storage.StateStorer) cashoutTestOption {
+	return func(c *cashoutTestConfig) {
+		c.store = store
+	}
+}
+
+// withBinding makes the service use the binding for every chequebook.
+func withBinding(binding *simpleSwapBindingMock) cashoutTestOption {
+	return withBindingFunc(func(common.Address, bind.ContractBackend) (chequebook.SimpleSwapBinding, error) {
+		return binding, nil
+	})
+}
+
+// withBindingFunc makes the service create the chequebook bindings with f.
+func withBindingFunc(f chequebook.SimpleSwapBindingFunc) cashoutTestOption {
+	return func(c *cashoutTestConfig) {
+		c.bindingFunc = f
+	}
+}
+
+// withBackend adds options of the backend mock.
+func withBackend(opts ...backendmock.Option) cashoutTestOption {
+	return func(c *cashoutTestConfig) {
+		c.backendOpts = append(c.backendOpts, opts...)
+	}
+}
+
+// withSendFunc makes the transaction service mock send transactions with f.
+func withSendFunc(f func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error)) cashoutTestOption {
+	return func(c *cashoutTestConfig) {
+		c.transactionOpts = append(c.transactionOpts, transactionmock.WithSendFunc(f))
+	}
+}
+
+// withChequeStore adds options of the cheque store mock.
+func withChequeStore(opts ...chequestoremock.Option) cashoutTestOption {
+	return func(c *cashoutTestConfig) {
+		c.chequeStoreOpts = append(c.chequeStoreOpts, opts...)
+	}
+}
+
+// withLastCheque makes the cheque store return the cheque as the last cheque
+// of every chequebook.
+func withLastCheque(cheque *chequebook.SignedCheque) cashoutTestOption {
+	return withChequeStore(chequestoremock.WithLastChequeFunc(func(common.Address) (*chequebook.SignedCheque, error) {
+		return cheque, nil
+	}))
+}
+
+// withCashoutOptions adds options of the cashout service itself.
+func withCashoutOptions(opts ...chequebook.CashoutOption) cashoutTestOption {
+	return func(c *cashoutTestConfig) {
+		c.opts = append(c.opts, opts...)
+	}
+}
+
+// newTestCashoutService creates a cashout service which discards its logs.
+// Unless configured otherwise it uses an empty store and the same binding of a
+// chequebook from which nothing was paid out yet for every chequebook, while
+// all calls of the backend and the transaction service fail.
+func newTestCashoutService(t *testing.T, opts ...cashoutTestOption) chequebook.CashoutService {
+	t.Helper()
+
+	c := &cashoutTestConfig{
+		store: storemock.NewStateStore(),
+	}
+	withBinding(&simpleSwapBindingMock{paidOut: noPaidOut})(c)
+	for _, o := range opts {
+		o(c)
+	}
+
+	cashoutService, err := chequebook.NewCashoutService(
+		logging.New(ioutil.Discard, 0),
+		c.store,
+		c.bindingFunc,
+		backendmock.New(c.backendOpts...),
+		transactionmock.New(c.transactionOpts...),
+		chequestoremock.NewChequeStore(c.chequeStoreOpts...),
+		c.opts...,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cashoutService
+}
+
+// noPaidOut is the paidOut function of a chequebook from which nothing was cashed yet
+func noPaidOut(*bind.CallOpts, common.Address) (*big.Int, error) {
+	return big.NewInt(0), nil
+}
+
+// chequeCashed returns a parseChequeCashed function of the binding mock which
+// parses every log into a ChequeCashed event of the beneficiary without a
+// caller payout.
+func chequeCashed(beneficiary, recipient common.Address, totalPayout, cumulativePayout *big.Int) func(types.Log) (*simpleswapfactory.ERC20SimpleSwapChequeCashed, error) {
+	return func(types.Log) (*simpleswapfactory.ERC20SimpleSwapChequeCashed, error) {
+		return &simpleswapfactory.ERC20SimpleSwapChequeCashed{
+			Beneficiary:      beneficiary,
+			Recipient:        recipient,
+			Caller:           beneficiary,
+			TotalPayout:      totalPayout,
+			CumulativePayout: cumulativePayout,
+			CallerPayout:     big.NewInt(0),
+		}, nil
+	}
+}
+
+// cashedReceipt is the receipt of a successful cashout transaction with a log
+// of the chequebook, which the binding mock parses.
+func cashedReceipt(chequebookAddress common.Address) *types.Receipt {
+	return &types.Receipt{
+		Status: types.ReceiptStatusSuccessful,
+		Logs:   []*types.Log{{Address: chequebookAddress}},
+	}
+}
+
+// sendToChequebookHash sends transactions with a hash derived from the
+// chequebook address, so that receipt mocks can tell the chequebook apart.
+func sendToChequebookHash(_ context.Context, request *transaction.TxRequest) (common.Hash, error) {
+	return common.BytesToHash(request.To.Bytes()), nil
+}
+
+// waitForResult polls the status of the latest cashout of the chequebook
+// until the receipt poller stored its outcome.
+func waitForResult(t *testing.T, cashoutService chequebook.CashoutService, chequebookAddress common.Address) *chequebook.CashoutStatus {
+	t.Helper()
+
+	var (
+		status *chequebook.CashoutStatus
+		err    error
+	)
+	for i := 0; i < 100; i++ {
+		status, err = cashoutService.CashoutStatus(context.Background(), chequebookAddress)
+		if err == nil && (status.Result != nil || status.Reverted) {
+			return status
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("cashout for chequebook %x not resolved: %v", chequebookAddress, err)
+	return nil
+}
+
+func TestProfitabilityReport(t *testing.T) {
+	beneficiary := common.HexToAddress("aaaa")
+	profitableChequebook := common.HexToAddress("abcd")
+	unprofitableChequebook := common.HexToAddress("bcde")
+	cashedChequebook := common.HexToAddress("cdef")
+	gasPrice := big.NewInt(2)
+	gasLimit := uint64(50)
+
+	cumulativePayouts := map[common.Address]*big.Int{
+		profitableChequebook:   big.NewInt(1000),
+		unprofitableChequebook: big.NewInt(500),
+		cashedChequebook:       big.NewInt(300),
+	}
+	paidOuts := map[common.Address]*big.Int{
+		profitableChequebook:   big.NewInt(0),
+		unprofitableChequebook: big.NewInt(450),
+		cashedChequebook:       big.NewInt(300),
+	}
+
+	cheques := make(map[common.Address]*chequebook.SignedCheque)
+	for c, cumulativePayout := range cumulativePayouts {
+		cheques[c] = &chequebook.SignedCheque{
+			Cheque: chequebook.Cheque{
+				Beneficiary:      beneficiary,
+				CumulativePayout: cumulativePayout,
+				Chequebook:       c,
+			},
+			Signature: []byte{},
+		}
+	}
+
+	cashoutService := newTestCashoutService(t,
+		withBindingFunc(func(c common.Address, _ bind.ContractBackend) (chequebook.SimpleSwapBinding, error) {
+			return &simpleSwapBindingMock{
+				paidOut: func(_ *bind.CallOpts, b common.Address) (*big.Int, error) {
+					if b != beneficiary {
+						t.Fatalf("querying paidOut for wrong beneficiary. wanted %v, got %v", beneficiary, b)
+					}
+					return paidOuts[c], nil
+				},
+			}, nil
+		}),
+		withBackend(
+			backendmock.WithEstimateGasFunc(func(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+				if call.From != beneficiary {
+					t.Fatalf("estimating gas from wrong address. wanted %v, got %v", beneficiary, call.From)
+				}
+				return gasLimit, nil
+			}),
+			backendmock.WithSuggestGasPriceFunc(func(ctx context.Context) (*big.Int, error) {
+				return gasPrice, nil
+			}),
+		),
+		withChequeStore(chequestoremock.WithLastChequesFunc(func() (map[common.Address]*chequebook.SignedCheque, error) {
+			return cheques, nil
+		})),
+	)
+
+	report, err := cashoutService.ProfitabilityReport(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report) != 2 {
+		t.Fatalf("got %d report entries, wanted 2", len(report))
+	}
+
+	expected := []chequebook.ChequebookProfit{
+		{
+			Chequebook:   profitableChequebook,
+			Uncashed:     big.NewInt(1000),
+			GasCost:      big.NewInt(100),
+			CallerPayout: big.NewInt(0),
+			NetProfit:    big.NewInt(900),
+		},
+		{
+			Chequebook:   unprofitableChequebook,
+			Uncashed:     big.NewInt(50),
+			GasCost:      big.NewInt(100),
+			CallerPayout: big.NewInt(0),
+			NetProfit:    big.NewInt(-50),
+		},
+	}
+
+	for i, e := range expected {
+		r := report[i]
+		if r.Chequebook != e.Chequebook {
+			t.Fatalf("wrong chequebook at position %d. wanted %v, got %v", i, e.Chequebook, r.Chequebook)
+		}
+		if r.Uncashed.Cmp(e.Uncashed) != 0 {
+			t.Fatalf("wrong uncashed amount for %v. wanted %d, got %d", e.Chequebook, e.Uncashed, r.Uncashed)
+		}
+		if r.GasCost.Cmp(e.GasCost) != 0 {
+			t.Fatalf("wrong gas cost for %v. wanted %d, got %d", e.Chequebook, e.GasCost, r.GasCost)
+		}
+		if r.CallerPayout.Cmp(e.CallerPayout) != 0 {
+			t.Fatalf("wrong caller payout for %v. wanted %d, got %d", e.Chequebook, e.CallerPayout, r.CallerPayout)
+		}
+		if r.NetProfit.Cmp(e.NetProfit) != 0 {
+			t.Fatalf("wrong net profit for %v. wanted %d, got %d", e.Chequebook, e.NetProfit, r.NetProfit)
+		}
+	}
+}
+
+func TestCashoutPollerResolvesPending(t *testing.T) {
+	const chequebookCount = 50
+	beneficiary := common.HexToAddress("aaaa")
+	recipientAddress := common.HexToAddress("efff")
+	totalPayout := big.NewInt(100)
+
+	cheques := make(map[common.Address]*chequebook.SignedCheque)
+	for i := 1; i <= chequebookCount; i++ {
+		c := common.BigToAddress(big.NewInt(int64(i)))
+		cheques[c] = &chequebook.SignedCheque{
+			Cheque: chequebook.Cheque{
+				Beneficiary:      beneficiary,
+				CumulativePayout: totalPayout,
+				Chequebook:       c,
+			},
+			Signature: []byte{},
+		}
+	}
+
+	cashoutService := newTestCashoutService(t,
+		withBinding(&simpleSwapBindingMock{
+			paidOut:           noPaidOut,
+			parseChequeCashed: chequeCashed(beneficiary, recipientAddress, totalPayout, totalPayout),
+		}),
+		withBackend(
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return cashedReceipt(common.BytesToAddress(hash.Bytes())), nil
+			}),
+		),
+		withSendFunc(sendToChequebookHash),
+		withChequeStore(chequestoremock.WithLastChequeFunc(func(c common.Address) (*chequebook.SignedCheque, error) {
+			return cheques[c], nil
+		})),
+		withCashoutOptions(chequebook.WithReceiptPollInterval(10*time.Millisecond)),
+	)
+
+	for c := range cheques {
+		if _, err := cashoutService.CashCheque(context.Background(), c, recipientAddress); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := cashoutService.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cashoutService.Close()
+
+	// the backend mock does not support TransactionByHash so CashoutStatus
+	// only succeeds once the poller has stored the result
+	for c := range cheques {
+		status := waitForResult(t, cashoutService, c)
+		if status.Result == nil {
+			t.Fatalf("missing result for chequebook %x", c)
+		}
+		if status.Result.TotalPayout.Cmp(totalPayout) != 0 {
+			t.Fatalf("wrong total payout for chequebook %x. wanted %d, got %d", c, totalPayout, status.Result.TotalPayout)
+		}
+	}
+}
+
+func TestCashoutReceiptTimeout(t *testing.T) {
+	beneficiary := common.HexToAddress("aaaa")
+	recipientAddress := common.HexToAddress("efff")
+	hangingChequebook := common.HexToAddress("1111")
+	minedChequebook := common.HexToAddress("2222")
+	totalPayout := big.NewInt(100)
+
+	var (
+		mu      sync.Mutex
+		queried int
+	)
+	cashoutService := newTestCashoutService(t,
+		withBinding(&simpleSwapBindingMock{
+			paidOut:           noPaidOut,
+			parseChequeCashed: chequeCashed(beneficiary, recipientAddress, totalPayout, totalPayout),
+		}),
+		withBackend(
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				c := common.BytesToAddress(hash.Bytes())
+				if c == hangingChequebook {
+					mu.Lock()
+					queried++
+					mu.Unlock()
+					// the node does not answer until the request is abandoned
+					<-ctx.Done()
+					return nil, ctx.Err()
+				}
+				return cashedReceipt(c), nil
+			}),
+		),
+		withSendFunc(sendToChequebookHash),
+		withChequeStore(chequestoremock.WithLastChequeFunc(func(c common.Address) (*chequebook.SignedCheque, error) {
+			return &chequebook.SignedCheque{
+				Cheque: chequebook.Cheque{
+					Beneficiary:      beneficiary,
+					CumulativePayout: totalPayout,
+					Chequebook:       c,
+				},
+				Signature: []byte{},
+			}, nil
+		})),
+		withCashoutOptions(
+			chequebook.WithReceiptPollInterval(10*time.Millisecond),
+			chequebook.WithReceiptTimeout(10*time.Millisecond),
+		),
+	)
+
+	for _, c := range []common.Address{hangingChequebook, minedChequebook} {
+		if _, err := cashoutService.CashCheque(context.Background(), c, recipientAddress); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := cashoutService.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cashoutService.Close()
+
+	waitForResult(t, cashoutService, minedChequebook)
+
+	// the hanging request is abandoned and retried on every poll
+	for i := 0; i < 100; i++ {
+		mu.Lock()
+		n := queried
+		mu.Unlock()
+		if n >= 3 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("hanging receipt request not abandoned")
+}
+
+func TestCashoutMetrics(t *testing.T) {
+	beneficiary := common.HexToAddress("aaaa")
+	recipientAddress := common.HexToAddress("efff")
+	confirmedChequebook := common.HexToAddress("1111")
+	revertedChequebook := common.HexToAddress("2222")
+	totalPayout := big.NewInt(100)
+	callerPayout := big.NewInt(10)
+
+	cashoutService := newTestCashoutService(t,
+		withBinding(&simpleSwapBindingMock{
+			paidOut: noPaidOut,
+			parseChequeCashed: func(l types.Log) (*simpleswapfactory.ERC20SimpleSwapChequeCashed, error) {
+				return &simpleswapfactory.ERC20SimpleSwapChequeCashed{
+					Beneficiary:      beneficiary,
+					Recipient:        recipientAddress,
+					Caller:           beneficiary,
+					TotalPayout:      totalPayout,
+					CumulativePayout: totalPayout,
+					CallerPayout:     callerPayout,
+				}, nil
+			},
+		}),
+		withBackend(
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				c := common.BytesToAddress(hash.Bytes())
+				if c == revertedChequebook {
+					return &types.Receipt{
+						Status: types.ReceiptStatusFailed,
+					}, nil
+				}
+				return cashedReceipt(c), nil
+			}),
+		),
+		withSendFunc(sendToChequebookHash),
+		withChequeStore(chequestoremock.WithLastChequeFunc(func(c common.Address) (*chequebook.SignedCheque, error) {
+			return &chequebook.SignedCheque{
+				Cheque: chequebook.Cheque{
+					Beneficiary:      beneficiary,
+					CumulativePayout: totalPayout,
+					Chequebook:       c,
+				},
+				Signature: []byte{},
+			}, nil
+		})),
+		withCashoutOptions(chequebook.WithReceiptPollInterval(10*time.Millisecond)),
+	)
+
+	for _, c := range []common.Address{confirmedChequebook, revertedChequebook} {
+		if _, err := cashoutService.CashCheque(context.Background(), c, recipientAddress); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := cashoutService.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cashoutService.Close()
+
+	collectors := cashoutService.(interface {
+		Metrics() []prometheus.Collector
+	}).Metrics()
+
+	values := make(map[string]*dto.Metric)
+	for i := 0; i < 100; i++ {
+		for _, c := range collectors {
+			metric := c.(prometheus.Metric)
+			var m dto.Metric
+			if err := metric.Write(&m); err != nil {
+				t.Fatal(err)
+			}
+			values[metric.Desc().String()] = &m
+		}
+		if metricValue(values, "cashouts_confirmed").GetCounter().GetValue() == 1 &&
+			metricValue(values, "cashouts_reverted").GetCounter().GetValue() == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for name, want := range map[string]float64{
+		"cashouts_confirmed": 1,
+		"cashouts_bounced":   0,
+		"cashouts_reverted":  1,
+	} {
+		if got := metricValue(values, name).GetCounter().GetValue(); got != want {
+			t.Fatalf("got %s %v, want %v", name, got, want)
+		}
+	}
+
+	payouts := metricValue(values, "cashout_caller_payout").GetHistogram()
+	if got := payouts.GetSampleCount(); got != 1 {
+		t.Fatalf("got %d caller payouts observed, want 1", got)
+	}
+	if got := payouts.GetSampleSum(); got != float64(callerPayout.Int64()) {
+		t.Fatalf("got caller payout sum %v, want %v", got, callerPayout)
+	}
+}
+
+// metricValue returns the written chequebook metric with the name from the
+// metrics keyed by their description, or nil if there is none.
+func metricValue(values map[string]*dto.Metric, name string) *dto.Metric {
+	fqName := prometheus.BuildFQName(metrics.Namespace, "chequebook", name)
+	for desc, m := range values {
+		if strings.Contains(desc, `"`+fqName+`"`) {
+			return m
+		}
+	}
+	return nil
+}
+
+func TestCashoutStartResumesUnconfirmed(t *testing.T) {
+	chequebookAddress := common.HexToAddress("abcd")
+	unconfirmedTxHash := common.HexToHash("1111")
+	confirmedTxHash := common.HexToHash("2222")
+	revertedTxHash := common.HexToHash("3333")
+
+	store := storemock.NewStateStore()
+	for seq, action := range []map[string]interface{}{
+		{
+			"TxHash": confirmedTxHash,
+			"Result": &chequebook.CashChequeResult{
+				TotalPayout:      big.NewInt(100),
+				CumulativePayout: big.NewInt(100),
+				CallerPayout:     big.NewInt(0),
+			},
+		},
+		{
+			"TxHash":   revertedTxHash,
+			"Reverted": true,
+		},
+		{
+			"TxHash": unconfirmedTxHash,
+		},
+	} {
+		err := store.Put(fmt.Sprintf("cashout_%x_%d", chequebookAddress, seq+1), action)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var (
+		mu      sync.Mutex
+		queried = make(map[common.Hash]int)
+	)
+	cashoutService := newTestCashoutService(t,
+		withStore(store),
+		withBackend(
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				queried[hash]++
+				return nil, errors.New("not found")
+			}),
+		),
+		withCashoutOptions(chequebook.WithReceiptPollInterval(10*time.Millisecond)),
+	)
+
+	if err := cashoutService.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// wait for a few polls of the unconfirmed transaction
+	for i := 0; i < 100; i++ {
+		mu.Lock()
+		n := queried[unconfirmedTxHash]
+		mu.Unlock()
+		if n >= 3 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := cashoutService.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if queried[unconfirmedTxHash] == 0 {
+		t.Fatal("unconfirmed cashout was not resumed")
+	}
+	for _, txHash := range []common.Hash{confirmedTxHash, revertedTxHash} {
+		if n := queried[txHash]; n != 0 {
+			t.Fatalf("confirmed cashout %x was resumed and polled %d times", txHash, n)
+		}
+	}
+}
+
+func TestPrepareCashCheque(t *testing.T) {
+	chequebookAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+
+	cheque := &chequebook.SignedCheque{
+		Cheque: chequebook.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Chequebook:       chequebookAddress,
+		},
+		Signature: []byte{1},
+	}
+
+	chequebookABI, err := abi.JSON(strings.NewReader(simpleswapfactory.ERC20SimpleSwapABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedCallData, err := chequebookABI.Pack("cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cashoutService := newTestCashoutService(t,
+		withSendFunc(func(c context.Context, request *transaction.TxRequest) (common.Hash, error) {
+			t.Fatal("sent transaction in dry-run mode")
+			return common.Hash{}, nil
+		}),
+		withLastCheque(cheque),
+	)
+
+	request, err := cashoutService.PrepareCashCheque(context.Background(), chequebookAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if request.To != chequebookAddress {
+		t.Fatalf("got transaction to %x, want %x", request.To, chequebookAddress)
+	}
+	if !bytes.Equal(request.Data, expectedCallData) {
+		t.Fatal("wrong call data")
+	}
+	if request.Value.Sign() != 0 {
+		t.Fatalf("got value %d, want 0", request.Value)
+	}
+
+	_, err = cashoutService.CashoutStatus(context.Background(), chequebookAddress)
+	if !errors.Is(err, chequebook.ErrNoCashout) {
+		t.Fatalf("got error %v, want %v", err, chequebook.ErrNoCashout)
+	}
+}
+
+func TestCashChequeToDefault(t *testing.T) {
+	chequebookAddress := common.HexToAddress("abcd")
+	treasuryAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &chequebook.SignedCheque{
+		Cheque: chequebook.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Chequebook:       chequebookAddress,
+		},
+		Signature: []byte{1},
+	}
+
+	chequebookABI, err := abi.JSON(strings.NewReader(simpleswapfactory.ERC20SimpleSwapABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedCallData, err := chequebookABI.Pack("cashChequeBeneficiary", treasuryAddress, cheque.CumulativePayout, cheque.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newService := func(opts ...chequebook.CashoutOption) chequebook.CashoutService {
+		return newTestCashoutService(t,
+			withSendFunc(func(c context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				if !bytes.Equal(request.Data, expectedCallData) {
+					t.Fatal("wrong call data")
+				}
+				return txHash, nil
+			}),
+			withLastCheque(cheque),
+			withCashoutOptions(opts...),
+		)
+	}
+
+	_, err = newService().CashChequeToDefault(context.Background(), chequebookAddress)
+	if !errors.Is(err, chequebook.ErrNoDefaultRecipient) {
+		t.Fatalf("got error %v, want %v", err, chequebook.ErrNoDefaultRecipient)
+	}
+
+	returnedTxHash, err := newService(chequebook.WithDefaultRecipient(treasuryAddress)).CashChequeToDefault(context.Background(), chequebookAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if returnedTxHash != txHash {
+		t.Fatalf("returned wrong transaction hash. wanted %v, got %v", txHash, returnedTxHash)
+	}
+}
+
+func TestCashoutNotAChequebook(t *testing.T) {
+	chequebookAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+
+	cashoutService := newTestCashoutService(t,
+		withBindingFunc(chequebook.NewSimpleSwapBindings),
+		withBackend(
+			backendmock.WithCallContractFunc(func(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+				return nil, nil
+			}),
+			backendmock.WithCodeAtFunc(func(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+				if contract != chequebookAddress {
+					t.Fatalf("checked code of %x, want %x", contract, chequebookAddress)
+				}
+				return nil, nil
+			}),
+		),
+		withSendFunc(func(c context.Context, request *transaction.TxRequest) (common.Hash, error) {
+			t.Fatal("sent transaction to an address which is not a chequebook")
+			return common.Hash{}, nil
+		}),
+		withLastCheque(&chequebook.SignedCheque{
+			Cheque: chequebook.Cheque{
+				Beneficiary:      common.HexToAddress("aaaa"),
+				CumulativePayout: big.NewInt(500),
+				Chequebook:       chequebookAddress,
+			},
+			Signature: []byte{1},
+		}),
+	)
+
+	_, err := cashoutService.CashCheque(context.Background(), chequebookAddress, recipientAddress)
+	if !errors.Is(err, chequebook.ErrNotAChequebook) {
+		t.Fatalf("got error %v, want %v", err, chequebook.ErrNotAChequebook)
+	}
+
+	_, err = cashoutService.PrepareCashCheque(context.Background(), chequebookAddress, recipientAddress)
+	if !errors.Is(err, chequebook.ErrNotAChequebook) {
+		t.Fatalf("got error %v, want %v", err, chequebook.ErrNotAChequebook)
+	}
+}
+
+func TestCashSpecificCheque(t *testing.T) {
+	chequebookAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+
+	lastCheque := &chequebook.SignedCheque{
+		Cheque: chequebook.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Chequebook:       chequebookAddress,
+		},
+		Signature: []byte{1},
+	}
+	earlierCheque := &chequebook.SignedCheque{
+		Cheque: chequebook.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(300),
+			Chequebook:       chequebookAddress,
+		},
+		Signature: []byte{2},
+	}
+
+	chequebookABI, err := abi.JSON(strings.NewReader(simpleswapfactory.ERC20SimpleSwapABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedCallData, err := chequebookABI.Pack("cashChequeBeneficiary", recipientAddress, earlierCheque.CumulativePayout, earlierCheque.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cashoutService := newTestCashoutService(t,
+		withBinding(&simpleSwapBindingMock{
+			paidOut: func(*bind.CallOpts, common.Address) (*big.Int, error) {
+				return big.NewInt(300), nil
+			},
+		}),
+		withBackend(
+			backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error) {
+				return nil, true, nil
+			}),
+		),
+		withSendFunc(func(c context.Context, request *transaction.TxRequest) (common.Hash, error) {
+			if !bytes.Equal(request.Data, expectedCallData) {
+				t.Fatal("sending wrong call data")
+			}
+			return txHash, nil
+		}),
+		withLastCheque(lastCheque),
+	)
+
+	// the earlier cheque has been paid out completely
+	_, err = cashoutService.CashSpecificCheque(context.Background(), chequebookAddress, recipientAddress, earlierCheque)
+	if !errors.Is(err, chequebook.ErrNothingToCash) {
+		t.Fatalf("got error %v, want %v", err, chequebook.ErrNothingToCash)
+	}
+
+	_, err = cashoutService.CashSpecificCheque(context.Background(), common.HexToAddress("bcde"), recipientAddress, earlierCheque)
+	if !errors.Is(err, chequebook.ErrChequeInvalid) {
+		t.Fatalf("got error %v, want %v", err, chequebook.ErrChequeInvalid)
+	}
+
+	earlierCheque.CumulativePayout = big.NewInt(400)
+	expectedCallData, err = chequebookABI.Pack("cashChequeBeneficiary", recipientAddress, earlierCheque.CumulativePayout, earlierCheque.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	returnedTxHash, err := cashoutService.CashSpecificCheque(context.Background(), chequebookAddress, recipientAddress, earlierCheque)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if returnedTxHash != txHash {
+		t.Fatalf("returned wrong transaction hash. wanted %v, got %v", txHash, returnedTxHash)
+	}
+
+	status, err := cashoutService.CashoutStatus(context.Background(), chequebookAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Cheque.Equal(earlierCheque) {
+		t.Fatalf("wrong cheque in status. wanted %v, got %v", earlierCheque, status.Cheque)
+	}
+}
+
+func TestCashoutNotifyPending(t *testing.T) {
+	chequebookAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &chequebook.SignedCheque{
+		Cheque: chequebook.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Chequebook:       chequebookAddress,
+		},
+		Signature: []byte{},
+	}
+
+	store := storemock.NewStateStore()
+	newService := func() chequebook.CashoutService {
+		return newTestCashoutService(t,
+			withStore(store),
+			withBackend(
+				backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+					return nil, errors.New("not mined")
+				}),
+			),
+			withSendFunc(func(c context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				return txHash, nil
+			}),
+			withLastCheque(cheque),
+		)
+	}
+
+	type notification struct {
+		chequebook common.Address
+		txHash     common.Hash
+	}
+	var notifications []notification
+	notify := func(c common.Address, h common.Hash) error {
+		notifications = append(notifications, notification{chequebook: c, txHash: h})
+		return nil
+	}
+	want := []notification{{chequebook: chequebookAddress, txHash: txHash}}
+
+	cashoutService := newService()
+	cashoutService.SetNotifyPendingFunc(notify)
+
+	_, err := cashoutService.CashCheque(context.Background(), chequebookAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(notifications, want) {
+		t.Fatalf("got notifications %v, want %v", notifications, want)
+	}
+
+	// the pending cashout is notified again when it is resumed
+	notifications = nil
+	restarted := newService()
+	restarted.SetNotifyPendingFunc(notify)
+	if err := restarted.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer restarted.Close()
+
+	if !reflect.DeepEqual(notifications, want) {
+		t.Fatalf("got notifications %v after restart, want %v", notifications, want)
+	}
+}
+
+func TestCashoutCooldown(t *testing.T) {
+	chequebookAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &chequebook.SignedCheque{
+		Cheque: chequebook.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Chequebook:       chequebookAddress,
+		},
+		Signature: []byte{},
+	}
+
+	sent := 0
+	cashoutService := newTestCashoutService(t,
+		withSendFunc(func(c context.Context, request *transaction.TxRequest) (common.Hash, error) {
+			sent++
+			return txHash, nil
+		}),
+		withLastCheque(cheque),
+	)
+
+	cashoutService.SetCashoutCooldown(time.Hour)
+
+	_, err := cashoutService.CashCheque(context.Background(), chequebookAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cashoutService.CashCheque(context.Background(), chequebookAddress, recipientAddress)
+	if !errors.Is(err, chequebook.ErrCashoutCooldown) {
+		t.Fatalf("got error %v, want %v", err, chequebook.ErrCashoutCooldown)
+	}
+
+	if sent != 1 {
+		t.Fatalf("sent %d transactions, wanted 1", sent)
+	}
+}
+
+func TestCashoutCooldownWhileSending(t *testing.T) {
+	chequebookAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &chequebook.SignedCheque{
+		Cheque: chequebook.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Chequebook:       chequebookAddress,
+		},
+		Signature: []byte{},
+	}
+
+	sending := make(chan struct{})
+	release := make(chan struct{})
+	cashoutService := newTestCashoutService(t,
+		withSendFunc(func(c context.Context, request *transaction.TxRequest) (common.Hash, error) {
+			close(sending)
+			<-release
+			return txHash, nil
+		}),
+		withLastCheque(cheque),
+	)
+
+	cashoutService.SetCashoutCooldown(time.Hour)
+
+	errC := make(chan error, 1)
+	go func() {
+		_, err := cashoutService.CashCheque(context.Background(), chequebookAddress, recipientAddress)
+		errC <- err
+	}()
+
+	<-sending
+
+	// the service is not locked while the first transaction is sent, but
+	// the chequebook is reserved for it
+	_, err := cashoutService.CashCheque(context.Background(), chequebookAddress, recipientAddress)
+	if !errors.Is(err, chequebook.ErrCashoutCooldown) {
+		t.Fatalf("got error %v, want %v", err, chequebook.ErrCashoutCooldown)
+	}
+
+	close(release)
+	if err := <-errC; err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cashoutService.CashCheque(context.Background(), chequebookAddress, recipientAddress)
+	if !errors.Is(err, chequebook.ErrCashoutCooldown) {
+		t.Fatalf("got error %v, want %v", err, chequebook.ErrCashoutCooldown)
+	}
+}
+
+func TestCashoutExternalSigner(t *testing.T) {
+	chequebookAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	totalPayout := big.NewInt(100)
+
+	cheque := &chequebook.SignedCheque{
+		Cheque: chequebook.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Chequebook:       chequebookAddress,
+		},
+		Signature: []byte{},
+	}
+
+	key, err := ethcrypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := ethcrypto.PubkeyToAddress(key.PublicKey)
+
+	var signedTx *types.Transaction
+	signer := func(ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
+		if *tx.To() != chequebookAddress {
+			t.Fatalf("signing transaction to wrong contract. wanted %x, got %x", chequebookAddress, tx.To())
+		}
+		signed, err := types.SignTx(tx, types.HomesteadSigner{}, key)
+		if err != nil {
+			return nil, err
+		}
+		signedTx = signed
+		return signed, nil
+	}
+
+	var broadcastTx *types.Transaction
+	cashoutService := newTestCashoutService(t,
+		withBinding(&simpleSwapBindingMock{
+			paidOut:           noPaidOut,
+			parseChequeCashed: chequeCashed(cheque.Beneficiary, recipientAddress, totalPayout, cheque.CumulativePayout),
+		}),
+		withBackend(
+			backendmock.WithEstimateGasFunc(func(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+				if call.From != sender {
+					t.Fatalf("estimating gas for wrong sender. wanted %x, got %x", sender, call.From)
+				}
+				return 100000, nil
+			}),
+			backendmock.WithSuggestGasPriceFunc(func(ctx context.Context) (*big.Int, error) {
+				return big.NewInt(10), nil
+			}),
+			backendmock.WithPendingNonceAtFunc(func(ctx context.Context, account common.Address) (uint64, error) {
+				return 3, nil
+			}),
+			backendmock.WithSendTransactionFunc(func(ctx context.Context, tx *types.Transaction) error {
+				broadcastTx = tx
+				return nil
+			}),
+			backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+				return nil, true, nil
+			}),
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				if signedTx == nil || hash != signedTx.Hash() {
+					return nil, errors.New("not found")
+				}
+				return cashedReceipt(chequebookAddress), nil
+			}),
+		),
+		withSendFunc(func(c context.Context, request *transaction.TxRequest) (common.Hash, error) {
+			t.Fatal("transaction service used despite external signer")
+			return common.Hash{}, nil
+		}),
+		withLastCheque(cheque),
+		withCashoutOptions(
+			chequebook.WithExternalSigner(sender, signer),
+			chequebook.WithReceiptPollInterval(10*time.Millisecond),
+		),
+	)
+
+	if err := cashoutService.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cashoutService.Close()
+
+	txHash, err := cashoutService.CashCheque(context.Background(), chequebookAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if broadcastTx == nil {
+		t.Fatal("no transaction broadcast")
+	}
+	if broadcastTx.Hash() != signedTx.Hash() {
+		t.Fatalf("broadcast wrong transaction. wanted %v, got %v", signedTx.Hash(), broadcastTx.Hash())
+	}
+	if txHash != signedTx.Hash() {
+		t.Fatalf("returned wrong transaction hash. wanted %v, got %v", signedTx.Hash(), txHash)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, err := cashoutService.CashoutStatus(context.Background(), chequebookAddress)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.TxHash != signedTx.Hash() {
+			t.Fatalf("monitoring wrong transaction. wanted %v, got %v", signedTx.Hash(), status.TxHash)
+		}
+		if status.Result != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("signed transaction not resolved")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCashoutPartial(t *testing.T) {
+	chequebookAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+	paidOut := big.NewInt(100)
+	uncashed := big.NewInt(400)
+	chainID := int64(1)
+
+	privKey, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer, err := crypto.NewEthereumAddress(privKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cheque := &chequebook.SignedCheque{
+		Cheque: chequebook.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Chequebook:       chequebookAddress,
+		},
+	}
+	cheque.Signature, err = chequebook.NewChequeSigner(crypto.NewDefaultSigner(privKey), chainID).Sign(&cheque.Cheque)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chequebookABI, err := abi.JSON(strings.NewReader(simpleswapfactory.ERC20SimpleSwapABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := 0
+	cashoutService := newTestCashoutService(t,
+		withBinding(&simpleSwapBindingMock{
+			paidOut: func(o *bind.CallOpts, beneficiary common.Address) (*big.Int, error) {
+				if beneficiary != cheque.Beneficiary {
+					t.Fatalf("querying paid out for wrong beneficiary. wanted %x, got %x", cheque.Beneficiary, beneficiary)
+				}
+				return paidOut, nil
+			},
+		}),
+		withBackend(
+			backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+				return nil, true, nil
+			}),
+		),
+		withSendFunc(func(c context.Context, request *transaction.TxRequest) (common.Hash, error) {
+			args, err := chequebookABI.Methods["cashChequeBeneficiary"].Inputs.UnpackValues(request.Data[4:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the chequebook only pays out if the issuer signed the packed payout
+			packed := &chequebook.SignedCheque{
+				Cheque: chequebook.Cheque{
+					Beneficiary:      cheque.Beneficiary,
+					CumulativePayout: args[1].(*big.Int),
+					Chequebook:       chequebookAddress,
+				},
+				Signature: args[2].([]byte),
+			}
+			signer, err := chequebook.RecoverCheque(packed, chainID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(signer.Bytes(), issuer) {
+				t.Fatalf("packed cumulative payout %d not signed by the issuer", packed.CumulativePayout)
+			}
+			sent++
+			return txHash, nil
+		}),
+		withLastCheque(cheque),
+	)
+
+	for _, tc := range []struct {
+		amount *big.Int
+		err    error
+	}{
+		{amount: big.NewInt(401), err: chequebook.ErrInvalidCashoutAmount},
+		{amount: big.NewInt(0), err: chequebook.ErrInvalidCashoutAmount},
+		// a cheque with a lower cumulative payout would have to be signed
+		{amount: big.NewInt(150), err: chequebook.ErrPartialCashoutUnsigned},
+	} {
+		_, err = cashoutService.CashChequeAmount(context.Background(), chequebookAddress, recipientAddress, tc.amount)
+		if !errors.Is(err, tc.err) {
+			t.Fatalf("amount %d: got error %v, want %v", tc.amount, err, tc.err)
+		}
+	}
+
+	results, err := cashoutService.CashCheques(context.Background(), []chequebook.CashRequest{
+		{Chequebook: chequebookAddress, Recipient: recipientAddress, Amount: big.NewInt(150)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(results[0].Err, chequebook.ErrPartialCashoutUnsigned) {
+		t.Fatalf("got error %v, want %v", results[0].Err, chequebook.ErrPartialCashoutUnsigned)
+	}
+
+	if sent != 0 {
+		t.Fatalf("sent %d transactions for invalid amounts", sent)
+	}
+
+	returnedTxHash, err := cashoutService.CashChequeAmount(context.Background(), chequebookAddress, recipientAddress, uncashed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if returnedTxHash != txHash {
+		t.Fatalf("returned wrong transaction hash. wanted %v, got %v", txHash, returnedTxHash)
+	}
+	if sent != 1 {
+		t.Fatalf("sent %d transactions, wanted 1", sent)
+	}
+
+	status, err := cashoutService.CashoutStatus(context.Background(), chequebookAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Amount == nil || status.Amount.Cmp(uncashed) != 0 {
+		t.Fatalf("wrong amount. wanted %d, got %v", uncashed, status.Amount)
+	}
+	if status.Outstanding.Sign() != 0 {
+		t.Fatalf("wrong outstanding amount. wanted 0, got %d", status.Outstanding)
+	}
+}
+
+func TestCashoutNotifyCashed(t *testing.T) {
+	cashedChequebook := common.HexToAddress("abcd")
+	bouncedChequebook := common.HexToAddress("bcde")
+	beneficiary := common.HexToAddress("aaaa")
+	recipientAddress := common.HexToAddress("efff")
+	totalPayout := big.NewInt(100)
+	bouncedTopic := common.HexToHash("eeee")
+
+	cashed := chequeCashed(beneficiary, recipientAddress, totalPayout, totalPayout)
+	cashoutService := newTestCashoutService(t,
+		withBinding(&simpleSwapBindingMock{
+			paidOut: noPaidOut,
+			parseChequeCashed: func(l types.Log) (*simpleswapfactory.ERC20SimpleSwapChequeCashed, error) {
+				if len(l.Topics) > 0 && l.Topics[0] == bouncedTopic {
+					return nil, errors.New("not cashed")
+				}
+				return cashed(l)
+			},
+			parseChequeBounced: func(l types.Log) (*simpleswapfactory.ERC20SimpleSwapChequeBounced, error) {
+				if len(l.Topics) == 0 || l.Topics[0] != bouncedTopic {
+					return nil, errors.New("not bounced")
+				}
+				return &simpleswapfactory.ERC20SimpleSwapChequeBounced{}, nil
+			},
+		}),
+		withBackend(
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				chequebookAddress := common.BytesToAddress(hash.Bytes())
+				receipt := cashedReceipt(chequebookAddress)
+				if chequebookAddress == bouncedChequebook {
+					// the bounce event is emitted next to the cashed event
+					receipt.Logs = append(receipt.Logs, &types.Log{
+						Address: chequebookAddress,
+						Topics:  []common.Hash{bouncedTopic},
+					})
+				}
+				return receipt, nil
+			}),
+		),
+		withSendFunc(sendToChequebookHash),
+		withChequeStore(chequestoremock.WithLastChequeFunc(func(c common.Address) (*chequebook.SignedCheque, error) {
+			return &chequebook.SignedCheque{
+				Cheque: chequebook.Cheque{
+					Beneficiary:      beneficiary,
+					CumulativePayout: totalPayout,
+					Chequebook:       c,
+				},
+				Signature: []byte{},
+			}, nil
+		})),
+		withCashoutOptions(chequebook.WithReceiptPollInterval(10*time.Millisecond)),
+	)
+
+	notified := make(chan common.Address, 2)
+	cashoutService.SetNotifyCashedFunc(func(c common.Address, result *chequebook.CashChequeResult) error {
+		if result.TotalPayout.Cmp(totalPayout) != 0 {
+			t.Errorf("wrong total payout. wanted %d, got %d", totalPayout, result.TotalPayout)
+		}
+		notified <- c
+		// errors of the notification must not prevent the result from being stored
+		return errors.New("notification failed")
+	})
+
+	for _, c := range []common.Address{cashedChequebook, bouncedChequebook} {
+		if _, err := cashoutService.CashCheque(context.Background(), c, recipientAddress); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := cashoutService.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cashoutService.Close()
+
+	select {
+	case c := <-notified:
+		if c != cashedChequebook {
+			t.Fatalf("notified for wrong chequebook. wanted %x, got %x", cashedChequebook, c)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notification for cashed cheque")
+	}
+
+	for _, c := range []common.Address{cashedChequebook, bouncedChequebook} {
+		status := waitForResult(t, cashoutService, c)
+		if status.Result == nil {
+			t.Fatalf("missing result for chequebook %x", c)
+		}
+		if status.Result.Bounced != (c == bouncedChequebook) {
+			t.Fatalf("wrong bounced flag for chequebook %x", c)
+		}
+	}
+
+	select {
+	case c := <-notified:
+		t.Fatalf("notified for bounced chequebook %x", c)
+	default:
+	}
+}
+
+func TestCashoutBindingCached(t *testing.T) {
+	chequebookAddresses := []common.Address{common.HexToAddress("1111"), common.HexToAddress("2222")}
+	recipientAddress := common.HexToAddress("efff")
+
+	var mu sync.Mutex
+	created := make(map[common.Address]int)
+
+	cashoutService := newTestCashoutService(t,
+		withBindingFunc(func(c common.Address, _ bind.ContractBackend) (chequebook.SimpleSwapBinding, error) {
+			mu.Lock()
+			created[c]++
+			mu.Unlock()
+			return &simpleSwapBindingMock{
+				paidOut: noPaidOut,
+			}, nil
+		}),
+		withSendFunc(sendToChequebookHash),
+		withChequeStore(chequestoremock.WithLastChequeFunc(func(c common.Address) (*chequebook.SignedCheque, error) {
+			return &chequebook.SignedCheque{
+				Cheque: chequebook.Cheque{
+					Beneficiary:      common.HexToAddress("aaaa"),
+					CumulativePayout: big.NewInt(500),
+					Chequebook:       c,
+				},
+				Signature: []byte{},
+			}, nil
+		})),
+	)
+
+	for _, c := range chequebookAddresses {
+		for i := 0; i < 3; i++ {
+			if _, err := cashoutService.UncashedAmount(context.Background(), c); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := cashoutService.CashCheque(context.Background(), c, recipientAddress); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(created) != len(chequebookAddresses) {
+		t.Fatalf("got bindings for %d chequebooks, want %d", len(created), len(chequebookAddresses))
+	}
+	for c, n := range created {
+		if n != 1 {
+			t.Fatalf("got binding for chequebook %x created %d times, want once", c, n)
+		}
+	}
+}
+
+func TestTotalCashedOut(t *testing.T) {
+	chequebookAddress := common.HexToAddress("abcd")
+
+	store := storemock.NewStateStore()
+	cashoutService := newTestCashoutService(t, withStore(store))
+
+	total, err := cashoutService.TotalCashedOut(chequebookAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total.Sign() != 0 {
+		t.Fatalf("got total %d without cashouts, want 0", total)
+	}
+
+	for seq, action := range []map[string]interface{}{
+		{
+			"TxHash": common.HexToHash("1111"),
+			"Result": &chequebook.CashChequeResult{
+				TotalPayout:      big.NewInt(100),
+				CumulativePayout: big.NewInt(100),
+				CallerPayout:     big.NewInt(0),
+			},
+		},
+		{
+			"TxHash": common.HexToHash("2222"),
+			"Result": &chequebook.CashChequeResult{
+				TotalPayout:      big.NewInt(50),
+				CumulativePayout: big.NewInt(200),
+				CallerPayout:     big.NewInt(0),
+				Bounced:          true,
+			},
+		},
+		{
+			"TxHash":   common.HexToHash("3333"),
+			"Reverted": true,
+		},
+		{
+			"TxHash": common.HexToHash("4444"),
+			"Result": &chequebook.CashChequeResult{
+				TotalPayout:      big.NewInt(200),
+				CumulativePayout: big.NewInt(300),
+				CallerPayout:     big.NewInt(0),
+			},
+		},
+		{
+			"TxHash": common.HexToHash("5555"),
+		},
+	} {
+		err := store.Put(fmt.Sprintf("cashout_%x_%d", chequebookAddress, seq+1), action)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = store.Put(fmt.Sprintf("cashoutsequence_%x", chequebookAddress), uint64(seq+1))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	total, err = cashoutService.TotalCashedOut(chequebookAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total.Cmp(big.NewInt(300)) != 0 {
+		t.Fatalf("got total %d, want 300", total)
+	}
+
+	total, err = cashoutService.TotalCashedOut(common.HexToAddress("bcde"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total.Sign() != 0 {
+		t.Fatalf("got total %d for another chequebook, want 0", total)
+	}
+}
+
+func TestCashoutHistory(t *testing.T) {
+	chequebookAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	totalPayout := big.NewInt(100)
+
+	cheque := &chequebook.SignedCheque{
+		Cheque: chequebook.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: totalPayout,
+			Chequebook:       chequebookAddress,
+		},
+		Signature: []byte{},
+	}
+
+	store := storemock.NewStateStore()
+
+	var (
+		mined   bool
+		minedMu sync.Mutex
+	)
+	newService := func() chequebook.CashoutService {
+		sent := int64(0)
+		return newTestCashoutService(t,
+			withStore(store),
+			withBinding(&simpleSwapBindingMock{
+				paidOut:           noPaidOut,
+				parseChequeCashed: chequeCashed(cheque.Beneficiary, recipientAddress, totalPayout, totalPayout),
+			}),
+			withBackend(
+				backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+					return nil, true, nil
+				}),
+				backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+					minedMu.Lock()
+					defer minedMu.Unlock()
+					if !mined {
+						return nil, errors.New("not mined")
+					}
+					return cashedReceipt(chequebookAddress), nil
+				}),
+			),
+			withSendFunc(func(c context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				sent++
+				return common.BigToHash(big.NewInt(sent)), nil
+			}),
+			withLastCheque(cheque),
+			withCashoutOptions(
+				chequebook.WithCashoutHistoryLimit(3),
+				chequebook.WithReceiptPollInterval(10*time.Millisecond),
+			),
+		)
+	}
+
+	cashoutService := newService()
+	for i := 0; i < 5; i++ {
+		if _, err := cashoutService.CashCheque(context.Background(), chequebookAddress, recipientAddress); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history, err := cashoutService.CashoutHistory(context.Background(), chequebookAddress, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("got %d history entries, want 3", len(history))
+	}
+	for i, status := range history {
+		// newest first, the two oldest actions were dropped
+		want := common.BigToHash(big.NewInt(int64(5 - i)))
+		if status.TxHash != want {
+			t.Fatalf("entry %d: got transaction %v, want %v", i, status.TxHash, want)
+		}
+		if status.Result != nil {
+			t.Fatalf("entry %d: got result for unmined transaction", i)
+		}
+	}
+
+	history, err = cashoutService.CashoutHistory(context.Background(), chequebookAddress, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("got %d history entries, want 2", len(history))
+	}
+
+	// simulate a restart after which all unconfirmed actions are monitored
+	minedMu.Lock()
+	mined = true
+	minedMu.Unlock()
+
+	cashoutService = newService()
+	if err := cashoutService.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cashoutService.Close()
+
+	for i := 0; i < 100; i++ {
+		history, err = cashoutService.CashoutHistory(context.Background(), chequebookAddress, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resolved := 0
+		for _, status := range history {
+			if status.Result != nil {
+				resolved++
+			}
+		}
+		if resolved == len(history) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("history not resolved after restart")
+}
+
+func TestCashoutWithOptions(t *testing.T) {
+	chequebookAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+	gasPrice := big.NewInt(20)
+	gasLimit := uint64(100000)
+
+	cheque := &chequebook.SignedCheque{
+		Cheque: chequebook.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Chequebook:       chequebookAddress,
+		},
+		Signature: []byte{},
+	}
+
+	var requests []*transaction.TxRequest
+	cashoutService := newTestCashoutService(t,
+		withSendFunc(func(c context.Context, request *transaction.TxRequest) (common.Hash, error) {
+			requests = append(requests, request)
+			return txHash, nil
+		}),
+		withLastCheque(cheque),
+	)
+
+	_, err := cashoutService.CashChequeWithOptions(context.Background(), chequebookAddress, recipientAddress, chequebook.CashoutTxOptions{
+		GasPrice: gasPrice,
+		GasLimit: &gasLimit,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cashoutService.CashChequeWithOptions(context.Background(), chequebookAddress, recipientAddress, chequebook.CashoutTxOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("sent %d transactions, want 2", len(requests))
+	}
+	if requests[0].GasPrice == nil || requests[0].GasPrice.Cmp(gasPrice) != 0 {
+		t.Fatalf("wrong gas price. wanted %d, got %v", gasPrice, requests[0].GasPrice)
+	}
+	if requests[0].GasLimit != gasLimit {
+		t.Fatalf("wrong gas limit. wanted %d, got %d", gasLimit, requests[0].GasLimit)
+	}
+	if requests[1].GasPrice != nil {
+		t.Fatalf("got gas price %d, wanted suggested gas price", requests[1].GasPrice)
+	}
+	if requests[1].GasLimit != 0 {
+		t.Fatalf("got gas limit %d, wanted estimated gas limit", requests[1].GasLimit)
+	}
+}
+
+func TestCashoutRebroadcast(t *testing.T) {
+	chequebookAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	stuckTxHash := common.HexToHash("dddd")
+	replacementTxHash := common.HexToHash("eeee")
+	totalPayout := big.NewInt(100)
+	nonce := uint64(7)
+
+	cheque := &chequebook.SignedCheque{
+		Cheque: chequebook.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: totalPayout,
+			Chequebook:       chequebookAddress,
+		},
+		Signature: []byte{},
+	}
+
+	var (
+		mu       sync.Mutex
+		requests []*transaction.TxRequest
+	)
+	cashoutService := newTestCashoutService(t,
+		withBinding(&simpleSwapBindingMock{
+			paidOut:           noPaidOut,
+			parseChequeCashed: chequeCashed(cheque.Beneficiary, recipientAddress, totalPayout, totalPayout),
+		}),
+		withBackend(
+			backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				request := requests[len(requests)-1]
+				return types.NewTransaction(nonce, chequebookAddress, big.NewInt(0), 100000, big.NewInt(100), request.Data), true, nil
+			}),
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				if hash != replacementTxHash {
+					return nil, errors.New("not mined")
+				}
+				return cashedReceipt(chequebookAddress), nil
+			}),
+		),
+		withSendFunc(func(c context.Context, request *transaction.TxRequest) (common.Hash, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			requests = append(requests, request)
+			if len(requests) == 1 {
+				return stuckTxHash, nil
+			}
+			return replacementTxHash, nil
+		}),
+		withLastCheque(cheque),
+		withCashoutOptions(
+			chequebook.WithRebroadcast(time.Nanosecond, 10),
+			chequebook.WithReceiptPollInterval(10*time.Millisecond),
+		),
+	)
+
+	if err := cashoutService.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cashoutService.Close()
+
+	txHash, err := cashoutService.CashCheque(context.Background(), chequebookAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txHash != stuckTxHash {
+		t.Fatalf("returned wrong transaction hash. wanted %v, got %v", stuckTxHash, txHash)
+	}
+
+	var status *chequebook.CashoutStatus
+	for i := 0; i < 500; i++ {
+		status, err = cashoutService.CashoutStatus(context.Background(), chequebookAddress)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.Result != nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if status.Result == nil {
+		t.Fatal("replacement transaction not resolved")
+	}
+	if status.TxHash != replacementTxHash {
+		t.Fatalf("status tracks wrong transaction. wanted %v, got %v", replacementTxHash, status.TxHash)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != 2 {
+		t.Fatalf("sent %d transactions, want 2", len(requests))
+	}
+	replacement := requests[1]
+	if replacement.Nonce == nil || *replacement.Nonce != nonce {
+		t.Fatalf("replacement uses wrong nonce. wanted %d, got %v", nonce, replacement.Nonce)
+	}
+	if replacement.GasPrice.Cmp(big.NewInt(110)) != 0 {
+		t.Fatalf("replacement uses wrong gas price. wanted 110, got %d", replacement.GasPrice)
+	}
+	if !bytes.Equal(replacement.Data, requests[0].Data) {
+		t.Fatal("replacement uses different call data")
+	}
+}
+
+func TestCashoutNothingToCash(t *testing.T) {
+	chequebookAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	cumulativePayout := big.NewInt(500)
+
+	cheque := &chequebook.SignedCheque{
+		Cheque: chequebook.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: cumulativePayout,
+			Chequebook:       chequebookAddress,
+		},
+		Signature: []byte{},
+	}
+
+	cashoutService := newTestCashoutService(t,
+		withBinding(&simpleSwapBindingMock{
+			paidOut: func(*bind.CallOpts, common.Address) (*big.Int, error) {
+				return cumulativePayout, nil
+			},
+		}),
+		withSendFunc(func(c context.Context, request *transaction.TxRequest) (common.Hash, error) {
+			t.Fatal("sent transaction for fully cashed cheque")
+			return common.Hash{}, nil
+		}),
+		withLastCheque(cheque),
+	)
+
+	_, err := cashoutService.CashCheque(context.Background(), chequebookAddress, recipientAddress)
+	if !errors.Is(err, chequebook.ErrNothingToCash) {
+		t.Fatalf("got error %v, want %v", err, chequebook.ErrNothingToCash)
+	}
+
+	_, err = cashoutService.CashoutStatus(context.Background(), chequebookAddress)
+	if !errors.Is(err, chequebook.ErrNoCashout) {
+		t.Fatalf("got error %v, want %v", err, chequebook.ErrNoCashout)
+	}
+}
+
+func TestCashoutSupersededNoMonitorLeak(t *testing.T) {
+	chequebookAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+
+	cheque := &chequebook.SignedCheque{
+		Cheque: chequebook.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Chequebook:       chequebookAddress,
+		},
+		Signature: []byte{},
+	}
+
+	sent := int64(0)
+	cashoutService := newTestCashoutService(t,
+		withBackend(
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return nil, errors.New("not mined")
+			}),
+		),
+		withSendFunc(func(c context.Context, request *transaction.TxRequest) (common.Hash, error) {
+			sent++
+			return common.BigToHash(big.NewInt(sent)), nil
+		}),
+		withLastCheque(cheque),
+		withCashoutOptions(chequebook.WithReceiptPollInterval(10*time.Millisecond)),
+	)
+
+	if err := cashoutService.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cashoutService.Close()
+
+	goroutines := runtime.NumGoroutine()
+
+	// superseding cashouts are monitored by the single receipt poller, so
+	// no goroutine is left behind for the stale transactions
+	for i := 0; i < 20; i++ {
+		if _, err := cashoutService.CashCheque(context.Background(), chequebookAddress, recipientAddress); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := runtime.NumGoroutine(); n > goroutines {
+		t.Fatalf("got %d goroutines after superseding cashouts, want at most %d", n, goroutines)
+	}
+}
+
+func TestCashoutGasCost(t *testing.T) {
+	chequebookAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+	gasPrice := big.NewInt(20)
+	gasUsed := uint64(50000)
+
+	cheque := &chequebook.SignedCheque{
+		Cheque: chequebook.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Chequebook:       chequebookAddress,
+		},
+		Signature: []byte{},
+	}
+
+	cashoutService := newTestCashoutService(t,
+		withBinding(&simpleSwapBindingMock{
+			paidOut:           noPaidOut,
+			parseChequeCashed: chequeCashed(cheque.Beneficiary, recipientAddress, cheque.CumulativePayout, cheque.CumulativePayout),
+		}),
+		withBackend(
+			backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+				return types.NewTransaction(0, chequebookAddress, big.NewInt(0), 100000, gasPrice, nil), false, nil
+			}),
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				receipt := cashedReceipt(chequebookAddress)
+				receipt.GasUsed = gasUsed
+				return receipt, nil
+			}),
+		),
+		withSendFunc(func(c context.Context, request *transaction.TxRequest) (common.Hash, error) {
+			return txHash, nil
+		}),
+		withLastCheque(cheque),
+		withCashoutOptions(chequebook.WithReceiptPollInterval(10*time.Millisecond)),
+	)
+
+	if _, err := cashoutService.CashCheque(context.Background(), chequebookAddress, recipientAddress); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedGasCost := big.NewInt(0).Mul(gasPrice, big.NewInt(int64(gasUsed)))
+
+	// before the poller ran the cost is computed from the live receipt
+	status, err := cashoutService.CashoutStatus(context.Background(), chequebookAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.GasCost == nil || status.GasCost.Cmp(expectedGasCost) != 0 {
+		t.Fatalf("wrong gas cost. wanted %d, got %v", expectedGasCost, status.GasCost)
+	}
+
+	if err := cashoutService.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cashoutService.Close()
+
+	// the cost remains available once the poller stored the outcome
+	for i := 0; i < 100; i++ {
+		history, err := cashoutService.CashoutHistory(context.Background(), chequebookAddress, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		status = history[0]
+		if status.Result != nil && status.GasCost != nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if status.GasCost == nil || status.GasCost.Cmp(expectedGasCost) != 0 {
+		t.Fatalf("wrong stored gas cost. wanted %d, got %v", expectedGasCost, status.GasCost)
+	}
+}
+
+func TestCashCheques(t *testing.T) {
+	recipientAddress := common.HexToAddress("efff")
+	chequebookAddresses := []common.Address{
+		common.HexToAddress("abcd"),
+		common.HexToAddress("bcde"),
+		common.HexToAddress("cdef"),
+	}
+	// the last chequebook has no cheque
+	withoutCheque := chequebookAddresses[2]
+
+	var (
+		sentMu sync.Mutex
+		sent   = make(map[common.Address]int)
+	)
+
+	cashoutService := newTestCashoutService(t,
+		withSendFunc(func(c context.Context, request *transaction.TxRequest) (common.Hash, error) {
+			sentMu.Lock()
+			defer sentMu.Unlock()
+			sent[request.To]++
+			return sendToChequebookHash(c, request)
+		}),
+		withChequeStore(chequestoremock.WithLastChequeFunc(func(c common.Address) (*chequebook.SignedCheque, error) {
+			if c == withoutCheque {
+				return nil, chequebook.ErrNoCheque
+			}
+			return &chequebook.SignedCheque{
+				Cheque: chequebook.Cheque{
+					Beneficiary:      common.HexToAddress("aaaa"),
+					CumulativePayout: big.NewInt(500),
+					Chequebook:       c,
+				},
+				Signature: []byte{},
+			}, nil
+		})),
+	)
+
+	var requests []chequebook.CashRequest
+	for _, c := range chequebookAddresses {
+		requests = append(requests, chequebook.CashRequest{
+			Chequebook: c,
+			Recipient:  recipientAddress,
+		})
+	}
+
+	results, err := cashoutService.CashCheques(context.Background(), requests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(requests) {
+		t.Fatalf("got %d results, want %d", len(results), len(requests))
+	}
+
+	for i, r := range results {
+		c := chequebookAddresses[i]
+		if r.Chequebook != c {
+			t.Fatalf("result %d: got chequebook %x, want %x", i, r.Chequebook, c)
+		}
+
+		if c == withoutCheque {
+			if !errors.Is(r.Err, chequebook.ErrNoCheque) {
+				t.Fatalf("result %d: got error %v, want %v", i, r.Err, chequebook.ErrNoCheque)
+			}
+			continue
+		}
+
+		if r.Err != nil {
+			t.Fatalf("result %d: %v", i, r.Err)
+		}
+		if want := common.BytesToHash(c.Bytes()); r.TxHash != want {
+			t.Fatalf("result %d: got transaction hash %x, want %x", i, r.TxHash, want)
+		}
+		if sent[c] != 1 {
+			t.Fatalf("result %d: got %d transactions sent, want 1", i, sent[c])
+		}
+
+		status, err := cashoutService.CashoutStatus(context.Background(), c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.TxHash != r.TxHash {
+			t.Fatalf("result %d: got stored transaction hash %x, want %x", i, status.TxHash, r.TxHash)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, err = cashoutService.CashCheques(ctx, requests)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want %v", err, context.Canceled)
+	}
+	for i, r := range results {
+		if !errors.Is(r.Err, context.Canceled) {
+			t.Fatalf("result %d: got error %v, want %v", i, r.Err, context.Canceled)
+		}
+	}
+	for _, c := range chequebookAddresses {
+		if sent[c] > 1 {
+			t.Fatalf("sent transaction for chequebook %x after cancellation", c)
+		}
+	}
+}
+
+func TestCashoutMinProfit(t *testing.T) {
+	chequebookAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	beneficiary := common.HexToAddress("aaaa")
+	txHash := common.HexToHash("dddd")
+	gasLimit := uint64(50)
+
+	cheque := &chequebook.SignedCheque{
+		Cheque: chequebook.Cheque{
+			Beneficiary:      beneficiary,
+			CumulativePayout: big.NewInt(500),
+			Chequebook:       chequebookAddress,
+		},
+		Signature: []byte{},
+	}
+
+	sent := false
+	cashoutService := newTestCashoutService(t,
+		withBinding(&simpleSwapBindingMock{
+			paidOut: func(*bind.CallOpts, common.Address) (*big.Int, error) {
+				return big.NewInt(400), nil
+			},
+		}),
+		withBackend(
+			backendmock.WithEstimateGasFunc(func(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+				if call.From != beneficiary {
+					t.Fatalf("estimating gas from wrong address. wanted %v, got %v", beneficiary, call.From)
+				}
+				return gasLimit, nil
+			}),
+			backendmock.WithSuggestGasPriceFunc(func(ctx context.Context) (*big.Int, error) {
+				return big.NewInt(2), nil
+			}),
+		),
+		withSendFunc(func(c context.Context, request *transaction.TxRequest) (common.Hash, error) {
+			sent = true
+			return txHash, nil
+		}),
+		withLastCheque(cheque),
+		withCashoutOptions(chequebook.WithMinProfit(big.NewInt(1))),
+	)
+
+	// uncashed 100 at the suggested gas price costs 2 * 50
+	_, err := cashoutService.CashCheque(context.Background(), chequebookAddress, recipientAddress)
+	if !errors.Is(err, chequebook.ErrUnprofitable) {
+		t.Fatalf("got error %v, want %v", err, chequebook.ErrUnprofitable)
+	}
+	var unprofitable *chequebook.UnprofitableError
+	if !errors.As(err, &unprofitable) {
+		t.Fatalf("got error %T, want %T", err, unprofitable)
+	}
+	if unprofitable.Estimate.GasCost.Cmp(big.NewInt(100)) != 0 {
+		t.Fatalf("got estimated gas cost %d, want 100", unprofitable.Estimate.GasCost)
+	}
+	if unprofitable.Estimate.NetProfit.Sign() != 0 {
+		t.Fatalf("got estimated profit %d, want 0", unprofitable.Estimate.NetProfit)
+	}
+	if sent {
+		t.Fatal("sent unprofitable cashout")
+	}
+
+	// at a gas price of 1 the cashout makes a profit of 50
+	returnedTxHash, err := cashoutService.CashChequeWithOptions(context.Background(), chequebookAddress, recipientAddress, chequebook.CashoutTxOptions{
+		GasPrice: big.NewInt(1),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if returnedTxHash != txHash {
+		t.Fatalf("returned wrong transaction hash. wanted %v, got %v", txHash, returnedTxHash)
+	}
+	if !sent {
+		t.Fatal("profitable cashout not sent")
+	}
+}
+
+func TestUncashedAmount(t *testing.T) {
+	chequebookAddress := common.HexToAddress("abcd")
+	otherChequebookAddress := common.HexToAddress("bcde")
+	paidOut := big.NewInt(100)
+	cumulativePayout := big.NewInt(500)
+
+	cheque := &chequebook.SignedCheque{
+		Cheque: chequebook.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: cumulativePayout,
+			Chequebook:       chequebookAddress,
+		},
+		Signature: []byte{},
+	}
+
+	cashoutService := newTestCashoutService(t,
+		withBinding(&simpleSwapBindingMock{
+			paidOut: func(o *bind.CallOpts, b common.Address) (*big.Int, error) {
+				if b != cheque.Beneficiary {
+					t.Fatalf("querying paidOut for wrong beneficiary. wanted %v, got %v", cheque.Beneficiary, b)
+				}
+				return paidOut, nil
+			},
+		}),
+		withChequeStore(chequestoremock.WithLastChequeFunc(func(c common.Address) (*chequebook.SignedCheque, error) {
+			if c != chequebookAddress {
+				return nil, chequebook.ErrNoCheque
+			}
+			return cheque, nil
+		})),
+	)
+
+	uncashed, err := cashoutService.UncashedAmount(context.Background(), chequebookAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := big.NewInt(0).Sub(cumulativePayout, paidOut)
+	if uncashed.Cmp(expected) != 0 {
+		t.Fatalf("got uncashed amount %v, want %v", uncashed, expected)
+	}
+
+	_, err = cashoutService.UncashedAmount(context.Background(), otherChequebookAddress)
+	if !errors.Is(err, chequebook.ErrNoCheque) {
+		t.Fatalf("got error %v, want %v", err, chequebook.ErrNoCheque)
+	}
+}
+
+func TestCashoutNoChequeCashedEvent(t *testing.T) {
+	chequebookAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &chequebook.SignedCheque{
+		Cheque: chequebook.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Chequebook:       chequebookAddress,
+		},
+		Signature: []byte{},
+	}
+
+	store := storemock.NewStateStore()
+	binding := &simpleSwapBindingMock{
+		paidOut: noPaidOut,
+		parseChequeCashed: func(l types.Log) (*simpleswapfactory.ERC20SimpleSwapChequeCashed, error) {
+			return nil, errors.New("not cashed")
+		},
+		parseChequeBounced: func(l types.Log) (*simpleswapfactory.ERC20SimpleSwapChequeBounced, error) {
+			return nil, errors.New("not bounced")
+		},
+	}
+
+	var (
+		mu       sync.Mutex
+		receipts int
+	)
+	newService := func() chequebook.CashoutService {
+		return newTestCashoutService(t,
+			withStore(store),
+			withBinding(binding),
+			withBackend(
+				backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+					return nil, false, nil
+				}),
+				backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+					mu.Lock()
+					receipts++
+					mu.Unlock()
+					return &types.Receipt{
+						Status: types.ReceiptStatusSuccessful,
+						TxHash: hash,
+						Logs: []*types.Log{
+							{
+								// a transfer event of the token
+								Address: common.HexToAddress("1234"),
+							},
+							{
+								// an unrelated event of the chequebook
+								Address: chequebookAddress,
+							},
+						},
+					}, nil
+				}),
+			),
+			withSendFunc(func(c context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				return txHash, nil
+			}),
+			withLastCheque(cheque),
+			withCashoutOptions(chequebook.WithReceiptPollInterval(10*time.Millisecond)),
+		)
+	}
+
+	cashoutService := newService()
+	cashoutService.SetCashoutCooldown(time.Hour)
+
+	_, err := cashoutService.CashCheque(context.Background(), chequebookAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := cashoutService.CashoutStatus(context.Background(), chequebookAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Failed == "" || status.Result != nil || status.Reverted {
+		t.Fatalf("got status %+v, want a failed cashout", status)
+	}
+
+	if err := cashoutService.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	collectors := cashoutService.(interface {
+		Metrics() []prometheus.Collector
+	}).Metrics()
+	failed := func() float64 {
+		for _, c := range collectors {
+			metric := c.(prometheus.Metric)
+			if !strings.Contains(metric.Desc().String(), "cashouts_failed") {
+				continue
+			}
+			var m dto.Metric
+			if err := metric.Write(&m); err != nil {
+				t.Fatal(err)
+			}
+			return m.GetCounter().GetValue()
+		}
+		t.Fatal("no failed cashouts metric")
+		return 0
+	}
+	for i := 0; i < 100 && failed() == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := failed(); got != 1 {
+		t.Fatalf("got %v failed cashouts, want 1", got)
+	}
+
+	if err := cashoutService.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	receipts = 0
+	mu.Unlock()
+
+	// the failure is final, so the cashout is not resumed after a restart
+	cashoutService = newService()
+	if err := cashoutService.Start(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := cashoutService.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err = cashoutService.CashoutStatus(context.Background(), chequebookAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Failed == "" {
+		t.Fatalf("got status %+v, want a failed cashout", status)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if receipts != 0 {
+		t.Fatalf("failed cashout was resumed and its receipt queried %d times", receipts)
+	}
+
+	// nothing was cashed, so the failed cashout does not start a cooldown
+	cashoutService.SetCashoutCooldown(time.Hour)
+	_, err = cashoutService.CashCheque(context.Background(), chequebookAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCashChequeResultEqualNilAmounts(t *testing.T) {
+	result := &chequebook.CashChequeResult{}
+
+	if !result.Equal(&chequebook.CashChequeResult{
+		TotalPayout:      big.NewInt(0),
+		CumulativePayout: big.NewInt(0),
+		CallerPayout:     big.NewInt(0),
+	}) {
+		t.Fatal("nil amounts not equal to zero")
+	}
+
+	if result.Equal(&chequebook.CashChequeResult{TotalPayout: big.NewInt(1)}) {
+		t.Fatal("nil amount equal to non-zero")
+	}
+}
+
+func TestCashoutStatusWithConfirmations(t *testing.T) {
+	chequebookAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+	totalPayout := big.NewInt(500)
+
+	cheque := &chequebook.SignedCheque{
+		Cheque: chequebook.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: totalPayout,
+			Chequebook:       chequebookAddress,
+		},
+		Signature: []byte{},
+	}
+
+	pending := true
+	cashoutService := newTestCashoutService(t,
+		withBinding(&simpleSwapBindingMock{
+			paidOut:           noPaidOut,
+			parseChequeCashed: chequeCashed(cheque.Beneficiary, recipientAddress, totalPayout, totalPayout),
+		}),
+		withBackend(
+			backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+				return nil, pending, nil
+			}),
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				receipt := cashedReceipt(chequebookAddress)
+				receipt.BlockNumber = big.NewInt(100)
+				return receipt, nil
+			}),
+			backendmock.WithBlockNumberFunc(func(ctx context.Context) (uint64, error) {
+				if pending {
+					t.Fatal("queried block number for pending cashout")
+				}
+				return 111, nil
+			}),
+		),
+		withSendFunc(func(c context.Context, request *transaction.TxRequest) (common.Hash, error) {
+			return txHash, nil
+		}),
+		withLastCheque(cheque),
+	)
+
+	_, err := cashoutService.CashCheque(context.Background(), chequebookAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := cashoutService.CashoutStatusWithConfirmations(context.Background(), chequebookAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Confirmations != 0 {
+		t.Fatalf("got %d confirmations for pending cashout, want 0", status.Confirmations)
+	}
+
+	pending = false
+	status, err = cashoutService.CashoutStatusWithConfirmations(context.Background(), chequebookAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Result == nil {
+		t.Fatal("missing result")
+	}
+	if status.Confirmations != 12 {
+		t.Fatalf("got %d confirmations, want 12", status.Confirmations)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2020 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package chequebook
+
+import (
+	"math/big"
+
+	m "github.com/ethersphere/bee/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type metrics struct {
+	// all metrics fields must be exported
+	// to be able to return them by Metrics()
+	// using reflection
+	CashoutsConfirmed prometheus.Counter
+	CashoutsBounced   prometheus.Counter
+	CashoutsReverted  prometheus.Counter
+	CashoutsFailed    prometheus.Counter
+	CallerPayout      prometheus.Histogram
+}
+
+func newMetrics() metrics {
+	subsystem := "chequebook"
+
+	return metrics{
+		CashoutsConfirmed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "cashouts_confirmed",
+			Help:      "Number of confirmed cashouts which did not bounce",
+		}),
+		CashoutsBounced: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "cashouts_bounced",
+			Help:      "Number of confirmed cashouts which bounced",
+		}),
+		CashoutsReverted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "cashouts_reverted",
+			Help:      "Number of reverted cashout transactions",
+		}),
+		CashoutsFailed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "cashouts_failed",
+			Help:      "Number of mined cashout transactions without a ChequeCashed event",
+		}),
+		CallerPayout: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "cashout_caller_payout",
+			Help:      "Histogram of the payout for the caller of confirmed cashouts",
+			Buckets:   prometheus.ExponentialBuckets(1, 10, 18),
+		}),
+	}
+}
+
+// recordOutcome counts the outcome of a mined cashout transaction.
+func (s *cashoutService) recordOutcome(action *cashoutAction) {
+	switch {
+	case action.Reverted:
+		s.metrics.CashoutsReverted.Inc()
+		return
+	case action.Failed != "":
+		s.metrics.CashoutsFailed.Inc()
+		return
+	case action.Result.Bounced:
+		s.metrics.CashoutsBounced.Inc()
+	default:
+		s.metrics.CashoutsConfirmed.Inc()
+	}
+
+	if action.Result.CallerPayout != nil {
+		payout, _ := new(big.Float).SetInt(action.Result.CallerPayout).Float64()
+		s.metrics.CallerPayout.Observe(payout)
+	}
+}
+
+func (s *cashoutService) Metrics() []prometheus.Collector {
+	return m.PrometheusCollectorsFromFields(s.metrics)
+}
@@ -76,6 +76,7 @@ func (m *addressbookMock) PutChequebook(peer swarm.Address, chequebook common.Ad
 }
 
 type cashoutMock struct {
+	chequebook.CashoutService
 	cashCheque    func(ctx context.Context, chequebook common.Address, recipient common.Address) (common.Hash, error)
 	cashoutStatus func(ctx context.Context, chequebookAddress common.Address) (*chequebook.CashoutStatus, error)
 }
@@ -17,12 +17,14 @@ import (
 
 type backendMock struct {
 	codeAt             func(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error)
+	callContract       func(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
 	sendTransaction    func(ctx context.Context, tx *types.Transaction) error
 	suggestGasPrice    func(ctx context.Context) (*big.Int, error)
 	estimateGas        func(ctx context.Context, call ethereum.CallMsg) (gas uint64, err error)
 	transactionReceipt func(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
 	pendingNonceAt     func(ctx context.Context, account common.Address) (uint64, error)
 	transactionByHash  func(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error)
+	blockNumber        func(ctx context.Context) (uint64, error)
 }
 
 func (m *backendMock) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
@@ -32,7 +34,10 @@ func (m *backendMock) CodeAt(ctx context.Context, contract common.Address, block
 	return nil, errors.New("not implemented")
 }
 
-func (*backendMock) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+func (m *backendMock) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if m.callContract != nil {
+		return m.callContract(ctx, call, blockNumber)
+	}
 	return nil, errors.New("not implemented")
 }
 
@@ -90,6 +95,13 @@ func (m *backendMock) TransactionByHash(ctx context.Context, hash common.Hash) (
 	return nil, false, errors.New("not implemented")
 }
 
+func (m *backendMock) BlockNumber(ctx context.Context) (uint64, error) {
+	if m.blockNumber != nil {
+		return m.blockNumber(ctx)
+	}
+	return 0, errors.New("not implemented")
+}
+
 func New(opts ...Option) transaction.Backend {
 	mock := new(backendMock)
 	for _, o := range opts {
@@ -113,6 +125,12 @@ func WithCodeAtFunc(f func(ctx context.Context, contract common.Address, blockNu
 	})
 }
 
+func WithCallContractFunc(f func(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)) Option {
+	return optionFunc(func(s *backendMock) {
+		s.callContract = f
+	})
+}
+
 func WithPendingNonceAtFunc(f func(ctx context.Context, account common.Address) (uint64, error)) Option {
 	return optionFunc(func(s *backendMock) {
 		s.pendingNonceAt = f
@@ -148,3 +166,9 @@ func WithSendTransactionFunc(f func(ctx context.Context, tx *types.Transaction)
 		s.sendTransaction = f
 	})
 }
+
+func WithBlockNumberFunc(f func(ctx context.Context) (uint64, error)) Option {
+	return optionFunc(func(s *backendMock) {
+		s.blockNumber = f
+	})
+}
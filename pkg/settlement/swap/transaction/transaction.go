@@ -28,6 +28,7 @@ type TxRequest struct {
 	GasPrice *big.Int       // gas price or nil if suggested gas price should be used
 	GasLimit uint64         // gas limit or 0 if it should be estimated
 	Value    *big.Int       // amount of wei to send
+	Nonce    *uint64        // nonce or nil if the pending nonce should be used, set to replace a pending transaction
 }
 
 // Service is the service to send transactions. It takes care of gas price, gas limit and nonce management.
@@ -61,7 +62,7 @@ func NewService(logger logging.Logger, backend Backend, signer crypto.Signer) (S
 
 // Send creates and signs a transaction based on the request and sends it.
 func (t *transactionService) Send(ctx context.Context, request *TxRequest) (txHash common.Hash, err error) {
-	tx, err := prepareTransaction(ctx, request, t.sender, t.backend)
+	tx, err := PrepareTransaction(ctx, request, t.sender, t.backend)
 	if err != nil {
 		return common.Hash{}, err
 	}
@@ -101,8 +102,9 @@ func (t *transactionService) WaitForReceipt(ctx context.Context, txHash common.H
 	}
 }
 
-// prepareTransaction creates a signable transaction based on a request.
-func prepareTransaction(ctx context.Context, request *TxRequest, from common.Address, backend Backend) (tx *types.Transaction, err error) {
+// PrepareTransaction creates a signable transaction based on a request. Gas
+// limit, gas price and nonce are taken from the backend unless set in the request.
+func PrepareTransaction(ctx context.Context, request *TxRequest, from common.Address, backend Backend) (tx *types.Transaction, err error) {
 	var gasLimit uint64
 	if request.GasLimit == 0 {
 		gasLimit, err = backend.EstimateGas(ctx, ethereum.CallMsg{
@@ -127,9 +129,14 @@ func prepareTransaction(ctx context.Context, request *TxRequest, from common.Add
 		gasPrice = request.GasPrice
 	}
 
-	nonce, err := backend.PendingNonceAt(ctx, from)
-	if err != nil {
-		return nil, err
+	var nonce uint64
+	if request.Nonce == nil {
+		nonce, err = backend.PendingNonceAt(ctx, from)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		nonce = *request.Nonce
 	}
 
 	return types.NewTransaction(
@@ -14,7 +14,10 @@ import (
 	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
-var _ storage.StateStorer = (*store)(nil)
+var (
+	_ storage.StateStorer      = (*store)(nil)
+	_ storage.StateBatchPutter = (*store)(nil)
+)
 
 // Store uses LevelDB to store values.
 type store struct {
@@ -54,18 +57,36 @@ func (s *store) Get(key string, i interface{}) error {
 // interface method will be called on the provided value
 // with fallback to JSON serialization.
 func (s *store) Put(key string, i interface{}) (err error) {
-	var bytes []byte
-	if marshaler, ok := i.(encoding.BinaryMarshaler); ok {
-		if bytes, err = marshaler.MarshalBinary(); err != nil {
-			return err
-		}
-	} else if bytes, err = json.Marshal(i); err != nil {
+	bytes, err := marshal(i)
+	if err != nil {
 		return err
 	}
 
 	return s.db.Put([]byte(key), bytes, nil)
 }
 
+// PutBatch stores all values in a single write. Values are serialized as in
+// Put and nothing is stored if any of them fails to serialize.
+func (s *store) PutBatch(values map[string]interface{}) (err error) {
+	batch := new(leveldb.Batch)
+	for key, i := range values {
+		bytes, err := marshal(i)
+		if err != nil {
+			return err
+		}
+		batch.Put([]byte(key), bytes)
+	}
+
+	return s.db.Write(batch, nil)
+}
+
+func marshal(i interface{}) ([]byte, error) {
+	if marshaler, ok := i.(encoding.BinaryMarshaler); ok {
+		return marshaler.MarshalBinary()
+	}
+	return json.Marshal(i)
+}
+
 // Delete removes entries stored under a specific key.
 func (s *store) Delete(key string) (err error) {
 	return s.db.Delete([]byte(key), nil)
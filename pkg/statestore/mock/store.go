@@ -13,7 +13,10 @@ import (
 	"github.com/ethersphere/bee/pkg/storage"
 )
 
-var _ storage.StateStorer = (*store)(nil)
+var (
+	_ storage.StateStorer      = (*store)(nil)
+	_ storage.StateBatchPutter = (*store)(nil)
+)
 
 type store struct {
 	store map[string][]byte
@@ -46,12 +49,8 @@ func (s *store) Put(key string, i interface{}) (err error) {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
 
-	var bytes []byte
-	if marshaler, ok := i.(encoding.BinaryMarshaler); ok {
-		if bytes, err = marshaler.MarshalBinary(); err != nil {
-			return err
-		}
-	} else if bytes, err = json.Marshal(i); err != nil {
+	bytes, err := marshal(i)
+	if err != nil {
 		return err
 	}
 
@@ -59,6 +58,30 @@ func (s *store) Put(key string, i interface{}) (err error) {
 	return nil
 }
 
+func (s *store) PutBatch(values map[string]interface{}) (err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	batch := make(map[string][]byte, len(values))
+	for key, i := range values {
+		if batch[key], err = marshal(i); err != nil {
+			return err
+		}
+	}
+
+	for key, bytes := range batch {
+		s.store[key] = bytes
+	}
+	return nil
+}
+
+func marshal(i interface{}) ([]byte, error) {
+	if marshaler, ok := i.(encoding.BinaryMarshaler); ok {
+		return marshaler.MarshalBinary()
+	}
+	return json.Marshal(i)
+}
+
 func (s *store) Delete(key string) (err error) {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
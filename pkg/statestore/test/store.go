@@ -85,6 +85,7 @@ func Run(t *testing.T, f func(t *testing.T) storage.StateStorer) {
 	t.Run("test_put_get", func(t *testing.T) { testPutGet(t, f) })
 	t.Run("test_delete", func(t *testing.T) { testDelete(t, f) })
 	t.Run("test_iterator", func(t *testing.T) { testIterator(t, f) })
+	t.Run("test_put_batch", func(t *testing.T) { testPutBatch(t, f) })
 }
 
 func testDelete(t *testing.T, f func(t *testing.T) storage.StateStorer) {
@@ -125,6 +126,39 @@ func testPutGet(t *testing.T, f func(t *testing.T) storage.StateStorer) {
 	testPersistedValues(t, store, key1, key2, value1, value2)
 }
 
+func testPutBatch(t *testing.T, f func(t *testing.T) storage.StateStorer) {
+	t.Helper()
+
+	// create a store
+	store := f(t)
+
+	batcher, ok := store.(storage.StateBatchPutter)
+	if !ok {
+		t.Skip("state store does not support batches")
+	}
+
+	// a value failing to serialize fails the whole batch
+	err := batcher.PutBatch(map[string]interface{}{
+		key1: value1,
+		key2: make(chan int),
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	testEmpty(t, store)
+
+	err = batcher.PutBatch(map[string]interface{}{
+		key1: value1,
+		key2: value2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// check that the persisted values match
+	testPersistedValues(t, store, key1, key2, value1, value2)
+}
+
 func testIterator(t *testing.T, f func(t *testing.T) storage.StateStorer) {
 	t.Helper()
 
@@ -178,5 +178,11 @@ type StateStorer interface {
 	io.Closer
 }
 
+// StateBatchPutter is implemented by state stores which can put multiple
+// values in a single write, so that either all or none of them are stored.
+type StateBatchPutter interface {
+	PutBatch(values map[string]interface{}) (err error)
+}
+
 // StateIterFunc is used when iterating through StateStorer key/value pairs
 type StateIterFunc func(key, value []byte) (stop bool, err error)
@@ -19,8 +19,10 @@ package tags
 import (
 	"context"
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -33,11 +35,25 @@ import (
 )
 
 var (
-	errExists = errors.New("already exists")
-	errNA     = errors.New("not available yet")
-	errNoETA  = errors.New("unable to calculate ETA")
+	errExists          = errors.New("already exists")
+	errBytesNotTracked = errors.New("bytes not tracked for state")
+	errNA              = errors.New("not available yet")
 )
 
+const (
+	// tagFormatMarker takes the place of the address length in tags
+	// marshalled with a format version. The formats without a version have
+	// the address length there, either as is or as -length-1, which is never
+	// the marker for the lengths of swarm addresses.
+	tagFormatMarker = -2
+	// tagFormatVersion is the version of the format written by MarshalBinary
+	tagFormatVersion = 1
+)
+
+// subscriptionBufferSize is the number of snapshots buffered for a
+// subscriber of a tag before further ones are dropped
+const subscriptionBufferSize = 16
+
 // State is the enum type for chunk states
 type State = uint32
 
@@ -50,6 +66,18 @@ const (
 	StateSynced              // proof is received; chunk removed from sync db; chunk is available everywhere
 )
 
+// TagState holds the counters of a tag at one point in time
+type TagState struct {
+	Total       int64
+	Split       int64
+	Seen        int64
+	Stored      int64
+	Sent        int64
+	Synced      int64
+	TotalBytes  int64
+	SyncedBytes int64
+}
+
 // Tag represents info on the status of new chunks
 type Tag struct {
 	Total  int64 // total chunks belonging to a tag
@@ -59,32 +87,71 @@ type Tag struct {
 	Sent   int64 // number of chunks sent for push syncing
 	Synced int64 // number of chunks synced with proof
 
+	TotalBytes  int64 // total bytes belonging to a tag
+	SyncedBytes int64 // number of bytes synced with proof
+
 	Uid       uint32        // a unique identifier for this tag
 	Name      string        // a name tag for this tag
 	Address   swarm.Address // the associated swarm hash for this tag
-	StartedAt time.Time     // tag started to calculate ETA
+	StartedAt time.Time     // time the tag was created
+
+	// WaitForSync is false for send-only uploads, which are complete once
+	// all chunks are sent and do not wait for sync receipts.
+	WaitForSync bool
+
+	// ParentUid is the uid of the tag whose counters include the ones of
+	// this tag, e.g. the tag of a directory upload, or 0 if there is none.
+	ParentUid uint32
+
+	// FirstSeenAt is the time the first chunk was split, from which the ETA
+	// is calculated. It is zero until then.
+	FirstSeenAt   time.Time
+	firstSeenMu   sync.Mutex // protects FirstSeenAt
+	firstSeenOnce sync.Once  // make sure we set FirstSeenAt only once
+
+	// Annotations are arbitrary key/value pairs persisted with the tag, e.g.
+	// to correlate it with external records. They are accessed with
+	// SetAnnotation and Annotation.
+	Annotations   map[string]string
+	annotationsMu sync.Mutex // protects Annotations
+
+	childrenMu sync.Mutex // protects children
+	children   []*Tag     // tags with this tag as their parent
+
+	// counters are changed under the read lock, so that Snapshot can read
+	// all of them at once under the write lock
+	counterMu sync.RWMutex
+
+	subscribersMu sync.Mutex                 // protects subscribers
+	subscribers   map[chan TagState]struct{} // channels receiving snapshots on increments
 
 	// end-to-end tag tracing
 	ctx        context.Context     // tracing context
 	span       opentracing.Span    // tracing root span
 	spanOnce   sync.Once           // make sure we close root span only once
 	stateStore storage.StateStorer // to persist the tag
+	keyPrefix  string              // state store key prefix to persist the tag under
 	logger     logging.Logger      // logger instance for logging
+
+	syncedMu   sync.Mutex    // protects syncedC
+	syncedC    chan struct{} // closed once the tag is done syncing
+	syncedOnce sync.Once     // make sure we close syncedC only once
 }
 
 // NewTag creates a new tag, and returns it
 func NewTag(ctx context.Context, uid uint32, s string, total int64, tracer *tracing.Tracer, stateStore storage.StateStorer, logger logging.Logger) *Tag {
 	t := &Tag{
-		Uid:        uid,
-		Name:       s,
-		StartedAt:  time.Now(),
-		Total:      total,
-		stateStore: stateStore,
-		logger:     logger,
+		Uid:         uid,
+		Name:        s,
+		StartedAt:   time.Now(),
+		Total:       total,
+		WaitForSync: true,
+		stateStore:  stateStore,
+		logger:      logger,
 	}
 
-	// context here is used only to store the root span `new.upload.tag` within Tag,
-	// we don't need any type of ctx Deadline or cancellation for this particular ctx
+	// context here is used to store the root span `new.upload.tag` within Tag,
+	// its cancellation only ends waiting for the tag to be done syncing
 	t.span, _, t.ctx = tracer.StartSpanFromContext(ctx, "new.upload.tag", nil)
 	return t
 }
@@ -94,6 +161,16 @@ func (t *Tag) Context() context.Context {
 	return t.ctx
 }
 
+// done returns the channel which is closed once the context of the tag is
+// done, or nil if the tag has no context, e.g. since it was loaded from the
+// state store.
+func (t *Tag) done() <-chan struct{} {
+	if t.ctx == nil {
+		return nil
+	}
+	return t.ctx.Done()
+}
+
 // FinishRootSpan closes the pushsync span of the tags
 func (t *Tag) FinishRootSpan() {
 	t.spanOnce.Do(func() {
@@ -118,21 +195,66 @@ func (t *Tag) IncN(state State, n int) error {
 	case StateSynced:
 		v = &t.Synced
 	}
+	t.counterMu.RLock()
 	atomic.AddInt64(v, int64(n))
+	t.counterMu.RUnlock()
+
+	if state == StateSplit && n > 0 {
+		t.firstSeenOnce.Do(t.setFirstSeen)
+	}
 
-	// check if syncing is over and persist the tag
-	if state == StateSynced {
+	t.notifySubscribers()
+	t.checkSynced()
+
+	// check if the upload is over and persist the tag
+	if state == t.finalState() {
 		total := atomic.LoadInt64(&t.Total)
 		seen := atomic.LoadInt64(&t.Seen)
-		synced := atomic.LoadInt64(&t.Synced)
+		done := atomic.LoadInt64(v)
 		totalUnique := total - seen
-		if synced >= totalUnique {
+		if done >= totalUnique {
 			return t.saveTag()
 		}
 	}
 	return nil
 }
 
+// setFirstSeen sets FirstSeenAt to the current time unless it is already set,
+// e.g. because the tag was loaded after the first chunk was split.
+func (t *Tag) setFirstSeen() {
+	t.firstSeenMu.Lock()
+	defer t.firstSeenMu.Unlock()
+
+	if t.FirstSeenAt.IsZero() {
+		t.FirstSeenAt = time.Now()
+	}
+}
+
+// firstSeen returns FirstSeenAt, or the zero time if no chunk was split yet.
+func (t *Tag) firstSeen() time.Time {
+	t.firstSeenMu.Lock()
+	defer t.firstSeenMu.Unlock()
+
+	return t.FirstSeenAt
+}
+
+// IncBytes increments the byte count for a state
+// only the total and the synced byte counts are tracked
+func (t *Tag) IncBytes(state State, n int64) error {
+	t.counterMu.RLock()
+	defer t.counterMu.RUnlock()
+
+	switch state {
+	case TotalChunks:
+		atomic.AddInt64(&t.TotalBytes, n)
+	case StateSynced:
+		atomic.AddInt64(&t.SyncedBytes, n)
+	default:
+		return errBytesNotTracked
+	}
+	return nil
+}
+
 // Inc increments the count for a state
 func (t *Tag) Inc(state State) error {
 	return t.IncN(state, 1)
@@ -158,6 +280,111 @@ func (t *Tag) Get(state State) int64 {
 	return atomic.LoadInt64(v)
 }
 
+// Snapshot returns all counters of the tag read at once, so that they are
+// consistent with each other even while the tag is updated concurrently.
+// The counters of child tags are added to the ones of the tag, each of them
+// read at once on its own.
+func (t *Tag) Snapshot() TagState {
+	state := t.ownSnapshot()
+
+	t.childrenMu.Lock()
+	children := append([]*Tag(nil), t.children...)
+	t.childrenMu.Unlock()
+
+	for _, c := range children {
+		state.add(c.Snapshot())
+	}
+
+	return state
+}
+
+// ownSnapshot returns the counters of the tag without its children.
+func (t *Tag) ownSnapshot() TagState {
+	t.counterMu.Lock()
+	defer t.counterMu.Unlock()
+
+	return TagState{
+		Total:       atomic.LoadInt64(&t.Total),
+		Split:       atomic.LoadInt64(&t.Split),
+		Seen:        atomic.LoadInt64(&t.Seen),
+		Stored:      atomic.LoadInt64(&t.Stored),
+		Sent:        atomic.LoadInt64(&t.Sent),
+		Synced:      atomic.LoadInt64(&t.Synced),
+		TotalBytes:  atomic.LoadInt64(&t.TotalBytes),
+		SyncedBytes: atomic.LoadInt64(&t.SyncedBytes),
+	}
+}
+
+func (s *TagState) add(o TagState) {
+	s.Total += o.Total
+	s.Split += o.Split
+	s.Seen += o.Seen
+	s.Stored += o.Stored
+	s.Sent += o.Sent
+	s.Synced += o.Synced
+	s.TotalBytes += o.TotalBytes
+	s.SyncedBytes += o.SyncedBytes
+}
+
+// addChild adds the tag to the children whose counters are included in
+// the snapshots of this tag.
+func (t *Tag) addChild(child *Tag) {
+	t.childrenMu.Lock()
+	defer t.childrenMu.Unlock()
+
+	for _, c := range t.children {
+		if c.Uid == child.Uid {
+			return
+		}
+	}
+	t.children = append(t.children, child)
+}
+
+// repairCounters clamps the counters so that no counter exceeds the ones of
+// the states preceding it: chunks are split before they are stored, only
+// stored chunks are seen, sent or synced, and no more chunks than the total
+// are split once it is known. It reports whether any counter was changed and
+// must only be called before the tag is used concurrently.
+func (t *Tag) repairCounters() bool {
+	repaired := false
+	clamp := func(v *int64, max int64) {
+		if *v < 0 {
+			*v, repaired = 0, true
+		}
+		if max >= 0 && *v > max {
+			*v, repaired = max, true
+		}
+	}
+
+	clamp(&t.Total, -1)
+	if t.Total > 0 {
+		clamp(&t.Split, t.Total)
+	} else {
+		clamp(&t.Split, -1)
+	}
+	clamp(&t.Stored, t.Split)
+	clamp(&t.Seen, t.Stored)
+	clamp(&t.Sent, t.Stored)
+	clamp(&t.Synced, t.Stored)
+
+	clamp(&t.TotalBytes, -1)
+	if t.TotalBytes > 0 {
+		clamp(&t.SyncedBytes, t.TotalBytes)
+	} else {
+		clamp(&t.SyncedBytes, -1)
+	}
+
+	return repaired
+}
+
+// finalState returns the state in which the upload of the tag is complete
+func (t *Tag) finalState() State {
+	if t.WaitForSync {
+		return StateSynced
+	}
+	return StateSent
+}
+
 // GetTotal returns the total count
 func (t *Tag) TotalCounter() int64 {
 	return atomic.LoadInt64(&t.Total)
@@ -183,6 +410,79 @@ func (t *Tag) WaitTillDone(ctx context.Context, s State) error {
 	}
 }
 
+// Subscribe returns a channel which receives a snapshot of the counters after
+// every increment and a function which cancels the subscription and closes
+// the channel. Snapshots are dropped while the channel buffer is full, so that
+// slow subscribers do not block the increments.
+func (t *Tag) Subscribe() (<-chan TagState, func()) {
+	c := make(chan TagState, subscriptionBufferSize)
+
+	t.subscribersMu.Lock()
+	if t.subscribers == nil {
+		t.subscribers = make(map[chan TagState]struct{})
+	}
+	t.subscribers[c] = struct{}{}
+	t.subscribersMu.Unlock()
+
+	var once sync.Once
+	return c, func() {
+		once.Do(func() {
+			t.subscribersMu.Lock()
+			delete(t.subscribers, c)
+			t.subscribersMu.Unlock()
+
+			close(c)
+		})
+	}
+}
+
+// notifySubscribers sends a snapshot of the counters to all subscribers
+// which have room for it in their channel buffer.
+func (t *Tag) notifySubscribers() {
+	t.subscribersMu.Lock()
+	defer t.subscribersMu.Unlock()
+
+	if len(t.subscribers) == 0 {
+		return
+	}
+
+	state := t.Snapshot()
+	for c := range t.subscribers {
+		select {
+		case c <- state:
+		default:
+		}
+	}
+}
+
+// syncedChan returns a channel which is closed once the tag is done syncing
+func (t *Tag) syncedChan() <-chan struct{} {
+	t.syncedMu.Lock()
+	defer t.syncedMu.Unlock()
+
+	if t.syncedC == nil {
+		t.syncedC = make(chan struct{})
+	}
+	return t.syncedC
+}
+
+// checkSynced signals the waiters once the tag is done syncing
+func (t *Tag) checkSynced() {
+	if !t.Done(StateSynced) {
+		return
+	}
+
+	t.syncedOnce.Do(func() {
+		t.syncedMu.Lock()
+		defer t.syncedMu.Unlock()
+
+		if t.syncedC == nil {
+			t.syncedC = make(chan struct{})
+		}
+		close(t.syncedC)
+	})
+}
+
 // Done returns true if tag is complete wrt the state given as argument
 func (t *Tag) Done(s State) bool {
 	n, total, err := t.Status(s)
@@ -192,13 +492,18 @@ func (t *Tag) Done(s State) bool {
 // DoneSplit sets total count to SPLIT count and sets the associated swarm hash for this tag
 // is meant to be called when splitter finishes for input streams of unknown size
 func (t *Tag) DoneSplit(address swarm.Address) (int64, error) {
+	t.counterMu.RLock()
 	total := atomic.LoadInt64(&t.Split)
 	atomic.StoreInt64(&t.Total, total)
+	t.counterMu.RUnlock()
 
 	if !address.Equal(swarm.ZeroAddress) {
 		t.Address = address
 	}
 
+	t.notifySubscribers()
+	t.checkSynced()
+
 	// persist the tag
 	err := t.saveTag()
 	if err != nil {
@@ -207,8 +512,81 @@ func (t *Tag) DoneSplit(address swarm.Address) (int64, error) {
 	return total, nil
 }
 
+// ResetUnsynced rewinds the sent count to the synced count, so that chunks
+// which were sent but never synced count as unsent and the tag reflects that
+// push-sync attempts them again. The chunks stay in the push index of the
+// local store until they are synced, so they are retried without further
+// action. Synced progress is kept. Loading persisted tags does the same,
+// since receipts of chunks sent before a shutdown are lost. Send-only tags
+// do not wait for receipts, so their sent count stands. It returns the
+// number of chunks which were rewound.
+func (t *Tag) ResetUnsynced() (int64, error) {
+	if !t.WaitForSync {
+		return 0, nil
+	}
+
+	t.counterMu.Lock()
+	synced := atomic.LoadInt64(&t.Synced)
+	sent := atomic.LoadInt64(&t.Sent)
+	var rewound int64
+	if sent > synced {
+		rewound = sent - synced
+		atomic.StoreInt64(&t.Sent, synced)
+	}
+	t.counterMu.Unlock()
+
+	if rewound == 0 {
+		return 0, nil
+	}
+
+	t.notifySubscribers()
+
+	return rewound, t.saveTag()
+}
+
+// SetAnnotation sets the annotation with the key and persists the tag.
+func (t *Tag) SetAnnotation(key, value string) error {
+	t.annotationsMu.Lock()
+	if t.Annotations == nil {
+		t.Annotations = make(map[string]string)
+	}
+	t.Annotations[key] = value
+	t.annotationsMu.Unlock()
+
+	return t.saveTag()
+}
+
+// Annotation returns the annotation with the key and whether it is set.
+func (t *Tag) Annotation(key string) (string, bool) {
+	t.annotationsMu.Lock()
+	defer t.annotationsMu.Unlock()
+
+	value, ok := t.Annotations[key]
+	return value, ok
+}
+
+// annotations returns a copy of the annotations, or nil if there are none.
+func (t *Tag) annotations() map[string]string {
+	t.annotationsMu.Lock()
+	defer t.annotationsMu.Unlock()
+
+	if len(t.Annotations) == 0 {
+		return nil
+	}
+	annotations := make(map[string]string, len(t.Annotations))
+	for k, v := range t.Annotations {
+		annotations[k] = v
+	}
+	return annotations
+}
+
 // Status returns the value of state and the total count
+// For send-only tags the synced state reports the sent count, as these
+// tags do not wait for sync receipts.
 func (t *Tag) Status(state State) (int64, int64, error) {
+	if state == StateSynced && !t.WaitForSync {
+		state = StateSent
+	}
 	count, seen, total := t.Get(state), atomic.LoadInt64(&t.Seen), atomic.LoadInt64(&t.Total)
 	if total == 0 {
 		return count, total, errNA
@@ -226,45 +604,168 @@ func (t *Tag) Status(state State) (int64, int64, error) {
 	return count, total, errNA
 }
 
-// ETA returns the time of completion estimated based on time passed and rate of completion
-func (t *Tag) ETA(state State) (time.Time, error) {
-	cnt, total, err := t.Status(state)
-	if err != nil {
-		return time.Time{}, err
+// Progress returns the completed fraction of the tag wrt the state given as
+// argument, between 0 and 1. It is 0 while the total is not known.
+func (t *Tag) Progress(state State) float64 {
+	cnt, total := t.progress(state)
+	if total <= 0 {
+		return 0
+	}
+	if cnt >= total {
+		return 1
+	}
+	return float64(cnt) / float64(total)
+}
+
+// ETA returns the time remaining until the tag is complete wrt the state given
+// as argument, extrapolated from the time passed since the first chunk was
+// split and the rate of completion. The ok result is false if no estimate can
+// be made yet, because there is no progress or the total is not known.
+func (t *Tag) ETA(state State) (remaining time.Duration, ok bool) {
+	cnt, total := t.progress(state)
+	if cnt <= 0 || total <= 0 {
+		return 0, false
+	}
+	if cnt >= total {
+		return 0, true
+	}
+	start := t.firstSeen()
+	if start.IsZero() {
+		start = t.StartedAt
+	}
+	elapsed := time.Since(start)
+	return time.Duration(float64(elapsed) * float64(total-cnt) / float64(cnt)), true
+}
+
+// progress returns the count for the state and the count at which the tag is
+// complete wrt the state, read at once like Status reports them.
+func (t *Tag) progress(state State) (cnt, total int64) {
+	if state == StateSynced && !t.WaitForSync {
+		state = StateSent
 	}
-	if cnt == 0 || total == 0 {
-		return time.Time{}, errNoETA
+	s := t.ownSnapshot()
+	switch state {
+	case TotalChunks:
+		return s.Total, s.Total
+	case StateSplit:
+		return s.Split, s.Total
+	case StateStored:
+		return s.Stored, s.Total
+	case StateSeen:
+		return s.Seen, s.Total
+	case StateSent:
+		return s.Sent, s.Total - s.Seen
+	case StateSynced:
+		return s.Synced, s.Total - s.Seen
 	}
-	diff := time.Since(t.StartedAt)
-	dur := time.Duration(total) * diff / time.Duration(cnt)
-	return t.StartedAt.Add(dur), nil
+	return 0, s.Total
 }
 
 // MarshalBinary marshals the tag into a byte slice
+// The counters and the start time are followed by tagFormatMarker and the
+// version of the format, after which all fields of the version are written.
 func (tag *Tag) MarshalBinary() (data []byte, err error) {
+	// children are persisted on their own
+	state := tag.ownSnapshot()
+
 	buffer := make([]byte, 4)
 	binary.BigEndian.PutUint32(buffer, tag.Uid)
-	encodeInt64Append(&buffer, atomic.LoadInt64(&tag.Total))
-	encodeInt64Append(&buffer, atomic.LoadInt64(&tag.Split))
-	encodeInt64Append(&buffer, atomic.LoadInt64(&tag.Seen))
-	encodeInt64Append(&buffer, atomic.LoadInt64(&tag.Stored))
-	encodeInt64Append(&buffer, atomic.LoadInt64(&tag.Sent))
-	encodeInt64Append(&buffer, atomic.LoadInt64(&tag.Synced))
-
-	intBuffer := make([]byte, 8)
-
-	n := binary.PutVarint(intBuffer, tag.StartedAt.Unix())
-	buffer = append(buffer, intBuffer[:n]...)
-
-	n = binary.PutVarint(intBuffer, int64(len(tag.Address.Bytes())))
-	buffer = append(buffer, intBuffer[:n]...)
+	encodeInt64Append(&buffer, state.Total)
+	encodeInt64Append(&buffer, state.Split)
+	encodeInt64Append(&buffer, state.Seen)
+	encodeInt64Append(&buffer, state.Stored)
+	encodeInt64Append(&buffer, state.Sent)
+	encodeInt64Append(&buffer, state.Synced)
+	encodeInt64Append(&buffer, tag.StartedAt.Unix())
+
+	encodeInt64Append(&buffer, tagFormatMarker)
+	buffer = append(buffer, tagFormatVersion)
+	encodeInt64Append(&buffer, int64(len(tag.Address.Bytes())))
 	buffer = append(buffer, tag.Address.Bytes()...)
+	encodeInt64Append(&buffer, int64(len(tag.Name)))
 	buffer = append(buffer, []byte(tag.Name)...)
 
+	if tag.WaitForSync {
+		buffer = append(buffer, 1)
+	} else {
+		buffer = append(buffer, 0)
+	}
+	encodeInt64Append(&buffer, state.TotalBytes)
+	encodeInt64Append(&buffer, state.SyncedBytes)
+	encodeInt64Append(&buffer, int64(tag.ParentUid))
+
+	annotations := tag.annotations()
+	keys := make([]string, 0, len(annotations))
+	for k := range annotations {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	encodeInt64Append(&buffer, int64(len(keys)))
+	for _, k := range keys {
+		encodeInt64Append(&buffer, int64(len(k)))
+		buffer = append(buffer, []byte(k)...)
+		encodeInt64Append(&buffer, int64(len(annotations[k])))
+		buffer = append(buffer, []byte(annotations[k])...)
+	}
+
+	var firstSeen int64
+	if t := tag.firstSeen(); !t.IsZero() {
+		firstSeen = t.Unix()
+	}
+	encodeInt64Append(&buffer, firstSeen)
+
 	return buffer, nil
 }
 
+// MarshalJSON marshals the exported fields of the tag like the default
+// encoding, with the counters taken from a snapshot of the tag without its
+// children, which are marshalled on their own.
+func (tag *Tag) MarshalJSON() ([]byte, error) {
+	state := tag.ownSnapshot()
+
+	return json.Marshal(struct {
+		Total       int64
+		Split       int64
+		Seen        int64
+		Stored      int64
+		Sent        int64
+		Synced      int64
+		TotalBytes  int64
+		SyncedBytes int64
+		Uid         uint32
+		Name        string
+		Address     swarm.Address
+		StartedAt   time.Time
+		FirstSeenAt time.Time
+		WaitForSync bool
+		ParentUid   uint32
+		Annotations map[string]string
+	}{
+		Total:       state.Total,
+		Split:       state.Split,
+		Seen:        state.Seen,
+		Stored:      state.Stored,
+		Sent:        state.Sent,
+		Synced:      state.Synced,
+		TotalBytes:  state.TotalBytes,
+		SyncedBytes: state.SyncedBytes,
+		Uid:         tag.Uid,
+		Name:        tag.Name,
+		Address:     tag.Address,
+		StartedAt:   tag.StartedAt,
+		FirstSeenAt: tag.firstSeen(),
+		WaitForSync: tag.WaitForSync,
+		ParentUid:   tag.ParentUid,
+		Annotations: tag.annotations(),
+	})
+}
+
 // UnmarshalBinary unmarshals a byte slice into a tag
+// Tags marshalled before the format was versioned are unmarshalled as well.
+// Tags marshalled before the upload mode and the byte counts were added have
+// the plain address length in place of tagFormatMarker, wait for sync and
+// have zero byte counts. Tags marshalled after that have the address length
+// encoded as -length-1 and may lack the fields which were added later.
 func (tag *Tag) UnmarshalBinary(buffer []byte) error {
 	if len(buffer) < 13 {
 		return errors.New("buffer too short")
@@ -285,16 +786,105 @@ func (tag *Tag) UnmarshalBinary(buffer []byte) error {
 
 	t, n = binary.Varint(buffer)
 	buffer = buffer[n:]
-	if t > 0 {
-		tag.Address = swarm.NewAddress(buffer[:t])
+
+	switch {
+	case t == tagFormatMarker:
+		if len(buffer) == 0 {
+			return errors.New("buffer too short")
+		}
+		if version := buffer[0]; version != tagFormatVersion {
+			return fmt.Errorf("unsupported tag format version %d", version)
+		}
+		buffer = buffer[1:]
+		addressLength := decodeInt64Splice(&buffer)
+		return tag.unmarshalFields(buffer, addressLength, false)
+	case t >= 0:
+		// the name takes up the rest of the buffer
+		if int64(len(buffer)) < t {
+			return errors.New("buffer too short")
+		}
+		if t > 0 {
+			tag.Address = swarm.NewAddress(buffer[:t])
+		}
+		tag.Name = string(buffer[t:])
+		tag.WaitForSync = true
+		tag.FirstSeenAt = tag.StartedAt
+		return nil
+	default:
+		return tag.unmarshalFields(buffer, -t-1, true)
+	}
+}
+
+// unmarshalFields unmarshals the fields following the address length. Tags
+// of the unversioned format may end after the byte counts or any of the
+// fields added after them, which then keep their defaults.
+func (tag *Tag) unmarshalFields(buffer []byte, addressLength int64, unversioned bool) error {
+	if addressLength < 0 || int64(len(buffer)) < addressLength {
+		return errors.New("buffer too short")
+	}
+	if addressLength > 0 {
+		tag.Address = swarm.NewAddress(buffer[:addressLength])
+	}
+	buffer = buffer[addressLength:]
+
+	nameLength := decodeInt64Splice(&buffer)
+	if nameLength < 0 || int64(len(buffer)) < nameLength+1 {
+		return errors.New("buffer too short")
+	}
+	tag.Name = string(buffer[:nameLength])
+	buffer = buffer[nameLength:]
+
+	tag.WaitForSync = buffer[0] == 1
+	buffer = buffer[1:]
+
+	atomic.AddInt64(&tag.TotalBytes, decodeInt64Splice(&buffer))
+	atomic.AddInt64(&tag.SyncedBytes, decodeInt64Splice(&buffer))
+
+	// whether the field follows, unversioned tags may end before it
+	next := func() bool {
+		return !unversioned || len(buffer) > 0
+	}
+
+	if next() {
+		tag.ParentUid = uint32(decodeInt64Splice(&buffer))
+	}
+
+	if next() {
+		count := decodeInt64Splice(&buffer)
+		if count < 0 {
+			return errors.New("invalid annotation count")
+		}
+		if count > 0 {
+			tag.Annotations = make(map[string]string)
+		}
+		for i := int64(0); i < count; i++ {
+			k, err := decodeStringSplice(&buffer)
+			if err != nil {
+				return err
+			}
+			v, err := decodeStringSplice(&buffer)
+			if err != nil {
+				return err
+			}
+			tag.Annotations[k] = v
+		}
+	}
+
+	// tags marshalled before the first seen time was added were seen when
+	// they were started, a zero time means no chunk was split yet
+	tag.FirstSeenAt = tag.StartedAt
+	if next() {
+		tag.FirstSeenAt = time.Time{}
+		if t := decodeInt64Splice(&buffer); t != 0 {
+			tag.FirstSeenAt = time.Unix(t, 0)
+		}
 	}
-	tag.Name = string(buffer[t:])
 
 	return nil
 }
 
 func encodeInt64Append(buffer *[]byte, val int64) {
-	intBuffer := make([]byte, 8)
+	intBuffer := make([]byte, binary.MaxVarintLen64)
 	n := binary.PutVarint(intBuffer, val)
 	*buffer = append(*buffer, intBuffer[:n]...)
 }
@@ -305,9 +895,19 @@ func decodeInt64Splice(buffer *[]byte) int64 {
 	return val
 }
 
+func decodeStringSplice(buffer *[]byte) (string, error) {
+	length := decodeInt64Splice(buffer)
+	if length < 0 || int64(len(*buffer)) < length {
+		return "", errors.New("buffer too short")
+	}
+	val := string((*buffer)[:length])
+	*buffer = (*buffer)[length:]
+	return val, nil
+}
+
 // saveTag update the tag in the state store
 func (tag *Tag) saveTag() error {
-	key := getKey(tag.Uid)
+	key := getKey(tag.keyPrefix, tag.Uid)
 	value, err := tag.MarshalBinary()
 	if err != nil {
 		return err
@@ -322,6 +922,9 @@ func (tag *Tag) saveTag() error {
 	return nil
 }
 
-func getKey(uid uint32) string {
-	return fmt.Sprintf("tags_%d", uid)
+func getKey(prefix string, uid uint32) string {
+	if prefix == "" {
+		prefix = defaultKeyPrefix
+	}
+	return fmt.Sprintf("%s%d", prefix, uid)
 }
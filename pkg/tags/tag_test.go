@@ -18,7 +18,10 @@ package tags
 
 import (
 	"context"
+	"encoding/binary"
+	"encoding/json"
 	"io/ioutil"
+	"math"
 	"sync"
 	"testing"
 	"time"
@@ -117,24 +120,129 @@ func TestTagStatus(t *testing.T) {
 	}
 }
 
+// TestTagWaitForSync tests when tags are done depending on the upload mode
+func TestTagWaitForSync(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		waitForSync bool
+	}{
+		{name: "wait for sync", waitForSync: true},
+		{name: "send only", waitForSync: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mockStatestore := statestore.NewStateStore()
+			logger := logging.New(ioutil.Discard, 0)
+			tg := NewTag(context.Background(), 1, "test/tag", 10, nil, mockStatestore, logger)
+			tg.WaitForSync = tc.waitForSync
+
+			for _, state := range []State{StateSplit, StateStored, StateSent} {
+				err := tg.IncN(state, 10)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if done := tg.Done(StateSynced); done == tc.waitForSync {
+				t.Fatalf("got done %v after all chunks were sent", done)
+			}
+
+			// only send-only tags are persisted once all chunks are sent
+			var data []byte
+			err := mockStatestore.Get(getKey("", tg.Uid), &data)
+			if tc.waitForSync && err == nil {
+				t.Fatal("tag persisted before all chunks were synced")
+			}
+			if !tc.waitForSync && err != nil {
+				t.Fatalf("tag not persisted after all chunks were sent: %v", err)
+			}
+
+			err = tg.IncN(StateSynced, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !tg.Done(StateSynced) {
+				t.Fatal("tag not done after all chunks were synced")
+			}
+			if err := mockStatestore.Get(getKey("", tg.Uid), &data); err != nil {
+				t.Fatalf("tag not persisted: %v", err)
+			}
+		})
+	}
+}
+
 // tests ETA is precise
 func TestTagETA(t *testing.T) {
 	now := time.Now()
 	maxDiff := 100000 // 100 microsecond
 	tg := &Tag{Total: 10, StartedAt: now}
+	// the upload starts some time after the tag was created
 	time.Sleep(100 * time.Millisecond)
 	err := tg.Inc(StateSplit)
 	if err != nil {
 		t.Fatal(err)
 	}
-	eta, err := tg.ETA(StateSplit)
-	if err != nil {
-		t.Fatal(err)
+	if !tg.FirstSeenAt.After(now) {
+		t.Fatalf("got first seen at %v, want after %v", tg.FirstSeenAt, now)
+	}
+	time.Sleep(10 * time.Millisecond)
+	eta, ok := tg.ETA(StateSplit)
+	if !ok {
+		t.Fatal("no ETA")
 	}
-	diff := time.Until(eta) - 9*time.Since(now)
+	diff := eta - 9*time.Since(tg.FirstSeenAt)
 	if int(diff) > maxDiff {
 		t.Fatalf("ETA is not precise, got diff %v > .1ms", diff)
 	}
+	if eta >= 9*100*time.Millisecond {
+		t.Fatalf("got ETA %v including the time before the first chunk was split", eta)
+	}
+}
+
+// TestTagProgress tests the completed fraction and the missing estimates
+func TestTagProgress(t *testing.T) {
+	tg := &Tag{StartedAt: time.Now()}
+	if p := tg.Progress(StateSplit); p != 0 {
+		t.Fatalf("got progress %v without total, want 0", p)
+	}
+	if _, ok := tg.ETA(StateSplit); ok {
+		t.Fatal("got ETA without total")
+	}
+
+	if err := tg.IncN(TotalChunks, 4); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tg.ETA(StateSplit); ok {
+		t.Fatal("got ETA without progress")
+	}
+
+	if err := tg.IncN(StateSplit, 1); err != nil {
+		t.Fatal(err)
+	}
+	if p := tg.Progress(StateSplit); p != 0.25 {
+		t.Fatalf("got progress %v, want 0.25", p)
+	}
+
+	// sent chunks are counted against the chunks which were not seen
+	if err := tg.IncN(StateSeen, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := tg.IncN(StateSent, 1); err != nil {
+		t.Fatal(err)
+	}
+	if p := tg.Progress(StateSent); p != 0.5 {
+		t.Fatalf("got progress %v, want 0.5", p)
+	}
+
+	if err := tg.IncN(StateSplit, 3); err != nil {
+		t.Fatal(err)
+	}
+	if p := tg.Progress(StateSplit); p != 1 {
+		t.Fatalf("got progress %v, want 1", p)
+	}
+	if eta, ok := tg.ETA(StateSplit); !ok || eta != 0 {
+		t.Fatalf("got ETA %v, %v for complete tag, want 0, true", eta, ok)
+	}
 }
 
 // TestTagConcurrentIncrements tests Inc calls concurrently
@@ -252,6 +360,10 @@ func TestMarshallingWithAddr(t *testing.T) {
 		t.Fatalf("tag names not equal. want %s got %s", tg.Name, unmarshalledTag.Name)
 	}
 
+	if unmarshalledTag.WaitForSync != tg.WaitForSync {
+		t.Fatalf("tag wait for sync not equal. want %v got %v", tg.WaitForSync, unmarshalledTag.WaitForSync)
+	}
+
 	for _, state := range allStates {
 		uv, tv := unmarshalledTag.Get(state), tg.Get(state)
 		if uv != tv {
@@ -318,3 +430,532 @@ func TestMarshallingNoAddr(t *testing.T) {
 		t.Fatalf("expected tag addresses to be equal length")
 	}
 }
+
+// TestTagBytes tests that the byte counts are tracked and marshalled
+func TestTagBytes(t *testing.T) {
+	tg := &Tag{Total: 10, WaitForSync: true}
+
+	if err := tg.IncBytes(TotalChunks, 40000); err != nil {
+		t.Fatal(err)
+	}
+	if err := tg.IncBytes(StateSynced, 4096); err != nil {
+		t.Fatal(err)
+	}
+	if err := tg.IncBytes(StateSynced, 1000); err != nil {
+		t.Fatal(err)
+	}
+	if err := tg.IncBytes(StateStored, 1000); err != errBytesNotTracked {
+		t.Fatalf("got error %v, want %v", err, errBytesNotTracked)
+	}
+
+	b, err := tg.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unmarshalledTag := &Tag{}
+	if err := unmarshalledTag.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+
+	if unmarshalledTag.TotalBytes != 40000 {
+		t.Fatalf("got total bytes %d, want 40000", unmarshalledTag.TotalBytes)
+	}
+	if unmarshalledTag.SyncedBytes != 5096 {
+		t.Fatalf("got synced bytes %d, want 5096", unmarshalledTag.SyncedBytes)
+	}
+}
+
+// TestUnmarshalLegacyTag tests that tags marshalled before the upload mode
+// and the byte counts were added can be unmarshalled
+func TestUnmarshalLegacyTag(t *testing.T) {
+	address := []byte{0, 1, 2, 3}
+
+	buffer := make([]byte, 4)
+	binary.BigEndian.PutUint32(buffer, 111)
+	for _, v := range []int64{10, 10, 1, 9, 9, 8, 1600000000, int64(len(address))} {
+		encodeInt64Append(&buffer, v)
+	}
+	buffer = append(buffer, address...)
+	buffer = append(buffer, []byte("legacy/tag")...)
+
+	tg := &Tag{}
+	if err := tg.UnmarshalBinary(buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	if tg.Uid != 111 {
+		t.Fatalf("got uid %d, want 111", tg.Uid)
+	}
+	if tg.Synced != 8 {
+		t.Fatalf("got synced %d, want 8", tg.Synced)
+	}
+	if tg.StartedAt.Unix() != 1600000000 {
+		t.Fatalf("got started at %d, want 1600000000", tg.StartedAt.Unix())
+	}
+	if !tg.Address.Equal(swarm.NewAddress(address)) {
+		t.Fatalf("got address %s, want %x", tg.Address, address)
+	}
+	if tg.Name != "legacy/tag" {
+		t.Fatalf("got name %q, want %q", tg.Name, "legacy/tag")
+	}
+	if !tg.WaitForSync {
+		t.Fatal("legacy tag does not wait for sync")
+	}
+	if tg.TotalBytes != 0 || tg.SyncedBytes != 0 {
+		t.Fatalf("got byte counts %d and %d, want zero", tg.TotalBytes, tg.SyncedBytes)
+	}
+	if !tg.FirstSeenAt.Equal(tg.StartedAt) {
+		t.Fatalf("got first seen at %v, want started at %v", tg.FirstSeenAt, tg.StartedAt)
+	}
+}
+
+// TestUnmarshalUnversionedTag tests that tags marshalled with the address
+// length encoded as -length-1, before the format was versioned, can be
+// unmarshalled, including the ones lacking the fields added later
+func TestUnmarshalUnversionedTag(t *testing.T) {
+	address := []byte{0, 1, 2, 3}
+
+	buffer := make([]byte, 4)
+	binary.BigEndian.PutUint32(buffer, 111)
+	for _, v := range []int64{10, 10, 1, 9, 9, 8, 1600000000, -int64(len(address)) - 1} {
+		encodeInt64Append(&buffer, v)
+	}
+	buffer = append(buffer, address...)
+	encodeInt64Append(&buffer, int64(len("unversioned/tag")))
+	buffer = append(buffer, []byte("unversioned/tag")...)
+	buffer = append(buffer, 0)
+	encodeInt64Append(&buffer, 40000)
+	encodeInt64Append(&buffer, 5096)
+
+	tg := &Tag{}
+	if err := tg.UnmarshalBinary(buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	if tg.Uid != 111 {
+		t.Fatalf("got uid %d, want 111", tg.Uid)
+	}
+	if !tg.Address.Equal(swarm.NewAddress(address)) {
+		t.Fatalf("got address %s, want %x", tg.Address, address)
+	}
+	if tg.Name != "unversioned/tag" {
+		t.Fatalf("got name %q, want %q", tg.Name, "unversioned/tag")
+	}
+	if tg.WaitForSync {
+		t.Fatal("send-only tag waits for sync")
+	}
+	if tg.TotalBytes != 40000 || tg.SyncedBytes != 5096 {
+		t.Fatalf("got byte counts %d and %d, want 40000 and 5096", tg.TotalBytes, tg.SyncedBytes)
+	}
+	if tg.ParentUid != 0 || tg.Annotations != nil {
+		t.Fatalf("got parent %d and annotations %v, want none", tg.ParentUid, tg.Annotations)
+	}
+	if !tg.FirstSeenAt.Equal(tg.StartedAt) {
+		t.Fatalf("got first seen at %v, want started at %v", tg.FirstSeenAt, tg.StartedAt)
+	}
+}
+
+// TestTagFormatVersion tests that marshalled tags carry the format version
+// and that tags of unknown versions are rejected
+func TestTagFormatVersion(t *testing.T) {
+	tg := &Tag{
+		Uid:         1,
+		Total:       10,
+		TotalBytes:  math.MaxInt64,
+		StartedAt:   time.Unix(1600000000, 0),
+		Name:        "tag",
+		WaitForSync: true,
+	}
+
+	b, err := tg.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var unmarshalled Tag
+	if err := unmarshalled.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if unmarshalled.TotalBytes != math.MaxInt64 {
+		t.Fatalf("got total bytes %d, want %d", unmarshalled.TotalBytes, int64(math.MaxInt64))
+	}
+	if unmarshalled.Name != "tag" {
+		t.Fatalf("got name %q, want %q", unmarshalled.Name, "tag")
+	}
+
+	// the version follows the counters, the start time and the marker
+	prefix := make([]byte, 4)
+	for _, v := range []int64{10, 0, 0, 0, 0, 0, 1600000000, tagFormatMarker} {
+		encodeInt64Append(&prefix, v)
+	}
+	if len(b) <= len(prefix) || b[len(prefix)] != tagFormatVersion {
+		t.Fatalf("got no format version %d after %x in %x", tagFormatVersion, prefix, b)
+	}
+
+	b[len(prefix)] = tagFormatVersion + 1
+	if err := new(Tag).UnmarshalBinary(b); err == nil {
+		t.Fatal("unmarshalled tag of unknown format version")
+	}
+}
+
+// TestTagSnapshot races chunk state increments against snapshots and checks
+// that every snapshot is a state the chunks can actually be in.
+func TestTagSnapshot(t *testing.T) {
+	mockStatestore := statestore.NewStateStore()
+	logger := logging.New(ioutil.Discard, 0)
+	chunks := 1000
+	tg := &Tag{Total: int64(chunks), stateStore: mockStatestore, logger: logger}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < chunks/4; j++ {
+				for _, state := range []State{StateSplit, StateStored, StateSent, StateSynced} {
+					if err := tg.Inc(state); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	check := func(s TagState) {
+		t.Helper()
+		if s.Synced > s.Sent || s.Sent > s.Stored || s.Stored > s.Split {
+			t.Fatalf("inconsistent snapshot %+v", s)
+		}
+	}
+
+	for {
+		select {
+		case <-done:
+			s := tg.Snapshot()
+			check(s)
+			if s.Synced != int64(chunks) {
+				t.Fatalf("got synced %d, want %d", s.Synced, chunks)
+			}
+			return
+		default:
+			check(tg.Snapshot())
+		}
+	}
+}
+
+// TestTagSubscribe tests subscriptions to the counters under concurrent increments
+func TestTagSubscribe(t *testing.T) {
+	chunks := 1000
+	tg := &Tag{Total: int64(chunks)}
+
+	// a subscriber which never receives must not block the increments
+	_, cancelIdle := tg.Subscribe()
+	defer cancelIdle()
+
+	updates, cancel := tg.Subscribe()
+	early, cancelEarly := tg.Subscribe()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < chunks/4; j++ {
+				if err := tg.Inc(StateSplit); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+
+	// unsubscribing during the increments must neither block nor panic
+	cancelEarly()
+	cancelEarly()
+	for range early {
+	}
+
+	received := 0
+	var last int64
+	for received < 10 {
+		s := <-updates
+		if s.Split < last {
+			t.Fatalf("split count decreased from %d to %d", last, s.Split)
+		}
+		last = s.Split
+		received++
+	}
+
+	wg.Wait()
+	cancel()
+	for s := range updates {
+		if s.Split < last {
+			t.Fatalf("split count decreased from %d to %d", last, s.Split)
+		}
+		last = s.Split
+	}
+	if last > int64(chunks) {
+		t.Fatalf("got split count %d, want at most %d", last, chunks)
+	}
+}
+
+// TestTagIncN tests that bulk increments notify subscribers and complete
+// the tag in the same way as single increments do
+func TestTagIncN(t *testing.T) {
+	chunks := 10
+	tg := &Tag{stateStore: statestore.NewStateStore(), logger: logging.New(ioutil.Discard, 0)}
+
+	updates, cancel := tg.Subscribe()
+	defer cancel()
+
+	if err := tg.IncN(StateSplit, chunks); err != nil {
+		t.Fatal(err)
+	}
+	if s := <-updates; s.Split != int64(chunks) {
+		t.Fatalf("got split count %d, want %d", s.Split, chunks)
+	}
+
+	if _, err := tg.DoneSplit(swarm.ZeroAddress); err != nil {
+		t.Fatal(err)
+	}
+	if s := <-updates; s.Total != int64(chunks) {
+		t.Fatalf("got total count %d, want %d", s.Total, chunks)
+	}
+
+	for _, state := range []State{StateStored, StateSent, StateSynced} {
+		if err := tg.IncN(state, chunks); err != nil {
+			t.Fatal(err)
+		}
+		<-updates
+		if got := tg.Get(state); got != int64(chunks) {
+			t.Fatalf("got count %d for state %d, want %d", got, state, chunks)
+		}
+	}
+
+	select {
+	case <-tg.syncedChan():
+	default:
+		t.Fatal("tag not done syncing")
+	}
+}
+
+func BenchmarkTagInc(b *testing.B) {
+	const chunks = 128
+
+	b.Run("Inc", func(b *testing.B) {
+		tg := &Tag{}
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < chunks; j++ {
+				_ = tg.Inc(StateSplit)
+			}
+		}
+	})
+
+	b.Run("IncN", func(b *testing.B) {
+		tg := &Tag{}
+		for i := 0; i < b.N; i++ {
+			_ = tg.IncN(StateSplit, chunks)
+		}
+	})
+}
+
+// TestTagResetUnsynced tests that the sent count is rewound to the synced
+// count while the other counters are kept
+func TestTagResetUnsynced(t *testing.T) {
+	mockStatestore := statestore.NewStateStore()
+	tg := &Tag{
+		Uid:         1,
+		Total:       10,
+		Split:       10,
+		Stored:      10,
+		Sent:        8,
+		Synced:      5,
+		WaitForSync: true,
+		stateStore:  mockStatestore,
+		logger:      logging.New(ioutil.Discard, 0),
+	}
+
+	rewound, err := tg.ResetUnsynced()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rewound != 3 {
+		t.Fatalf("got %d rewound chunks, want 3", rewound)
+	}
+
+	for state, want := range map[State]int64{
+		StateSplit:  10,
+		StateStored: 10,
+		StateSent:   5,
+		StateSynced: 5,
+	} {
+		if got := tg.Get(state); got != want {
+			t.Fatalf("got count %d for state %d, want %d", got, state, want)
+		}
+	}
+
+	var data []byte
+	if err := mockStatestore.Get(getKey("", tg.Uid), &data); err != nil {
+		t.Fatal(err)
+	}
+	var persisted Tag
+	if err := persisted.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if persisted.Sent != 5 {
+		t.Fatalf("got persisted sent count %d, want 5", persisted.Sent)
+	}
+
+	rewound, err = tg.ResetUnsynced()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rewound != 0 {
+		t.Fatalf("got %d rewound chunks on second reset, want 0", rewound)
+	}
+
+	sendOnly := &Tag{Total: 10, Sent: 8}
+	rewound, err = sendOnly.ResetUnsynced()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rewound != 0 || sendOnly.Get(StateSent) != 8 {
+		t.Fatalf("send-only tag rewound by %d to sent count %d", rewound, sendOnly.Get(StateSent))
+	}
+}
+
+func TestTagAnnotations(t *testing.T) {
+	mockStatestore := statestore.NewStateStore()
+	logger := logging.New(ioutil.Discard, 0)
+	ts := NewTags(mockStatestore, logger)
+	tg, err := ts.Create("annotated", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := tg.Annotation("user"); ok {
+		t.Fatal("got annotation on a new tag")
+	}
+
+	annotations := map[string]string{
+		"user":         "alice",
+		"request-id":   "42",
+		"content-type": "text/html",
+		"empty":        "",
+	}
+	for k, v := range annotations {
+		if err := tg.SetAnnotation(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check := func(t *testing.T, tg *Tag) {
+		t.Helper()
+		for k, want := range annotations {
+			got, ok := tg.Annotation(k)
+			if !ok {
+				t.Fatalf("annotation %q not found", k)
+			}
+			if got != want {
+				t.Fatalf("got annotation %q value %q, want %q", k, got, want)
+			}
+		}
+		if len(tg.Annotations) != len(annotations) {
+			t.Fatalf("got %d annotations, want %d", len(tg.Annotations), len(annotations))
+		}
+	}
+
+	t.Run("state store", func(t *testing.T) {
+		// the annotations were persisted when they were set
+		rcvd, err := NewTags(mockStatestore, logger).Get(tg.Uid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, rcvd)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		b, err := json.Marshal(tg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var rcvd Tag
+		if err := json.Unmarshal(b, &rcvd); err != nil {
+			t.Fatal(err)
+		}
+		check(t, &rcvd)
+	})
+}
+
+func TestTagFirstSeenAt(t *testing.T) {
+	tg := &Tag{Uid: 1, Total: 10, StartedAt: time.Unix(1600000000, 0), WaitForSync: true}
+
+	for _, state := range []State{StateStored, StateSent} {
+		if err := tg.Inc(state); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !tg.FirstSeenAt.IsZero() {
+		t.Fatalf("got first seen at %v before a chunk was split", tg.FirstSeenAt)
+	}
+
+	b, err := tg.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var unseen Tag
+	if err := unseen.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if !unseen.FirstSeenAt.IsZero() {
+		t.Fatalf("got unmarshalled first seen at %v before a chunk was split", unseen.FirstSeenAt)
+	}
+
+	if err := tg.Inc(StateSplit); err != nil {
+		t.Fatal(err)
+	}
+	firstSeen := tg.FirstSeenAt
+	if firstSeen.IsZero() {
+		t.Fatal("first seen at not set by the first split chunk")
+	}
+	if err := tg.Inc(StateSplit); err != nil {
+		t.Fatal(err)
+	}
+	if !tg.FirstSeenAt.Equal(firstSeen) {
+		t.Fatalf("got first seen at %v changed by the second split chunk, want %v", tg.FirstSeenAt, firstSeen)
+	}
+
+	b, err = tg.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seen Tag
+	if err := seen.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if seen.FirstSeenAt.Unix() != firstSeen.Unix() {
+		t.Fatalf("got unmarshalled first seen at %v, want %v", seen.FirstSeenAt, firstSeen)
+	}
+	if !seen.StartedAt.Equal(tg.StartedAt) {
+		t.Fatalf("got unmarshalled started at %v, want %v", seen.StartedAt, tg.StartedAt)
+	}
+
+	ts := NewTags(statestore.NewStateStore(), logging.New(ioutil.Discard, 0))
+	if err := ts.UnmarshalJSON([]byte(`{"1":{"Uid":1,"Total":10,"StartedAt":"2020-09-13T12:26:40Z","WaitForSync":true}}`)); err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := ts.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !legacy.FirstSeenAt.Equal(legacy.StartedAt) {
+		t.Fatalf("got first seen at %v of a legacy tag, want started at %v", legacy.FirstSeenAt, legacy.StartedAt)
+	}
+}
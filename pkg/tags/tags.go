@@ -22,7 +22,9 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -36,26 +38,127 @@ var (
 	ErrNotFound = errors.New("tag not found")
 )
 
+// createAttempts is the number of random uids tried before creating a tag fails
+const createAttempts = 5
+
+// defaultKeyPrefix is the state store key prefix used when none is configured
+const defaultKeyPrefix = "tags_"
+
 // Tags hold tag information indexed by a unique random uint32
 type Tags struct {
 	tags       *sync.Map
 	stateStore storage.StateStorer
 	logger     logging.Logger
+	keyPrefix  string
+	now        func() time.Time // clock used to timestamp tags
+}
+
+// Option is a function that applies an option to Tags.
+type Option func(*Tags)
+
+// WithKeyPrefix sets the state store key prefix under which tags are
+// persisted. Instances sharing a state store need distinct prefixes.
+func WithKeyPrefix(prefix string) Option {
+	return func(ts *Tags) {
+		ts.keyPrefix = prefix
+	}
+}
+
+// WithClock sets the clock used to timestamp created tags.
+func WithClock(now func() time.Time) Option {
+	return func(ts *Tags) {
+		ts.now = now
+	}
 }
 
 // NewTags creates a tags object
-func NewTags(stateStore storage.StateStorer, logger logging.Logger) *Tags {
-	return &Tags{
+func NewTags(stateStore storage.StateStorer, logger logging.Logger, opts ...Option) *Tags {
+	ts := &Tags{
 		tags:       &sync.Map{},
 		stateStore: stateStore,
 		logger:     logger,
+		keyPrefix:  defaultKeyPrefix,
+		now:        time.Now,
+	}
+	for _, o := range opts {
+		o(ts)
 	}
+	return ts
 }
 
 // Create creates a new tag, stores it by the name and returns it
-// it returns an error if the tag with this name already exists
+// it retries with a new uid if the uid is already taken and returns an
+// error if all attempts collide
 func (ts *Tags) Create(s string, total int64) (*Tag, error) {
-	t := NewTag(context.Background(), TagUidFunc(), s, total, nil, ts.stateStore, ts.logger)
+	return ts.createWithRandomUid(context.Background(), s, total, true)
+}
+
+// CreateWithContext creates a new tag like Create, whose context is derived
+// from the given one, e.g. to bind the tag to the request of the upload.
+// Increments are still recorded once the context is done, so that the tag
+// reflects what happened to the chunks, but waiting for the tag to be done
+// syncing returns the context error.
+func (ts *Tags) CreateWithContext(ctx context.Context, s string, total int64) (*Tag, error) {
+	return ts.createWithRandomUid(ctx, s, total, true)
+}
+
+// CreateWithUid creates a new tag with the given uid, stores it by the name
+// and returns it
+// it returns an error if a tag with this uid already exists, including one
+// which was persisted and is no longer held in memory
+func (ts *Tags) CreateWithUid(uid uint32, s string, total int64) (*Tag, error) {
+	// a tag with the uid might have been persisted and evicted from memory
+	if ts.stateStore != nil {
+		if _, err := ts.getTagFromStore(uid); err == nil {
+			return nil, errExists
+		}
+	}
+	return ts.create(context.Background(), uid, s, total, true)
+}
+
+// CreateSendOnly creates a new tag for an upload which is complete once all
+// chunks are sent, without waiting for sync receipts
+func (ts *Tags) CreateSendOnly(s string, total int64) (*Tag, error) {
+	return ts.createWithRandomUid(context.Background(), s, total, false)
+}
+
+// CreateChild creates a new tag whose counters are included in the snapshots
+// of the parent tag, e.g. for a file of a directory upload
+func (ts *Tags) CreateChild(parentUid uint32, name string, total int64) (*Tag, error) {
+	parent, err := ts.Get(parentUid)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := ts.createWithRandomUid(context.Background(), name, total, parent.WaitForSync)
+	if err != nil {
+		return nil, err
+	}
+	t.ParentUid = parentUid
+	parent.addChild(t)
+
+	return t, nil
+}
+
+// createWithRandomUid creates a tag with a random uid, retrying with another
+// one if the uid is taken by a tag held in memory. Random uids are not checked
+// against persisted tags which were evicted from memory, so that creating a
+// tag does not cost a state store lookup.
+func (ts *Tags) createWithRandomUid(ctx context.Context, s string, total int64, waitForSync bool) (t *Tag, err error) {
+	for i := 0; i < createAttempts; i++ {
+		t, err = ts.create(ctx, TagUidFunc(), s, total, waitForSync)
+		if !errors.Is(err, errExists) {
+			return t, err
+		}
+	}
+	return nil, err
+}
+
+func (ts *Tags) create(ctx context.Context, uid uint32, s string, total int64, waitForSync bool) (*Tag, error) {
+	t := NewTag(ctx, uid, s, total, nil, ts.stateStore, ts.logger)
+	t.WaitForSync = waitForSync
+	t.keyPrefix = ts.keyPrefix
+	t.StartedAt = ts.now()
 
 	if _, loaded := ts.tags.LoadOrStore(t.Uid, t); loaded {
 		return nil, errExists
@@ -64,11 +167,101 @@ func (ts *Tags) Create(s string, total int64) (*Tag, error) {
 	return t, nil
 }
 
-// All returns all existing tags in Tags' sync.Map
-// Note that tags are returned in no particular order
+// All returns all existing tags, including the persisted ones which are not
+// held in memory, sorted by the time they were started, newest first
 func (ts *Tags) All() (t []*Tag) {
+	t, err := ts.ListTags(0, 0, true)
+	if err != nil {
+		ts.logger.Errorf("tags: list tags: %v", err)
+	}
+	return t
+}
+
+// ListTags returns tags sorted by the time they were started, newest first,
+// skipping offset tags and returning at most limit tags or all remaining
+// tags if limit is 0. Tags which were persisted but are not held in memory
+// are included. Unless includeDone is set, tags which are done are left out.
+func (ts *Tags) ListTags(offset, limit int, includeDone bool) ([]*Tag, error) {
+	if offset < 0 || limit < 0 {
+		return nil, fmt.Errorf("invalid offset %d or limit %d", offset, limit)
+	}
+
+	tags := make(map[uint32]*Tag)
+	ts.tags.Range(func(k, v interface{}) bool {
+		t := v.(*Tag)
+		tags[t.Uid] = t
+		return true
+	})
+
+	if ts.stateStore != nil {
+		stored, err := ts.storedTags()
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range stored {
+			if _, ok := tags[t.Uid]; !ok {
+				tags[t.Uid] = t
+			}
+		}
+	}
+
+	list := make([]*Tag, 0, len(tags))
+	for _, t := range tags {
+		if includeDone || !t.Done(StateSynced) {
+			list = append(list, t)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].StartedAt.Equal(list[j].StartedAt) {
+			return list[i].Uid < list[j].Uid
+		}
+		return list[i].StartedAt.After(list[j].StartedAt)
+	})
+
+	if offset >= len(list) {
+		return nil, nil
+	}
+	list = list[offset:]
+	if limit > 0 && limit < len(list) {
+		list = list[:limit]
+	}
+
+	return list, nil
+}
+
+// TagsMetrics are the counters of all tags held in memory summed up
+type TagsMetrics struct {
+	Tags     int      // number of tags
+	Active   int      // number of tags which are not done
+	Counters TagState // sum of the counters of the tags
+}
+
+// Metrics sums up the counters of the tags held in memory. Persisted tags
+// which are not loaded are not included. The counters of every tag are read
+// at once, but the tags are read one after the other. Child tags are only
+// counted on their own, as their parents do not include them here.
+func (ts *Tags) Metrics() TagsMetrics {
+	var m TagsMetrics
+	ts.tags.Range(func(k, v interface{}) bool {
+		t := v.(*Tag)
+		m.Tags++
+		if !t.Done(StateSynced) {
+			m.Active++
+		}
+		m.Counters.add(t.ownSnapshot())
+		return true
+	})
+	return m
+}
+
+// ActiveSince returns the tags which were started after the given time
+// Note that tags are returned in no particular order
+func (ts *Tags) ActiveSince(since time.Time) (t []*Tag) {
 	ts.tags.Range(func(k, v interface{}) bool {
-		t = append(t, v.(*Tag))
+		if tag := v.(*Tag); tag.StartedAt.After(since) {
+			t = append(t, tag)
+		}
 
 		return true
 	})
@@ -86,19 +279,72 @@ func (ts *Tags) Get(uid uint32) (*Tag, error) {
 		if err != nil {
 			return nil, ErrNotFound
 		}
-		ts.tags.LoadOrStore(ta.Uid, ta)
+		if _, loaded := ts.tags.LoadOrStore(ta.Uid, ta); !loaded {
+			ts.attach(ta)
+		}
 		return ta, nil
 	}
 	return t.(*Tag), nil
 }
 
+// WaitSynced blocks until the tag with the uid is done syncing or either the
+// given context or the context of the tag is done
+func (ts *Tags) WaitSynced(ctx context.Context, uid uint32) error {
+	t, err := ts.Get(uid)
+	if err != nil {
+		return err
+	}
+
+	synced := t.syncedChan()
+	if t.Done(StateSynced) {
+		return nil
+	}
+
+	select {
+	case <-synced:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.done():
+		return t.ctx.Err()
+	}
+}
+
 // GetByAddress returns the latest underlying tag for the address or an error if not found
+// Tags without a parent take precedence over child tags with the same address.
 func (ts *Tags) GetByAddress(address swarm.Address) (*Tag, error) {
+	var root, child *Tag
+	ts.tags.Range(func(key interface{}, value interface{}) bool {
+		rcvdTag := value.(*Tag)
+		if !rcvdTag.Address.Equal(address) {
+			return true
+		}
+		if rcvdTag.ParentUid == 0 {
+			if root == nil || rcvdTag.StartedAt.After(root.StartedAt) {
+				root = rcvdTag
+			}
+		} else if child == nil || rcvdTag.StartedAt.After(child.StartedAt) {
+			child = rcvdTag
+		}
+		return true
+	})
+
+	if root != nil {
+		return root, nil
+	}
+	if child != nil {
+		return child, nil
+	}
+	return nil, ErrNotFound
+}
+
+// GetByName returns the latest underlying tag with the name or an error if not found
+func (ts *Tags) GetByName(name string) (*Tag, error) {
 	var t *Tag
 	var lastTime time.Time
 	ts.tags.Range(func(key interface{}, value interface{}) bool {
 		rcvdTag := value.(*Tag)
-		if rcvdTag.Address.Equal(address) && rcvdTag.StartedAt.After(lastTime) {
+		if rcvdTag.Name == name && rcvdTag.StartedAt.After(lastTime) {
 			t = rcvdTag
 			lastTime = rcvdTag.StartedAt
 		}
@@ -111,6 +357,20 @@ func (ts *Tags) GetByAddress(address swarm.Address) (*Tag, error) {
 	return t, nil
 }
 
+// attach links the tag with its parent and children held in memory
+func (ts *Tags) attach(t *Tag) {
+	ts.tags.Range(func(_, v interface{}) bool {
+		other := v.(*Tag)
+		if t.ParentUid != 0 && other.Uid == t.ParentUid {
+			other.addChild(t)
+		}
+		if other.ParentUid == t.Uid {
+			t.addChild(other)
+		}
+		return true
+	})
+}
+
 // Range exposes sync.Map's iterator
 func (ts *Tags) Range(fn func(k, v interface{}) bool) {
 	ts.tags.Range(fn)
@@ -126,7 +386,8 @@ func (ts *Tags) MarshalJSON() (out []byte, err error) {
 		key := fmt.Sprintf("%d", k)
 		val := v.(*Tag)
 
-		// don't persist tags which were already done
+		// don't persist tags which were already done, send-only tags
+		// report their sent count as synced
 		if !val.Done(StateSynced) {
 			m[key] = val
 		}
@@ -135,31 +396,123 @@ func (ts *Tags) MarshalJSON() (out []byte, err error) {
 	return json.Marshal(m)
 }
 
+// UnmarshalJSON loads the tags from their JSON encoding. Tags with counters
+// which violate the invariants between them, e.g. because the state store
+// was corrupted, are repaired by clamping the counters rather than dropped,
+// so that the uploads remain visible, and a warning is logged.
 func (ts *Tags) UnmarshalJSON(value []byte) error {
-	m := make(map[string]*Tag)
+	m := make(map[string]json.RawMessage)
 	err := json.Unmarshal(value, &m)
 	if err != nil {
 		return err
 	}
-	for k, v := range m {
+	for k, raw := range m {
 		key, err := strconv.ParseUint(k, 10, 32)
 		if err != nil {
 			return err
 		}
 
+		// tags persisted before send-only uploads existed wait for sync
+		v := &Tag{WaitForSync: true}
+		if err := json.Unmarshal(raw, v); err != nil {
+			return err
+		}
+
+		// tags persisted before the first seen time was added were seen
+		// when they were started
+		var firstSeen struct{ FirstSeenAt *time.Time }
+		if err := json.Unmarshal(raw, &firstSeen); err != nil {
+			return err
+		}
+		if firstSeen.FirstSeenAt == nil {
+			v.FirstSeenAt = v.StartedAt
+		}
+
+		if v.repairCounters() {
+			ts.logger.Warningf("tags: repaired inconsistent counters of tag %d", v.Uid)
+		}
+
 		// prevent a condition where a chunk was sent before shutdown
 		// and the node was turned off before the receipt was received
-		v.Sent = v.Synced
+		// send-only tags do not wait for receipts so their sent count stands
+		// this is what Tag.ResetUnsynced does for a tag in use
+		if v.WaitForSync {
+			v.Sent = v.Synced
+		}
+		v.keyPrefix = ts.keyPrefix
 
 		ts.tags.Store(key, v)
 	}
 
+	ts.tags.Range(func(_, v interface{}) bool {
+		ts.attach(v.(*Tag))
+		return true
+	})
+
 	return err
 }
 
+// GC removes the persisted tags which are done syncing and were started
+// more than olderThan ago from the state store and from memory. It returns
+// the number of removed tags.
+func (ts *Tags) GC(olderThan time.Duration) (int, error) {
+	if ts.stateStore == nil {
+		return 0, nil
+	}
+
+	stored, err := ts.storedTags()
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := ts.now().Add(-olderThan)
+
+	removed := 0
+	for _, t := range stored {
+		if !t.Done(StateSynced) || !t.StartedAt.Before(cutoff) {
+			continue
+		}
+
+		if err := ts.stateStore.Delete(getKey(ts.keyPrefix, t.Uid)); err != nil {
+			return removed, err
+		}
+		ts.tags.Delete(t.Uid)
+		removed++
+	}
+
+	return removed, nil
+}
+
+// storedTags returns all tags persisted in the state store.
+func (ts *Tags) storedTags() (tags []*Tag, err error) {
+	err = ts.stateStore.Iterate(ts.keyPrefix, func(key, value []byte) (bool, error) {
+		if _, err := strconv.ParseUint(strings.TrimPrefix(string(key), ts.keyPrefix), 10, 32); err != nil {
+			// not a tag key
+			return false, nil
+		}
+
+		// tags are stored as byte slices, which state stores encode as json
+		var data []byte
+		if err := json.Unmarshal(value, &data); err != nil {
+			return true, err
+		}
+
+		var ta Tag
+		if err := ta.UnmarshalBinary(data); err != nil {
+			return true, err
+		}
+		ta.keyPrefix = ts.keyPrefix
+		ta.stateStore = ts.stateStore
+		ta.logger = ts.logger
+		tags = append(tags, &ta)
+		return false, nil
+	})
+	return tags, err
+}
+
 // getTagFromStore get a given tag from the state store.
 func (ts *Tags) getTagFromStore(uid uint32) (*Tag, error) {
-	key := "tags_" + strconv.Itoa(int(uid))
+	key := getKey(ts.keyPrefix, uid)
 	var data []byte
 	err := ts.stateStore.Get(key, &data)
 	if err != nil {
@@ -170,19 +523,77 @@ func (ts *Tags) getTagFromStore(uid uint32) (*Tag, error) {
 	if err != nil {
 		return nil, err
 	}
+	ta.keyPrefix = ts.keyPrefix
 	return &ta, nil
 }
 
-// Close is called when the node goes down. This is when all the tags in memory is persisted.
+// Close is called when the node goes down. This is when all the tags in memory
+// are persisted. If the state store supports batches, all tags are persisted in
+// a single write, so that a failure leaves none of them updated. Tags which
+// can not be serialized are skipped and reported in a PersistError, while the
+// rest of the tags are persisted.
 func (ts *Tags) Close() (err error) {
-	// store all the tags in memory
-	tags := ts.All()
-	for _, t := range tags {
+	values := make(map[string]interface{})
+	failed := make(map[uint32]error)
+	ts.tags.Range(func(k, v interface{}) bool {
+		t := v.(*Tag)
 		ts.logger.Trace("updating tag: ", t.Uid)
-		err := t.saveTag()
+		value, err := marshalTag(t)
 		if err != nil {
+			failed[t.Uid] = err
+			return true
+		}
+		values[getKey(t.keyPrefix, t.Uid)] = value
+		return true
+	})
+
+	if ts.stateStore != nil {
+		if err := putAll(ts.stateStore, values); err != nil {
+			return err
+		}
+	}
+
+	if len(failed) > 0 {
+		return &PersistError{Failed: failed}
+	}
+	return nil
+}
+
+// marshalTag serializes the tag for the state store.
+var marshalTag = func(t *Tag) ([]byte, error) {
+	return t.MarshalBinary()
+}
+
+// putAll puts the values in a single batch if the state store supports it,
+// and one by one otherwise.
+func putAll(s storage.StateStorer, values map[string]interface{}) error {
+	if b, ok := s.(storage.StateBatchPutter); ok {
+		return b.PutBatch(values)
+	}
+	for key, value := range values {
+		if err := s.Put(key, value); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// PersistError is returned by Close when some of the tags could not be
+// serialized. The other tags are persisted.
+type PersistError struct {
+	Failed map[uint32]error // serialization errors by tag uid
+}
+
+func (e *PersistError) Error() string {
+	uids := make([]uint32, 0, len(e.Failed))
+	for uid := range e.Failed {
+		uids = append(uids, uid)
+	}
+	sort.Slice(uids, func(i, j int) bool { return uids[i] < uids[j] })
+
+	msgs := make([]string, 0, len(uids))
+	for _, uid := range uids {
+		msgs = append(msgs, fmt.Sprintf("tag %d: %v", uid, e.Failed[uid]))
+	}
+	return fmt.Sprintf("persist tags: %s", strings.Join(msgs, "; "))
+}
@@ -17,12 +17,20 @@
 package tags
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ethersphere/bee/pkg/logging"
 	statestore "github.com/ethersphere/bee/pkg/statestore/mock"
 	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/sirupsen/logrus"
 )
 
 func TestAll(t *testing.T) {
@@ -137,3 +145,710 @@ func TestPersistence(t *testing.T) {
 		t.Fatalf("invalid synced: expected %d got %d", ta.Synced, rcvd2.Synced)
 	}
 }
+
+func TestClosePartialFailure(t *testing.T) {
+	mockStatestore := statestore.NewStateStore()
+	logger := logging.New(ioutil.Discard, 0)
+	ts := NewTags(mockStatestore, logger)
+
+	var created []*Tag
+	for _, name := range []string{"one", "two", "three"} {
+		ta, err := ts.Create(name, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ta.Total = 10
+		ta.Stored = 5
+		created = append(created, ta)
+	}
+	broken := created[1]
+
+	errMarshal := errors.New("marshal failure")
+	defer func(f func(*Tag) ([]byte, error)) { marshalTag = f }(marshalTag)
+	marshalTag = func(ta *Tag) ([]byte, error) {
+		if ta.Uid == broken.Uid {
+			return nil, errMarshal
+		}
+		return ta.MarshalBinary()
+	}
+
+	err := ts.Close()
+	var perr *PersistError
+	if !errors.As(err, &perr) {
+		t.Fatalf("got error %v, want %T", err, perr)
+	}
+	if len(perr.Failed) != 1 || !errors.Is(perr.Failed[broken.Uid], errMarshal) {
+		t.Fatalf("got failed tags %v, want only tag %d", perr.Failed, broken.Uid)
+	}
+
+	// the other tags are persisted
+	ts = NewTags(mockStatestore, logger)
+	for _, ta := range created {
+		rcvd, err := ts.Get(ta.Uid)
+		if ta == broken {
+			if !errors.Is(err, ErrNotFound) {
+				t.Fatalf("got error %v for the tag failing to serialize, want %v", err, ErrNotFound)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rcvd.Stored != ta.Stored {
+			t.Fatalf("tag %d: got stored %d, want %d", ta.Uid, rcvd.Stored, ta.Stored)
+		}
+	}
+}
+
+func TestKeyPrefix(t *testing.T) {
+	mockStatestore := statestore.NewStateStore()
+	logger := logging.New(ioutil.Discard, 0)
+
+	defer func(f func() uint32) { TagUidFunc = f }(TagUidFunc)
+	TagUidFunc = func() uint32 { return 42 }
+
+	for _, tc := range []struct {
+		prefix string
+		total  int64
+	}{
+		{prefix: "a_", total: 10},
+		{prefix: "b_", total: 20},
+	} {
+		ts := NewTags(mockStatestore, logger, WithKeyPrefix(tc.prefix))
+		ta, err := ts.Create("one", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ta.Split = tc.total
+		if _, err := ta.DoneSplit(swarm.ZeroAddress); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// simulate node boot up with fresh instances sharing the state store
+	for _, tc := range []struct {
+		prefix string
+		total  int64
+	}{
+		{prefix: "a_", total: 10},
+		{prefix: "b_", total: 20},
+	} {
+		ts := NewTags(mockStatestore, logger, WithKeyPrefix(tc.prefix))
+		ta, err := ts.Get(42)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ta.Total != tc.total {
+			t.Fatalf("prefix %s: invalid total: expected %d got %d", tc.prefix, tc.total, ta.Total)
+		}
+	}
+
+	ts := NewTags(mockStatestore, logger)
+	if _, err := ts.Get(42); err != ErrNotFound {
+		t.Fatalf("got error %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestActiveSince(t *testing.T) {
+	mockStatestore := statestore.NewStateStore()
+	logger := logging.New(ioutil.Discard, 0)
+
+	start := time.Unix(1600000000, 0)
+	now := start
+	ts := NewTags(mockStatestore, logger, WithClock(func() time.Time { return now }))
+
+	for _, name := range []string{"old1", "old2", "recent1", "recent2"} {
+		now = now.Add(time.Minute)
+		if _, err := ts.Create(name, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	active := ts.ActiveSince(start.Add(2 * time.Minute))
+	if len(active) != 2 {
+		t.Fatalf("expected 2 active tags got %d", len(active))
+	}
+	for _, ta := range active {
+		if ta.Name != "recent1" && ta.Name != "recent2" {
+			t.Fatalf("unexpected active tag %s", ta.Name)
+		}
+	}
+
+	if active := ts.ActiveSince(now); len(active) != 0 {
+		t.Fatalf("expected no active tags got %d", len(active))
+	}
+}
+
+func TestMarshalJSONUploadModes(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	ts := NewTags(statestore.NewStateStore(), logger)
+
+	synced, err := ts.Create("synced", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sendOnly, err := ts.CreateSendOnly("send-only", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending, err := ts.CreateSendOnly("pending", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ta := range []*Tag{synced, sendOnly} {
+		for _, state := range []State{StateSplit, StateStored, StateSent} {
+			if err := ta.IncN(state, 10); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	for _, state := range []State{StateSplit, StateStored} {
+		if err := pending.IncN(state, 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pending.IncN(StateSent, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ts.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewTags(statestore.NewStateStore(), logger)
+	if err := restored.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+
+	// the tag waiting for sync is persisted with its sent count reset to
+	// the synced count, the complete send-only tag is not persisted at all
+	var uids []uint32
+	restored.Range(func(k, v interface{}) bool {
+		ta := v.(*Tag)
+		uids = append(uids, ta.Uid)
+		switch ta.Uid {
+		case synced.Uid:
+			if !ta.WaitForSync {
+				t.Error("restored synced tag is send-only")
+			}
+			if ta.Sent != 0 {
+				t.Errorf("got sent %d for synced tag, want 0", ta.Sent)
+			}
+		case pending.Uid:
+			if ta.WaitForSync {
+				t.Error("restored send-only tag waits for sync")
+			}
+			if ta.Sent != 5 {
+				t.Errorf("got sent %d for send-only tag, want 5", ta.Sent)
+			}
+		}
+		return true
+	})
+
+	if len(uids) != 2 {
+		t.Fatalf("got %d restored tags, want 2", len(uids))
+	}
+	for _, uid := range uids {
+		if uid == sendOnly.Uid {
+			t.Fatal("complete send-only tag was persisted")
+		}
+	}
+}
+
+func TestCreateWithUid(t *testing.T) {
+	mockStatestore := statestore.NewStateStore()
+	logger := logging.New(ioutil.Discard, 0)
+	address := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+
+	ts := NewTags(mockStatestore, logger)
+	ta, err := ts.CreateWithUid(7, "seven", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ta.Uid != 7 {
+		t.Fatalf("got uid %d, want 7", ta.Uid)
+	}
+
+	if _, err := ts.CreateWithUid(7, "other", 1); err != errExists {
+		t.Fatalf("got error %v, want %v", err, errExists)
+	}
+
+	ta.Split = 10
+	if _, err := ta.DoneSplit(address); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ts.GetByAddress(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Uid != 7 {
+		t.Fatalf("got uid %d by address, want 7", got.Uid)
+	}
+
+	// simulate node boot up
+	ts = NewTags(mockStatestore, logger)
+
+	got, err = ts.Get(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "seven" || got.Total != 10 || !got.Address.Equal(address) {
+		t.Fatalf("got tag %s with total %d and address %s after restart", got.Name, got.Total, got.Address)
+	}
+
+	// the persisted tag collides even though it is not loaded in memory
+	ts = NewTags(mockStatestore, logger)
+	if _, err := ts.CreateWithUid(7, "other", 1); err != errExists {
+		t.Fatalf("got error %v, want %v", err, errExists)
+	}
+}
+
+func TestListTags(t *testing.T) {
+	mockStatestore := statestore.NewStateStore()
+	logger := logging.New(ioutil.Discard, 0)
+
+	now := time.Unix(1600000000, 0)
+	ts := NewTags(mockStatestore, logger, WithClock(func() time.Time { return now }))
+
+	var created []*Tag
+	for i := 0; i < 5; i++ {
+		now = now.Add(time.Minute)
+		ta, err := ts.CreateWithUid(uint32(i+1), fmt.Sprintf("tag%d", i), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		created = append(created, ta)
+	}
+
+	// the first two tags are done
+	for _, ta := range created[:2] {
+		for _, state := range []State{StateSplit, StateStored, StateSent, StateSynced} {
+			if err := ta.IncN(state, 10); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// the second tag was persisted when it was done and evicted from memory
+	ts.Delete(created[1].Uid)
+
+	uids := func(tags []*Tag) (u []uint32) {
+		for _, ta := range tags {
+			u = append(u, ta.Uid)
+		}
+		return u
+	}
+
+	for _, tc := range []struct {
+		name        string
+		offset      int
+		limit       int
+		includeDone bool
+		want        []uint32
+	}{
+		{name: "all", includeDone: true, want: []uint32{5, 4, 3, 2, 1}},
+		{name: "not done", want: []uint32{5, 4, 3}},
+		{name: "first page", limit: 2, includeDone: true, want: []uint32{5, 4}},
+		{name: "second page", offset: 2, limit: 2, includeDone: true, want: []uint32{3, 2}},
+		{name: "last page", offset: 4, limit: 2, includeDone: true, want: []uint32{1}},
+		{name: "beyond end", offset: 5, limit: 2, includeDone: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tags, err := ts.ListTags(tc.offset, tc.limit, tc.includeDone)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := uids(tags); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got uids %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	if got := uids(ts.All()); !reflect.DeepEqual(got, []uint32{5, 4, 3, 2, 1}) {
+		t.Fatalf("got uids %v from All, want all tags", got)
+	}
+}
+
+func TestWaitSynced(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	ts := NewTags(statestore.NewStateStore(), logger)
+
+	ta, err := ts.Create("one", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, state := range []State{StateSplit, StateStored, StateSent} {
+		if err := ta.IncN(state, 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := ts.WaitSynced(ctx, ta.Uid); err != context.DeadlineExceeded {
+		t.Fatalf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	go func() {
+		for i := 0; i < 10; i++ {
+			time.Sleep(time.Millisecond)
+			if err := ta.Inc(StateSynced); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := ts.WaitSynced(ctx, ta.Uid); err != nil {
+		t.Fatal(err)
+	}
+	if synced := ta.Get(StateSynced); synced != 10 {
+		t.Fatalf("got %d synced chunks after waiting, want 10", synced)
+	}
+
+	// waiting for a synced tag returns immediately
+	if err := ts.WaitSynced(context.Background(), ta.Uid); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ts.WaitSynced(context.Background(), ta.Uid+1); err != ErrNotFound {
+		t.Fatalf("got error %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestCreateWithContext(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	ts := NewTags(statestore.NewStateStore(), logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ta, err := ts.CreateWithContext(ctx, "aborted", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ta.IncN(StateSplit, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+
+	// increments after the cancellation are still recorded
+	if err := ta.IncN(StateSplit, 5); err != nil {
+		t.Fatal(err)
+	}
+	if split := ta.Get(StateSplit); split != 10 {
+		t.Fatalf("got %d split chunks, want 10", split)
+	}
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer waitCancel()
+	if err := ts.WaitSynced(waitCtx, ta.Uid); err != context.Canceled {
+		t.Fatalf("got error %v, want %v", err, context.Canceled)
+	}
+	if err := ta.Context().Err(); err != context.Canceled {
+		t.Fatalf("got tag context error %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestGC(t *testing.T) {
+	mockStatestore := statestore.NewStateStore()
+	logger := logging.New(ioutil.Discard, 0)
+
+	now := time.Unix(1600000000, 0)
+	ts := NewTags(mockStatestore, logger, WithClock(func() time.Time { return now }))
+
+	create := func(uid uint32, startedAt time.Time, done bool) {
+		now = startedAt
+		ta, err := ts.CreateWithUid(uid, "tag", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		states := []State{StateSplit, StateStored}
+		if done {
+			states = append(states, StateSent, StateSynced)
+		}
+		for _, state := range states {
+			if err := ta.IncN(state, 10); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// persist tags which are not done as well
+		if _, err := ta.DoneSplit(swarm.ZeroAddress); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start := now
+	create(1, start, true)                     // old and done
+	create(2, start.Add(time.Minute), true)    // old and done
+	create(3, start.Add(2*time.Minute), false) // old but not done
+	create(4, start.Add(2*time.Hour), true)    // recent and done
+	create(5, start.Add(2*time.Hour+1), false) // recent and not done
+
+	now = start.Add(3 * time.Hour)
+
+	removed, err := ts.GC(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Fatalf("removed %d tags, want 2", removed)
+	}
+
+	for uid, want := range map[uint32]bool{1: false, 2: false, 3: true, 4: true, 5: true} {
+		_, inMemory := ts.tags.Load(uid)
+		var data []byte
+		inStore := mockStatestore.Get(getKey("", uid), &data) == nil
+		if inMemory != want || inStore != want {
+			t.Fatalf("tag %d: got in memory %v and in store %v, want %v", uid, inMemory, inStore, want)
+		}
+	}
+}
+
+func TestGetByName(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+
+	now := time.Unix(1600000000, 0)
+	ts := NewTags(statestore.NewStateStore(), logger, WithClock(func() time.Time { return now }))
+
+	var latest *Tag
+	for _, name := range []string{"site", "other", "site"} {
+		now = now.Add(time.Minute)
+		ta, err := ts.Create(name, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name == "site" {
+			latest = ta
+		}
+	}
+
+	got, err := ts.GetByName("site")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Uid != latest.Uid {
+		t.Fatalf("got tag %d, want the latest tag %d", got.Uid, latest.Uid)
+	}
+
+	if _, err := ts.GetByName("missing"); err != ErrNotFound {
+		t.Fatalf("got error %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestCreateRetriesUid(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	ts := NewTags(statestore.NewStateStore(), logger)
+
+	defer func(f func() uint32) { TagUidFunc = f }(TagUidFunc)
+
+	uids := []uint32{1, 1, 2}
+	TagUidFunc = func() uint32 {
+		uid := uids[0]
+		uids = uids[1:]
+		return uid
+	}
+
+	first, err := ts.Create("first", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the duplicate uid is replaced by a fresh one
+	second, err := ts.Create("second", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Uid != 1 || second.Uid != 2 {
+		t.Fatalf("got uids %d and %d, want 1 and 2", first.Uid, second.Uid)
+	}
+
+	// all attempts collide
+	TagUidFunc = func() uint32 { return 1 }
+	if _, err := ts.Create("third", 1); err != errExists {
+		t.Fatalf("got error %v, want %v", err, errExists)
+	}
+}
+
+func TestCreateChild(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	mockStatestore := statestore.NewStateStore()
+	ts := NewTags(mockStatestore, logger)
+	address := swarm.MustParseHexAddress("1111111111111111111111111111111111111111111111111111111111111111")
+
+	parent, err := ts.Create("dir", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent.Address = address
+
+	var children []*Tag
+	for _, name := range []string{"dir/a", "dir/b"} {
+		child, err := ts.CreateChild(parent.Uid, name, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if child.ParentUid != parent.Uid {
+			t.Fatalf("got parent uid %d, want %d", child.ParentUid, parent.Uid)
+		}
+		children = append(children, child)
+	}
+	// a child with the same address as its parent
+	children[0].Address = address
+
+	if err := parent.Inc(StateStored); err != nil {
+		t.Fatal(err)
+	}
+	for _, child := range children {
+		if err := child.IncN(StateStored, 2); err != nil {
+			t.Fatal(err)
+		}
+		if err := child.Inc(StateSynced); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := TagState{Total: 5, Stored: 5, Synced: 2}
+	if got := parent.Snapshot(); got != want {
+		t.Fatalf("got parent snapshot %+v, want %+v", got, want)
+	}
+
+	got, err := ts.GetByAddress(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Uid != parent.Uid {
+		t.Fatalf("got tag %d for the parent address, want the parent %d", got.Uid, parent.Uid)
+	}
+
+	if _, err := ts.CreateChild(parent.Uid+1, "orphan", 1); err != ErrNotFound {
+		t.Fatalf("got error %v, want %v", err, ErrNotFound)
+	}
+
+	// the linkage is restored when the tags are loaded from the store
+	if err := ts.Close(); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewTags(mockStatestore, logger)
+	if _, err := loaded.Get(children[0].Uid); err != nil {
+		t.Fatal(err)
+	}
+	loadedParent, err := loaded.Get(parent.Uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loaded.Get(children[1].Uid); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadedParent.Snapshot(); got != want {
+		t.Fatalf("got loaded parent snapshot %+v, want %+v", got, want)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	ts := NewTags(statestore.NewStateStore(), logger)
+
+	done, err := ts.Create("done", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := done.IncN(StateStored, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := done.IncN(StateSynced, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	active, err := ts.Create("active", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := active.IncN(StateSplit, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	// children are counted once, not also through their parent
+	child, err := ts.CreateChild(active.Uid, "child", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := child.Inc(StateSplit); err != nil {
+		t.Fatal(err)
+	}
+
+	want := TagsMetrics{
+		Tags:   3,
+		Active: 2,
+		Counters: TagState{
+			Total:  6,
+			Split:  4,
+			Stored: 2,
+			Synced: 2,
+		},
+	}
+	if got := ts.Metrics(); got != want {
+		t.Fatalf("got metrics %+v, want %+v", got, want)
+	}
+
+	few := testing.AllocsPerRun(10, func() { ts.Metrics() })
+	for i := 0; i < 100; i++ {
+		if _, err := ts.Create(fmt.Sprintf("tag%d", i), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ts.Metrics()
+	many := testing.AllocsPerRun(10, func() { ts.Metrics() })
+	if many > few {
+		t.Fatalf("got %v allocations with many tags, want at most %v", many, few)
+	}
+}
+
+func TestUnmarshalJSONRepairsCounters(t *testing.T) {
+	var buf bytes.Buffer
+	ts := NewTags(nil, logging.New(&buf, logrus.WarnLevel))
+
+	data := []byte(`{
+		"1": {"Uid": 1, "Name": "corrupt", "WaitForSync": true, "Total": 10, "Split": 12, "Stored": 4, "Seen": 5, "Sent": 9, "Synced": 9, "TotalBytes": 100, "SyncedBytes": 200},
+		"2": {"Uid": 2, "Name": "negative", "WaitForSync": true, "Total": -1, "Split": 2, "Stored": 2, "Synced": -3},
+		"3": {"Uid": 3, "Name": "consistent", "WaitForSync": true, "Total": 4, "Split": 4, "Stored": 4, "Seen": 1, "Sent": 3, "Synced": 3, "TotalBytes": 100, "SyncedBytes": 50}
+	}`)
+	if err := ts.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		uid      uint32
+		want     TagState
+		repaired bool
+	}{
+		{
+			uid:      1,
+			want:     TagState{Total: 10, Split: 10, Stored: 4, Seen: 4, Sent: 4, Synced: 4, TotalBytes: 100, SyncedBytes: 100},
+			repaired: true,
+		},
+		{
+			uid:      2,
+			want:     TagState{Split: 2, Stored: 2},
+			repaired: true,
+		},
+		{
+			uid:  3,
+			want: TagState{Total: 4, Split: 4, Stored: 4, Seen: 1, Sent: 3, Synced: 3, TotalBytes: 100, SyncedBytes: 50},
+		},
+	} {
+		tag, err := ts.Get(tc.uid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tag.Snapshot(); got != tc.want {
+			t.Fatalf("tag %d: got counters %+v, want %+v", tc.uid, got, tc.want)
+		}
+		logged := strings.Contains(buf.String(), fmt.Sprintf("counters of tag %d\"", tc.uid))
+		if logged != tc.repaired {
+			t.Fatalf("tag %d: got repair logged %v, want %v", tc.uid, logged, tc.repaired)
+		}
+	}
+}